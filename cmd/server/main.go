@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
 	"os"
 	"os/signal"
@@ -26,6 +27,30 @@ func main() {
 		log.Printf("Warning: Failed to load configuration: %v. Using defaults.", err)
 		cfg = config.DefaultConfig()
 	}
+
+	// Command-line flags override the transport settings from the config file
+	flag.StringVar(&cfg.Transport.Type, "transport", cfg.Transport.Type, "Transport to serve on: stdio, sse or http")
+	flag.StringVar(&cfg.Transport.ListenAddr, "listen", cfg.Transport.ListenAddr, "Listen address for the sse and http transports")
+	flag.StringVar(&cfg.Transport.BasePath, "base-path", cfg.Transport.BasePath, "URL path the MCP endpoints are mounted under")
+	flag.StringVar(&cfg.Transport.TLSCertFile, "tls-cert", cfg.Transport.TLSCertFile, "TLS certificate file for the sse and http transports")
+	flag.StringVar(&cfg.Transport.TLSKeyFile, "tls-key", cfg.Transport.TLSKeyFile, "TLS private key file for the sse and http transports")
+
+	// The auth token can also come from the environment so it does not show up in process listings
+	if token := os.Getenv("GO_DEV_MCP_AUTH_TOKEN"); token != "" {
+		cfg.Transport.AuthToken = token
+	}
+	flag.StringVar(&cfg.Transport.AuthToken, "auth-token", cfg.Transport.AuthToken, "Bearer token required by the sse and http transports (or set GO_DEV_MCP_AUTH_TOKEN)")
+	flag.StringVar(&cfg.SandboxType, "sandbox", cfg.SandboxType, "Sandbox for spawned go processes: process, namespace or none")
+	flag.Parse()
+
+	// Refuse to start with a sandbox that cannot be used on this platform
+	if _, err := tools.NewSandbox(cfg.SandboxType); err != nil {
+		log.Fatalf("Invalid sandbox configuration: %v", err)
+	}
+	log.Printf("Running commands in the %s sandbox", cfg.SandboxType)
+	if cfg.Analysis.InProcess && cfg.SandboxType != "none" {
+		log.Printf("go_analyze loads packages for its in-process analyzers outside the sandbox; set analysis.inProcess to false to run go vet only")
+	}
 	// Create hooks for enhanced server observability
 	hooks := &server.Hooks{}
 	hooks.AddBeforeAny(func(ctx context.Context, id any, method mcp.MCPMethod, message any) {
@@ -37,6 +62,7 @@ func main() {
 	hooks.AddOnError(func(ctx context.Context, id any, method mcp.MCPMethod, message any, err error) {
 		log.Printf("Error processing request: %s, ID: %v, Error: %v", method, id, err)
 	})
+	hooks.AddOnUnregisterSession(customServer.ForgetClientRoots)
 
 	// Get fuzzy matching middleware
 	fuzzyMiddleware := customServer.FuzzyMatchMiddleware(cfg, nil)
@@ -50,26 +76,37 @@ func main() {
 		server.WithLogging(),
 		server.WithHooks(hooks),
 		server.WithToolHandlerMiddleware(fuzzyMiddleware),
+		server.WithToolHandlerMiddleware(tools.PathPolicyMiddleware),
+		server.WithToolHandlerMiddleware(tools.NetworkPolicyMiddleware),
+		server.WithToolHandlerMiddleware(tools.ProgressMiddleware),
+		server.WithToolHandlerMiddleware(customServer.CancellationMiddleware),
 	)
 
 	// Log that fuzzy matching middleware is applied
 	log.Println("Fuzzy matching middleware applied")
+	log.Println("Progress notifications enabled for requests with a progress token")
 
 	// Handle signals for graceful shutdown
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		<-sigCh
 		log.Println("Shutting down...")
-		os.Exit(0)
+		cancel()
 	}()
 
 	// Register tools directly with the MCP server
+	tools.SetConfig(cfg)
 	registerTools(s)
 
-	// Start the server with context
+	// Start the server with context using the configured transport
 	log.Println("Server is ready to accept connections")
-	if err := server.ServeStdio(s); err != nil {
+	err = customServer.Serve(ctx, s, cfg.Transport)
+	// Scratch sessions do not outlive the server
+	tools.CloseSessions()
+	if err != nil && err != context.Canceled {
 		log.Fatalf("Failed to serve: %v", err)
 	}
 }
@@ -86,12 +123,16 @@ func registerTools(s *server.MCPServer) { // Register go_build tool
 			mcp.Description("Path to a Go workspace directory (go.work file).")),
 		mcp.WithString("module",
 			mcp.Description("Specific module to build within a workspace.")),
+		mcp.WithString("mainFile",
+			mcp.Description("With both code and project_path, the path inside the project the code is placed at (default main.go), e.g. cmd/try/main.go or pkg/foo/foo.go. The project itself is never modified.")),
+		mcp.WithNumber("parallel",
+			mcp.Description("Number of workspace modules to process concurrently when targeting the whole workspace (default 1).")),
 		mcp.WithString("outputPath",
 			mcp.Description("Path where the compiled executable should be saved.")),
 		mcp.WithString("buildTags",
 			mcp.Description("Build tags to use during compilation.")))
 
-	s.AddTool(buildTool, tools.ExecuteGoBuildTool)
+	s.AddTool(withAsync(withResourceLimits(withSession(withFileInput(buildTool)))), tools.WithAsync(tools.WithWorkspaceLock(tools.ExecuteGoBuildTool)))
 	// Register go_run tool
 	runTool := mcp.NewTool("go_run",
 		mcp.WithDescription("Run Go code directly."),
@@ -103,11 +144,22 @@ func registerTools(s *server.MCPServer) { // Register go_build tool
 			mcp.Description("Path to a Go workspace directory (go.work file).")),
 		mcp.WithString("module",
 			mcp.Description("Specific module to run within a workspace.")),
-		mcp.WithNumber("timeoutSecs",
-			mcp.Description("Maximum execution time in seconds before the program is terminated."),
-			mcp.DefaultNumber(30)))
+		mcp.WithString("mainFile",
+			mcp.Description("With both code and project_path, the path inside the project the code is placed at (default main.go), e.g. cmd/try/main.go or pkg/foo/foo.go. The project itself is never modified.")),
+		mcp.WithString("package",
+			mcp.Description("Main package to run, as a directory (e.g., ./cmd/server) or import path. By default the main package in the module root or under cmd/ is used when there is exactly one.")),
+		mcp.WithArray("args",
+			mcp.Description("Command-line arguments passed to the program, in order."),
+			mcp.Items(map[string]any{"type": "string"})),
+		mcp.WithString("stdin",
+			mcp.Description("Text written to the program's standard input.")),
+		mcp.WithObject("env",
+			mcp.Description("Environment variables for the program, as name to value. Names must be allowed by run.envAllowlist in the server configuration."),
+			mcp.AdditionalProperties(map[string]any{"type": "string"})),
+		mcp.WithString("working_dir",
+			mcp.Description("Directory to run the program in, relative to the project or workspace root.")))
 
-	s.AddTool(runTool, tools.ExecuteGoRunTool)
+	s.AddTool(withAsync(withResourceLimits(withSession(withFileInput(runTool)))), tools.WithAsync(tools.WithWorkspaceLock(tools.ExecuteGoRunTool)))
 	// Register go_fmt tool
 	fmtTool := mcp.NewTool("go_fmt",
 		mcp.WithDescription("Format Go code according to standard Go formatting rules."),
@@ -118,9 +170,14 @@ func registerTools(s *server.MCPServer) { // Register go_build tool
 		mcp.WithString("workspace_path",
 			mcp.Description("Path to a Go workspace directory (go.work file).")),
 		mcp.WithString("module",
-			mcp.Description("Specific module to format within a workspace.")))
+			mcp.Description("Specific module to format within a workspace.")),
+		mcp.WithString("mode",
+			mcp.Description("write rewrites the files (default), check lists the files that are not formatted, and diff returns a unified diff per file (gofmt -d). check and diff never modify files."),
+			mcp.Enum("write", "check", "diff")),
+		mcp.WithNumber("parallel",
+			mcp.Description("Number of workspace modules to process concurrently when targeting the whole workspace (default 1).")))
 
-	s.AddTool(fmtTool, tools.ExecuteGoFmtTool)
+	s.AddTool(withResourceLimits(withSession(withFileInput(fmtTool))), tools.WithWorkspaceLock(tools.ExecuteGoFmtTool))
 	// Register go_test tool
 	testTool := mcp.NewTool("go_test",
 		mcp.WithDescription("Run tests on Go code."),
@@ -134,6 +191,10 @@ func registerTools(s *server.MCPServer) { // Register go_build tool
 			mcp.Description("Path to a Go workspace directory (go.work file).")),
 		mcp.WithString("module",
 			mcp.Description("Specific module to test within a workspace.")),
+		mcp.WithString("mainFile",
+			mcp.Description("With both code and project_path, the path inside the project the code is placed at (default main.go), e.g. cmd/try/main.go or pkg/foo/foo.go. The project itself is never modified.")),
+		mcp.WithNumber("parallel",
+			mcp.Description("Number of workspace modules to process concurrently when targeting the whole workspace (default 1).")),
 		mcp.WithString("testPattern",
 			mcp.Description("Pattern to filter which tests to run.")),
 		mcp.WithBoolean("verbose",
@@ -143,7 +204,7 @@ func registerTools(s *server.MCPServer) { // Register go_build tool
 			mcp.Description("Enable coverage reporting."),
 			mcp.DefaultBool(false)))
 
-	s.AddTool(testTool, tools.ExecuteGoTestTool)
+	s.AddTool(withAsync(withResourceLimits(withSession(withFileInput(testTool)))), tools.WithAsync(tools.WithWorkspaceLock(tools.ExecuteGoTestTool)))
 	// Register go_mod tool
 	modTool := mcp.NewTool("go_mod",
 		mcp.WithDescription("Manage Go module dependencies."),
@@ -161,10 +222,10 @@ func registerTools(s *server.MCPServer) { // Register go_build tool
 		mcp.WithString("module",
 			mcp.Description("Specific module to manage within a workspace.")))
 
-	s.AddTool(modTool, tools.ExecuteGoModTool)
+	s.AddTool(withAsync(withResourceLimits(withSession(modTool))), tools.WithAsync(tools.WithWorkspaceLock(tools.ExecuteGoModTool)))
 	// Register go_analyze tool
 	analyzeTool := mcp.NewTool("go_analyze",
-		mcp.WithDescription("Analyze Go code for potential issues using go vet."),
+		mcp.WithDescription("Analyze Go code for potential issues using go vet. Returns structured diagnostics with the analyzer, package, position, message and suggested fixes of each finding."),
 		mcp.WithString("code",
 			mcp.Description("Go source code to analyze.")),
 		mcp.WithString("project_path",
@@ -173,11 +234,29 @@ func registerTools(s *server.MCPServer) { // Register go_build tool
 			mcp.Description("Path to a Go workspace directory (go.work file).")),
 		mcp.WithString("module",
 			mcp.Description("Specific module to analyze within a workspace.")),
+		mcp.WithString("mainFile",
+			mcp.Description("With both code and project_path, the path inside the project the code is placed at (default main.go), e.g. cmd/try/main.go or pkg/foo/foo.go. The project itself is never modified.")),
+		mcp.WithNumber("parallel",
+			mcp.Description("Number of workspace modules to process concurrently when targeting the whole workspace (default 1).")),
 		mcp.WithBoolean("vet",
 			mcp.Description("Run go vet analysis."),
+			mcp.DefaultBool(true)),
+		mcp.WithArray("analyzers",
+			mcp.Description("Run only these analyzers, e.g. [\"printf\", \"nilness\"]: go vet analyzers or the in-process nilness, shadow, unusedwrite, errorsas, error-wrapping, defer-in-loop and context-leak. By default go vet runs all of its analyzers and the configured in-process analyzers run."),
+			mcp.Items(map[string]any{"type": "string"})),
+		mcp.WithArray("disable",
+			mcp.Description("go vet or in-process analyzers to turn off."),
+			mcp.Items(map[string]any{"type": "string"})),
+		mcp.WithObject("analyzerFlags",
+			mcp.Description("Analyzer flags as analyzer.flag to value, e.g. {\"printf.funcs\": \"Logf,Warnf\"}.")),
+		mcp.WithBoolean("fix",
+			mcp.Description("Apply the suggested fixes of the diagnostics and return a unified diff per changed file."),
+			mcp.DefaultBool(false)),
+		mcp.WithBoolean("dry_run",
+			mcp.Description("With fix, only show the diffs without writing the files. Set to false to write the fixes to the project."),
 			mcp.DefaultBool(true)))
 
-	s.AddTool(analyzeTool, tools.ExecuteGoAnalyzeTool) // Register go_workspace tool
+	s.AddTool(withAsync(withResourceLimits(withSession(withFileInput(analyzeTool)))), tools.WithAsync(tools.WithWorkspaceLock(tools.ExecuteGoAnalyzeTool))) // Register go_workspace tool
 	workspaceTool := mcp.NewTool("go_workspace",
 		mcp.WithDescription("Manage Go workspaces for multi-module development."),
 		mcp.WithString("command",
@@ -186,14 +265,154 @@ func registerTools(s *server.MCPServer) { // Register go_build tool
 		mcp.WithString("workspace_path",
 			mcp.Description("Path to the workspace directory where go.work file is or will be created.")),
 		mcp.WithArray("modules",
-			mcp.Description("List of module paths for 'use' command, or module names for 'edit' command.")),
-		mcp.WithString("version",
-			mcp.Description("Version constraint for 'edit' command (e.g., v1.2.3, latest).")),
+			mcp.Description("List of module paths for 'init' and 'use' commands."),
+			mcp.Items(map[string]any{"type": "string"})),
+		mcp.WithArray("add_use",
+			mcp.Description("Module directories to add to go.work with the 'edit' command."),
+			mcp.Items(map[string]any{"type": "string"})),
+		mcp.WithArray("drop_use",
+			mcp.Description("Module directories to remove from go.work with the 'edit' command."),
+			mcp.Items(map[string]any{"type": "string"})),
+		mcp.WithArray("add_replace",
+			mcp.Description("Replace directives to add or update with the 'edit' command. Leave new_version empty when new is a local directory."),
+			mcp.Items(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"old":         map[string]any{"type": "string", "description": "Module path to replace"},
+					"old_version": map[string]any{"type": "string", "description": "Version to replace; empty replaces all versions"},
+					"new":         map[string]any{"type": "string", "description": "Replacement module path or directory"},
+					"new_version": map[string]any{"type": "string", "description": "Replacement module version"},
+				},
+				"required": []string{"old", "new"},
+			})),
+		mcp.WithArray("drop_replace",
+			mcp.Description("Replace directives to remove with the 'edit' command, as module path or path@version."),
+			mcp.Items(map[string]any{"type": "string"})),
+		mcp.WithString("go_version",
+			mcp.Description("Go version for the go directive with the 'edit' command (e.g., 1.23.0). Empty removes it.")),
+		mcp.WithString("toolchain",
+			mcp.Description("Toolchain for the toolchain directive with the 'edit' command (e.g., go1.24.2). Empty removes it.")),
+		mcp.WithBoolean("dry_run",
+			mcp.Description("Show the go.work diff for the 'edit' command without writing the file."),
+			mcp.DefaultBool(false)),
 		mcp.WithBoolean("recursive",
 			mcp.Description("Search for modules recursively when using 'use' command."),
 			mcp.DefaultBool(false)))
 
-	s.AddTool(workspaceTool, tools.ExecuteGoWorkspaceTool)
+	s.AddTool(withResourceLimits(workspaceTool), tools.WithWorkspaceLock(tools.ExecuteGoWorkspaceTool))
+
+	// Register the tools for jobs started with async
+	jobStatusTool := mcp.NewTool("go_job_status",
+		mcp.WithDescription("Get the status of a background job started with async, including the tool result once it has finished."),
+		mcp.WithString("job_id",
+			mcp.Description("ID of the job returned when it was started."),
+			mcp.Required()))
+
+	s.AddTool(jobStatusTool, tools.ExecuteGoJobStatusTool)
+
+	jobOutputTool := mcp.NewTool("go_job_output",
+		mcp.WithDescription("Read the live output of a background job started with async."),
+		mcp.WithString("job_id",
+			mcp.Description("ID of the job returned when it was started."),
+			mcp.Required()),
+		mcp.WithNumber("offset",
+			mcp.Description("Output offset to read from; pass the nextOffset of the previous call to read only new output (default 0).")))
+
+	s.AddTool(jobOutputTool, tools.ExecuteGoJobOutputTool)
+
+	jobCancelTool := mcp.NewTool("go_job_cancel",
+		mcp.WithDescription("Cancel a running background job started with async."),
+		mcp.WithString("job_id",
+			mcp.Description("ID of the job to cancel."),
+			mcp.Required()))
+
+	s.AddTool(jobCancelTool, tools.ExecuteGoJobCancelTool)
+
+	// Register the scratch session tools
+	sessionCreateTool := withFileInput(mcp.NewTool("go_session_create",
+		mcp.WithDescription("Create a persistent scratch module that go_build, go_run, go_test, go_fmt, go_mod and go_analyze can work on with session_id. Unlike code input, the module and its go.sum survive between calls."),
+		mcp.WithString("module",
+			mcp.Description("Module path for the generated go.mod (default scratch). Ignored when the files include a go.mod."))))
+
+	s.AddTool(withResourceLimits(sessionCreateTool), tools.ExecuteGoSessionCreateTool)
+
+	sessionWriteTool := withFileInput(mcp.NewTool("go_session_write",
+		mcp.WithDescription("Write files into a scratch session, replacing files with the same path, and delete files from it."),
+		mcp.WithString("session_id",
+			mcp.Description("ID of the session returned by go_session_create."),
+			mcp.Required()),
+		mcp.WithArray("delete",
+			mcp.Description("Relative paths of files or directories to delete from the session."),
+			mcp.Items(map[string]interface{}{"type": "string"}))))
+
+	s.AddTool(sessionWriteTool, tools.ExecuteGoSessionWriteTool)
+
+	sessionListTool := mcp.NewTool("go_session_list",
+		mcp.WithDescription("List the scratch sessions with their files, disk usage and expiry."))
+
+	s.AddTool(sessionListTool, tools.ExecuteGoSessionListTool)
+
+	sessionDeleteTool := mcp.NewTool("go_session_delete",
+		mcp.WithDescription("Delete a scratch session and its files."),
+		mcp.WithString("session_id",
+			mcp.Description("ID of the session to delete."),
+			mcp.Required()))
+
+	s.AddTool(sessionDeleteTool, tools.ExecuteGoSessionDeleteTool)
+
+	// Register go_server_status tool
+	serverStatusTool := mcp.NewTool("go_server_status",
+		mcp.WithDescription("Report how busy the server is: worker pool size, running and queued go commands per client, rejected commands, running jobs and sessions."))
+
+	s.AddTool(serverStatusTool, tools.ExecuteGoServerStatusTool)
 
 	log.Printf("Registered comprehensive tools with MCP server")
 }
+
+// withAsync adds the parameter that runs a tool call as a background job
+func withAsync(tool mcp.Tool) mcp.Tool {
+	mcp.WithBoolean("async",
+		mcp.Description("Run in the background and return a job ID at once. Poll go_job_status and go_job_output, or stop it with go_job_cancel. Jobs use the longer jobs.timeoutSecs timeout."))(&tool)
+	return tool
+}
+
+// withSession adds the parameter that runs a tool on a scratch session
+func withSession(tool mcp.Tool) mcp.Tool {
+	mcp.WithString("session_id",
+		mcp.Description("Work on the scratch session created with go_session_create instead of a temporary module. Code and files given with it are saved into the session first."))(&tool)
+	return tool
+}
+
+// withFileInput adds the multi-file code input parameters to a tool
+func withFileInput(tool mcp.Tool) mcp.Tool {
+	options := []mcp.ToolOption{
+		mcp.WithObject("files",
+			mcp.Description("Multiple source files as an object mapping relative paths to contents, e.g. {\"go.mod\": \"...\", \"pkg/util/util.go\": \"...\"}. Paths must stay inside the module."),
+			mcp.AdditionalProperties(map[string]interface{}{"type": "string"})),
+		mcp.WithString("txtar",
+			mcp.Description("Multiple source files as a txtar archive, with each file introduced by a '-- path --' line.")),
+	}
+	for _, option := range options {
+		option(&tool)
+	}
+	return tool
+}
+
+// withResourceLimits declares the per-request resource limit and network overrides shared by
+// every tool. Overrides can only tighten the limits from the server configuration.
+func withResourceLimits(tool mcp.Tool) mcp.Tool {
+	options := []mcp.ToolOption{
+		mcp.WithNumber("timeoutSecs",
+			mcp.Description("Maximum execution time in seconds before the command is terminated.")),
+		mcp.WithNumber("memoryLimitMB",
+			mcp.Description("Maximum memory in MB available to spawned go processes.")),
+		mcp.WithNumber("cpuLimit",
+			mcp.Description("Maximum number of CPU cores available to spawned go processes.")),
+		mcp.WithBoolean("offline",
+			mcp.Description("Run without network access: GOPROXY=off, a scrubbed environment and, where the sandbox supports it, no network. Tools configured to run offline cannot be brought back online.")),
+	}
+	for _, option := range options {
+		option(&tool)
+	}
+	return tool
+}
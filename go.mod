@@ -1,6 +1,6 @@
 module github.com/MrFixit96/go-dev-mcp
 
-go 1.23
+go 1.23.0
 
 toolchain go1.24.2
 
@@ -8,6 +8,8 @@ require (
 	github.com/mark3labs/mcp-go v0.29.0
 	github.com/olekukonko/tablewriter v0.0.5
 	github.com/stretchr/testify v1.9.0
+	golang.org/x/mod v0.24.0
+	golang.org/x/tools v0.32.0
 )
 
 require (
@@ -19,5 +21,6 @@ require (
 	github.com/spf13/cast v1.8.0 // indirect
 	github.com/stretchr/objx v0.5.2 // indirect
 	github.com/yosida95/uritemplate/v3 v3.0.2 // indirect
+	golang.org/x/sync v0.13.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -14,6 +14,16 @@ type Config struct {
 	SandboxType    string         `json:"sandboxType"`
 	ResourceLimits ResourceLimits `json:"resourceLimits"`
 	NLProcessing   NLProcessing   `json:"nlProcessing"`
+	Transport      Transport      `json:"transport"`
+	Run            Run            `json:"run"`
+	Jobs           Jobs           `json:"jobs"`
+	Cache          Cache          `json:"cache"`
+	Sessions       Sessions       `json:"sessions"`
+	Locks          Locks          `json:"locks"`
+	Concurrency    Concurrency    `json:"concurrency"`
+	Roots          Roots          `json:"roots"`
+	Network        Network        `json:"network"`
+	Analysis       Analysis       `json:"analysis"`
 }
 
 // ResourceLimits defines resource constraints for the execution environment
@@ -29,6 +39,89 @@ type NLProcessing struct {
 	MatchThreshold      float64 `json:"matchThreshold"`
 }
 
+// Transport configures how the server is exposed to MCP clients
+type Transport struct {
+	Type        string `json:"type"`       // stdio, sse or http (streamable HTTP)
+	ListenAddr  string `json:"listenAddr"` // host:port for the sse and http transports
+	BasePath    string `json:"basePath"`   // URL path the MCP endpoints are mounted under
+	TLSCertFile string `json:"tlsCertFile,omitempty"`
+	TLSKeyFile  string `json:"tlsKeyFile,omitempty"`
+	AuthToken   string `json:"authToken,omitempty"` // Bearer token required on every HTTP request
+	// SessionIdleTimeoutSecs ends streamable HTTP sessions without requests for
+	// this long; 0 keeps them until the client deletes them
+	SessionIdleTimeoutSecs int `json:"sessionIdleTimeoutSecs"`
+}
+
+// Run configures the programs started by go_run
+type Run struct {
+	// EnvAllowlist lists the environment variables a go_run request may set.
+	// Entries may be glob patterns such as APP_*.
+	EnvAllowlist []string `json:"envAllowlist"`
+}
+
+// Jobs configures tool calls run in the background with async set
+type Jobs struct {
+	MaxRunning    int `json:"maxRunning"`    // Jobs that may run at the same time
+	MaxRetained   int `json:"maxRetained"`   // Finished jobs kept for go_job_status and go_job_output
+	RetentionSecs int `json:"retentionSecs"` // How long finished jobs are kept
+	TimeoutSecs   int `json:"timeoutSecs"`   // Timeout for commands run by a job, replacing resourceLimits.timeoutSecs
+}
+
+// Cache configures the cache of build, vet, fmt and test results for code input
+type Cache struct {
+	Enabled   bool `json:"enabled"`
+	MaxSizeMB int  `json:"maxSizeMB"` // Total size of the cached output
+	TTLSecs   int  `json:"ttlSecs"`   // How long a result stays cached
+}
+
+// Sessions configures the persistent scratch modules created with go_session_create
+type Sessions struct {
+	MaxSessions     int `json:"maxSessions"`     // Sessions that may exist at the same time
+	MaxSizeMB       int `json:"maxSizeMB"`       // Disk quota of each session, checked when files are written
+	IdleTimeoutSecs int `json:"idleTimeoutSecs"` // Sessions unused for this long are deleted
+}
+
+// Locks configures the serialization of tool calls on the same project or workspace
+type Locks struct {
+	TimeoutSecs int `json:"timeoutSecs"` // How long a call waits for its lock before failing
+}
+
+// Concurrency configures the worker pool that bounds the go commands running at once
+type Concurrency struct {
+	Workers  int `json:"workers"`  // Commands run at once; 0 uses resourceLimits.cpuLimit
+	MaxQueue int `json:"maxQueue"` // Commands that may wait for a worker before the server reports busy
+}
+
+// Roots restricts the directories the tools may read and write
+type Roots struct {
+	// Allowed lists the directories that project, workspace and output paths
+	// must be inside. When empty and no client roots are known, any path is allowed.
+	Allowed []string `json:"allowed"`
+	// UseClientRoots adds the roots a client declares with the MCP roots capability
+	UseClientRoots bool `json:"useClientRoots"`
+}
+
+// Network configures which tools may reach the network. Offline tools run with
+// GOPROXY=off and GOFLAGS=-mod=mod, an environment scrubbed to EnvAllowlist and,
+// where the sandbox supports it, no network access at all.
+type Network struct {
+	Offline bool            `json:"offline"`         // Run every tool offline
+	Tools   map[string]bool `json:"tools,omitempty"` // Per-tool setting overriding offline, e.g. {"go_run": true}
+	// EnvAllowlist lists the server environment variables passed to offline
+	// commands. Entries may be glob patterns such as LC_*.
+	EnvAllowlist []string `json:"envAllowlist"`
+}
+
+// Analysis configures the analyzers go_analyze runs in-process next to go vet
+type Analysis struct {
+	// InProcess runs the analyzers in the server process. Loading the packages
+	// runs go list and type-checks them outside the sandbox, the resource limits
+	// and the process group kill; turn it off to run go vet only.
+	InProcess bool `json:"inProcess"`
+	// Analyzers turns each analyzer on or off by name, e.g. {"shadow": true}
+	Analyzers map[string]bool `json:"analyzers"`
+}
+
 // DefaultConfig returns the default configuration
 func DefaultConfig() *Config {
 	return &Config{
@@ -44,6 +137,59 @@ func DefaultConfig() *Config {
 			EnableFuzzyMatching: true,
 			MatchThreshold:      0.4,
 		},
+		Transport: Transport{
+			Type:                   "stdio",
+			ListenAddr:             "127.0.0.1:8080",
+			BasePath:               "/mcp",
+			SessionIdleTimeoutSecs: 1800,
+		},
+		Run: Run{
+			EnvAllowlist: []string{"APP_*", "DEBUG", "LOG_LEVEL", "PORT", "TZ"},
+		},
+		Jobs: Jobs{
+			MaxRunning:    4,
+			MaxRetained:   100,
+			RetentionSecs: 3600,
+			TimeoutSecs:   1800,
+		},
+		Cache: Cache{
+			Enabled:   true,
+			MaxSizeMB: 64,
+			TTLSecs:   3600,
+		},
+		Sessions: Sessions{
+			MaxSessions:     10,
+			MaxSizeMB:       100,
+			IdleTimeoutSecs: 3600,
+		},
+		Locks: Locks{
+			TimeoutSecs: 120,
+		},
+		Concurrency: Concurrency{
+			MaxQueue: 64,
+		},
+		Roots: Roots{
+			UseClientRoots: true,
+		},
+		Network: Network{
+			EnvAllowlist: []string{
+				"PATH", "HOME", "USER", "LANG", "LC_*", "TZ", "TMPDIR",
+				"GOROOT", "GOPATH", "GOCACHE", "GOMODCACHE", "GOOS", "GOARCH", "CGO_ENABLED", "CC", "CXX",
+				"SystemRoot", "USERPROFILE", "LOCALAPPDATA", "APPDATA",
+			},
+		},
+		Analysis: Analysis{
+			InProcess: true,
+			Analyzers: map[string]bool{
+				"nilness":        true,
+				"shadow":         false, // Reports many intentional shadowings
+				"unusedwrite":    true,
+				"errorsas":       true,
+				"error-wrapping": false, // Reports every error formatted with %v
+				"defer-in-loop":  true,
+				"context-leak":   true,
+			},
+		},
 	}
 }
 
@@ -71,14 +217,15 @@ func Load() (*Config, error) {
 		return nil, err
 	}
 
-	// Parse config
-	var config Config
-	err = json.Unmarshal(data, &config)
+	// Parse config on top of the defaults so sections missing from older
+	// config files keep sensible values
+	config := DefaultConfig()
+	err = json.Unmarshal(data, config)
 	if err != nil {
 		return nil, err
 	}
 
-	return &config, nil
+	return config, nil
 }
 
 // getConfigPath returns the path to the config file
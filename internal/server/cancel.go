@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// methodCancelled is the notification a client sends to cancel one of its requests
+const methodCancelled = "notifications/cancelled"
+
+// requestKey identifies an in-flight request within a session
+type requestKey struct {
+	session string
+	id      string
+}
+
+// inflightRequest is a tool call that the client may cancel
+type inflightRequest struct {
+	done    chan struct{}
+	once    sync.Once
+	release func()
+}
+
+// cancel signals the request's handler to stop
+func (r *inflightRequest) cancel() {
+	r.once.Do(func() { close(r.done) })
+}
+
+// inflightKey is the context key for the in-flight request being handled
+type inflightKey struct{}
+
+// cancellations tracks the in-flight tool calls of every session so that a
+// notifications/cancelled message stops the request it names. The transports
+// register requests; CancellationMiddleware turns the signal into a cancelled
+// handler context, which kills the go processes the handler started.
+type cancellations struct {
+	mu       sync.Mutex
+	inflight map[requestKey]*inflightRequest
+}
+
+// newCancellations creates an empty request tracker
+func newCancellations() *cancellations {
+	return &cancellations{inflight: make(map[requestKey]*inflightRequest)}
+}
+
+// track registers request id of session and returns a context carrying it,
+// along with a function that unregisters the request once it has been answered
+func (c *cancellations) track(ctx context.Context, session string, id any) (context.Context, func()) {
+	key := requestKey{session: session, id: fmt.Sprint(id)}
+	req := &inflightRequest{done: make(chan struct{})}
+	req.release = func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.inflight[key] == req {
+			delete(c.inflight, key)
+		}
+	}
+
+	c.mu.Lock()
+	c.inflight[key] = req
+	c.mu.Unlock()
+	return context.WithValue(ctx, inflightKey{}, req), req.release
+}
+
+// cancel handles a notifications/cancelled message from session. Requests that
+// already finished or are unknown are ignored, as the protocol requires.
+func (c *cancellations) cancel(session string, message []byte) {
+	var notification struct {
+		Params struct {
+			RequestID any    `json:"requestId"`
+			Reason    string `json:"reason"`
+		} `json:"params"`
+	}
+	if err := json.Unmarshal(message, &notification); err != nil || notification.Params.RequestID == nil {
+		return
+	}
+
+	key := requestKey{session: session, id: fmt.Sprint(notification.Params.RequestID)}
+	c.mu.Lock()
+	req, ok := c.inflight[key]
+	c.mu.Unlock()
+	if ok {
+		log.Printf("Request %v cancelled by client: %s", notification.Params.RequestID, notification.Params.Reason)
+		req.cancel()
+	}
+}
+
+// isToolCall reports whether method is a request that may run long enough to be cancelled
+func isToolCall(method mcp.MCPMethod) bool {
+	return method == mcp.MethodToolsCall
+}
+
+// CancellationMiddleware cancels the context of a tool handler when the client
+// cancels the request with notifications/cancelled
+func CancellationMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		inflight, ok := ctx.Value(inflightKey{}).(*inflightRequest)
+		if !ok {
+			return next(ctx, req)
+		}
+		defer inflight.release()
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go func() {
+			select {
+			case <-inflight.done:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		return next(ctx, req)
+	}
+}
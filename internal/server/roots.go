@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/MrFixit96/go-dev-mcp/internal/tools"
+)
+
+// methodRootsListChanged is the notification a client sends when its roots change
+const methodRootsListChanged = "notifications/roots/list_changed"
+
+// rootsRequestPrefix marks the IDs of roots/list requests sent by the server
+const rootsRequestPrefix = "roots-"
+
+// clientRoots asks a client that supports the roots capability for its roots
+// and hands them to the tools, which keep every path argument inside them
+type clientRoots struct {
+	session string
+
+	mu        sync.Mutex
+	supported bool
+	sent      int
+	pending   string // ID of the roots/list request awaiting a response
+}
+
+// initialize records whether the client declared the roots capability. Tool
+// calls from a client that did wait for its roots before their paths are checked.
+func (c *clientRoots) initialize(message []byte) {
+	var request struct {
+		Params struct {
+			Capabilities mcp.ClientCapabilities `json:"capabilities"`
+		} `json:"params"`
+	}
+	if json.Unmarshal(message, &request) != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.supported = request.Params.Capabilities.Roots != nil
+	if c.supported {
+		tools.ExpectClientRoots(c.session)
+	}
+}
+
+// request sends a roots/list request when the client supports roots
+func (c *clientRoots) request(write func(message any)) {
+	c.mu.Lock()
+	if !c.supported {
+		c.mu.Unlock()
+		return
+	}
+	c.sent++
+	c.pending = fmt.Sprintf("%s%d", rootsRequestPrefix, c.sent)
+	id := c.pending
+	c.mu.Unlock()
+
+	write(map[string]any{
+		"jsonrpc": mcp.JSONRPC_VERSION,
+		"id":      id,
+		"method":  "roots/list",
+	})
+}
+
+// response handles the client's answer to a roots/list request and reports
+// whether the message was one
+func (c *clientRoots) response(id any, message []byte) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := fmt.Sprint(id)
+	if !strings.HasPrefix(key, rootsRequestPrefix) {
+		return false
+	}
+	if key != c.pending {
+		// Superseded by a later request
+		return true
+	}
+	c.pending = ""
+
+	var response struct {
+		Result *mcp.ListRootsResult `json:"result"`
+		Error  *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(message, &response); err != nil || response.Result == nil {
+		if response.Error != nil {
+			log.Printf("Client did not list its roots: %s", response.Error.Message)
+		}
+		// Let waiting tool calls go ahead without client roots
+		tools.SetClientRoots(c.session, nil)
+		return true
+	}
+
+	uris := []string{}
+	for _, root := range response.Result.Roots {
+		uris = append(uris, root.URI)
+	}
+	log.Printf("Client roots: %v", uris)
+	tools.SetClientRoots(c.session, uris)
+	return true
+}
+
+// sessionRoots holds the client roots of the sessions of transports that do
+// not keep per-session state of their own, by session ID
+var sessionRoots sync.Map
+
+// ForgetClientRoots drops the client roots of a session once it ends. It is an
+// OnUnregisterSession hook for the MCP server.
+func ForgetClientRoots(ctx context.Context, session server.ClientSession) {
+	sessionRoots.Delete(session.SessionID())
+	tools.SetClientRoots(session.SessionID(), nil)
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// sseTransport inspects the messages posted to the SSE transport before the
+// SSE server handles them: it registers tool calls and handles their
+// cancellation, and asks clients that support roots for their roots
+type sseTransport struct {
+	sse     *server.SSEServer
+	cancels *cancellations
+}
+
+// newSSEServer creates the SSE server of the sse transport
+func newSSEServer(s *server.MCPServer, basePath string) *server.SSEServer {
+	t := &sseTransport{cancels: newCancellations()}
+	t.sse = server.NewSSEServer(s,
+		server.WithStaticBasePath(basePath),
+		server.WithSSEContextFunc(t.context))
+	return t.sse
+}
+
+// context is the SSE context function. The SSE server reads the message after
+// this runs, so the body is restored once it has been inspected.
+func (t *sseTransport) context(ctx context.Context, r *http.Request) context.Context {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBodySize))
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ctx
+	}
+
+	var envelope struct {
+		ID     any           `json:"id,omitempty"`
+		Method mcp.MCPMethod `json:"method"`
+	}
+	if json.Unmarshal(body, &envelope) != nil {
+		return ctx
+	}
+	session := r.URL.Query().Get("sessionId")
+	switch {
+	case envelope.Method == methodCancelled:
+		t.cancels.cancel(session, body)
+	case envelope.Method == mcp.MethodInitialize:
+		roots := &clientRoots{session: session}
+		sessionRoots.Store(session, roots)
+		roots.initialize(body)
+	case envelope.Method == "notifications/initialized" || envelope.Method == methodRootsListChanged:
+		if roots, ok := sessionRoots.Load(session); ok {
+			roots.(*clientRoots).request(func(message any) {
+				if err := t.sse.SendEventToSession(session, message); err != nil {
+					log.Printf("Failed to request client roots: %v", err)
+				}
+			})
+		}
+	case envelope.Method == "" && envelope.ID != nil:
+		// Responses to the server's own requests
+		if roots, ok := sessionRoots.Load(session); ok {
+			roots.(*clientRoots).response(envelope.ID, body)
+		}
+	case envelope.ID != nil && isToolCall(envelope.Method):
+		// The request is released by CancellationMiddleware once the tool returns
+		ctx, _ = t.cancels.track(ctx, session, envelope.ID)
+	}
+	return ctx
+}
@@ -0,0 +1,159 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/MrFixit96/go-dev-mcp/internal/tools"
+)
+
+// stdioSessionID identifies the single client of the stdio transport
+const stdioSessionID = "stdio"
+
+// ServeStdio serves MCP over newline-delimited JSON-RPC on in and out until in
+// is closed or ctx is cancelled. Unlike the stdio server of mcp-go, tool calls
+// are handled concurrently so that notifications/cancelled can stop a call
+// while it runs.
+func ServeStdio(ctx context.Context, s *server.MCPServer, in io.Reader, out io.Writer) error {
+	session := &stdioSession{notifications: make(chan mcp.JSONRPCNotification, 100)}
+	if err := s.RegisterSession(ctx, session); err != nil {
+		return err
+	}
+	defer s.UnregisterSession(ctx, stdioSessionID)
+	ctx = s.WithContext(ctx, session)
+
+	var writeMu sync.Mutex
+	write := func(message any) {
+		data, err := json.Marshal(message)
+		if err != nil {
+			log.Printf("Failed to marshal message: %v", err)
+			return
+		}
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		if _, err := out.Write(append(data, '\n')); err != nil {
+			log.Printf("Failed to write message: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		for {
+			select {
+			case notification := <-session.notifications:
+				write(notification)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Read in the background so that shutdown does not wait for input
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		reader := bufio.NewReader(in)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if len(bytes.TrimSpace(line)) > 0 {
+				select {
+				case lines <- line:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if err != nil {
+				readErr <- err
+				return
+			}
+		}
+	}()
+
+	cancels := newCancellations()
+	roots := &clientRoots{session: stdioSessionID}
+	defer tools.SetClientRoots(stdioSessionID, nil)
+	var calls sync.WaitGroup
+	defer calls.Wait()
+	for {
+		var line []byte
+		select {
+		case line = <-lines:
+		case err := <-readErr:
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		var envelope struct {
+			ID     any           `json:"id,omitempty"`
+			Method mcp.MCPMethod `json:"method"`
+		}
+		if !json.Valid(line) {
+			write(mcp.NewJSONRPCError(mcp.RequestId{}, mcp.PARSE_ERROR, "Parse error", nil))
+			continue
+		}
+		// Messages that are not single objects are left to HandleMessage
+		_ = json.Unmarshal(line, &envelope)
+		switch envelope.Method {
+		case methodCancelled:
+			cancels.cancel(stdioSessionID, line)
+		case mcp.MethodInitialize:
+			roots.initialize(line)
+		case "":
+			// Responses to the server's own requests
+			if envelope.ID != nil && roots.response(envelope.ID, line) {
+				continue
+			}
+		}
+
+		// Tool calls run concurrently; everything else is handled in order
+		if envelope.ID != nil && isToolCall(envelope.Method) {
+			calls.Add(1)
+			go func(line []byte, id any) {
+				defer calls.Done()
+				callCtx, release := cancels.track(ctx, stdioSessionID, id)
+				defer release()
+				if response := s.HandleMessage(callCtx, line); response != nil {
+					write(response)
+				}
+			}(line, envelope.ID)
+			continue
+		}
+		if response := s.HandleMessage(ctx, line); response != nil {
+			write(response)
+		}
+		if envelope.Method == "notifications/initialized" || envelope.Method == methodRootsListChanged {
+			roots.request(write)
+		}
+	}
+}
+
+// stdioSession is the client session of the stdio transport
+type stdioSession struct {
+	initialized   atomic.Bool
+	notifications chan mcp.JSONRPCNotification
+}
+
+func (s *stdioSession) SessionID() string { return stdioSessionID }
+
+func (s *stdioSession) Initialize() { s.initialized.Store(true) }
+
+func (s *stdioSession) Initialized() bool { return s.initialized.Load() }
+
+func (s *stdioSession) NotificationChannel() chan<- mcp.JSONRPCNotification {
+	return s.notifications
+}
@@ -0,0 +1,170 @@
+package server_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	customServer "github.com/MrFixit96/go-dev-mcp/internal/server"
+	"github.com/MrFixit96/go-dev-mcp/internal/tools"
+)
+
+func TestStdioCancellation(t *testing.T) {
+	s := server.NewMCPServer("test", "0.0.0",
+		server.WithToolCapabilities(true),
+		server.WithToolHandlerMiddleware(customServer.CancellationMiddleware))
+	started := make(chan struct{})
+	s.AddTool(mcp.NewTool("wait"),
+		func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			close(started)
+			<-ctx.Done()
+			return mcp.NewToolResultError("cancelled: " + ctx.Err().Error()), nil
+		})
+	s.AddTool(mcp.NewTool("echo", mcp.WithString("text")),
+		func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText(mcp.ParseString(req, "text", "")), nil
+		})
+
+	clientIn, serverOut := io.Pipe()
+	serverIn, clientOut := io.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go customServer.ServeStdio(ctx, s, serverIn, serverOut)
+
+	encoder := json.NewEncoder(clientOut)
+	responses := make(chan map[string]interface{}, 10)
+	go func() {
+		scanner := bufio.NewScanner(clientIn)
+		for scanner.Scan() {
+			var message map[string]interface{}
+			if json.Unmarshal(scanner.Bytes(), &message) == nil {
+				responses <- message
+			}
+		}
+	}()
+	next := func() map[string]interface{} {
+		select {
+		case message := <-responses:
+			return message
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for a response")
+			return nil
+		}
+	}
+
+	require.NoError(t, encoder.Encode(map[string]interface{}{
+		"jsonrpc": "2.0", "id": 1, "method": "initialize",
+		"params": map[string]interface{}{"protocolVersion": "2025-03-26", "clientInfo": map[string]interface{}{"name": "test", "version": "1"}},
+	}))
+	assert.EqualValues(t, 1, next()["id"])
+	require.NoError(t, encoder.Encode(map[string]interface{}{"jsonrpc": "2.0", "method": "notifications/initialized"}))
+
+	// A long tool call does not block other requests
+	require.NoError(t, encoder.Encode(map[string]interface{}{
+		"jsonrpc": "2.0", "id": 2, "method": "tools/call", "params": map[string]interface{}{"name": "wait"},
+	}))
+	<-started
+	require.NoError(t, encoder.Encode(map[string]interface{}{
+		"jsonrpc": "2.0", "id": 3, "method": "tools/call",
+		"params": map[string]interface{}{"name": "echo", "arguments": map[string]interface{}{"text": "hi"}},
+	}))
+	assert.EqualValues(t, 3, next()["id"])
+
+	// Cancelling the first call stops its handler
+	require.NoError(t, encoder.Encode(map[string]interface{}{
+		"jsonrpc": "2.0", "method": "notifications/cancelled",
+		"params": map[string]interface{}{"requestId": 2, "reason": "user abort"},
+	}))
+	response := next()
+	assert.EqualValues(t, 2, response["id"])
+	data, _ := json.Marshal(response["result"])
+	assert.Contains(t, string(data), "context canceled")
+}
+
+func TestStdioClientRoots(t *testing.T) {
+	s := server.NewMCPServer("test", "0.0.0",
+		server.WithToolCapabilities(true),
+		server.WithToolHandlerMiddleware(tools.PathPolicyMiddleware))
+	s.AddTool(mcp.NewTool("use_path", mcp.WithString("project_path")),
+		func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText("ok"), nil
+		})
+	allowed := t.TempDir()
+	outside := t.TempDir()
+
+	clientIn, serverOut := io.Pipe()
+	serverIn, clientOut := io.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go customServer.ServeStdio(ctx, s, serverIn, serverOut)
+
+	encoder := json.NewEncoder(clientOut)
+	messages := make(chan map[string]interface{}, 10)
+	go func() {
+		scanner := bufio.NewScanner(clientIn)
+		for scanner.Scan() {
+			var message map[string]interface{}
+			if json.Unmarshal(scanner.Bytes(), &message) == nil {
+				messages <- message
+			}
+		}
+	}()
+	next := func() map[string]interface{} {
+		select {
+		case message := <-messages:
+			return message
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for a message")
+			return nil
+		}
+	}
+	callTool := func(id int, path string) bool {
+		require.NoError(t, encoder.Encode(map[string]interface{}{
+			"jsonrpc": "2.0", "id": id, "method": "tools/call",
+			"params": map[string]interface{}{"name": "use_path", "arguments": map[string]interface{}{"project_path": path}},
+		}))
+		response := next()
+		require.EqualValues(t, id, response["id"])
+		return response["result"].(map[string]interface{})["isError"] == true
+	}
+
+	require.NoError(t, encoder.Encode(map[string]interface{}{
+		"jsonrpc": "2.0", "id": 1, "method": "initialize",
+		"params": map[string]interface{}{
+			"protocolVersion": "2025-03-26",
+			"clientInfo":      map[string]interface{}{"name": "test", "version": "1"},
+			"capabilities":    map[string]interface{}{"roots": map[string]interface{}{"listChanged": true}},
+		},
+	}))
+	assert.EqualValues(t, 1, next()["id"])
+	require.NoError(t, encoder.Encode(map[string]interface{}{"jsonrpc": "2.0", "method": "notifications/initialized"}))
+
+	// The server asks for the client's roots once it is initialized
+	request := next()
+	require.Equal(t, "roots/list", request["method"])
+	require.NoError(t, encoder.Encode(map[string]interface{}{
+		"jsonrpc": "2.0", "id": request["id"],
+		"result": map[string]interface{}{"roots": []interface{}{map[string]interface{}{"uri": "file://" + allowed, "name": "project"}}},
+	}))
+
+	assert.False(t, callTool(2, allowed), "path inside the client roots should be allowed")
+	assert.True(t, callTool(3, outside), "path outside the client roots should be rejected")
+
+	// Changed roots are requested again
+	require.NoError(t, encoder.Encode(map[string]interface{}{"jsonrpc": "2.0", "method": "notifications/roots/list_changed"}))
+	request = next()
+	require.Equal(t, "roots/list", request["method"])
+	require.NoError(t, encoder.Encode(map[string]interface{}{
+		"jsonrpc": "2.0", "id": request["id"],
+		"result": map[string]interface{}{"roots": []interface{}{map[string]interface{}{"uri": "file://" + outside}}},
+	}))
+	assert.False(t, callTool(4, outside), "path inside the new client roots should be allowed")
+}
@@ -0,0 +1,444 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/MrFixit96/go-dev-mcp/internal/config"
+	"github.com/MrFixit96/go-dev-mcp/internal/tools"
+)
+
+// Supported transport types
+const (
+	TransportStdio = "stdio"
+	TransportSSE   = "sse"
+	TransportHTTP  = "http"
+)
+
+// sessionIDHeader is the header used by the streamable HTTP transport to
+// associate requests with a session
+const sessionIDHeader = "Mcp-Session-Id"
+
+// maxRequestBodySize bounds the size of a single JSON-RPC message accepted over HTTP
+const maxRequestBodySize = 4 << 20
+
+// Serve exposes the MCP server using the transport selected in cfg.
+// It blocks until the transport stops or ctx is cancelled.
+func Serve(ctx context.Context, s *server.MCPServer, cfg config.Transport) error {
+	switch strings.ToLower(cfg.Type) {
+	case "", TransportStdio:
+		log.Println("Serving MCP over stdio")
+		return ServeStdio(ctx, s, os.Stdin, os.Stdout)
+	case TransportSSE, TransportHTTP:
+		handler, err := NewHTTPHandler(s, cfg)
+		if err != nil {
+			return err
+		}
+		return serveHTTP(ctx, handler, cfg)
+	default:
+		return fmt.Errorf("unknown transport type: %s (expected stdio, sse or http)", cfg.Type)
+	}
+}
+
+// NewHTTPHandler builds the http.Handler for the sse or http transports,
+// including bearer-token authentication when an auth token is configured.
+func NewHTTPHandler(s *server.MCPServer, cfg config.Transport) (http.Handler, error) {
+	basePath := cfg.BasePath
+	if basePath == "" {
+		basePath = "/mcp"
+	}
+
+	var handler http.Handler
+	switch strings.ToLower(cfg.Type) {
+	case TransportSSE:
+		handler = newSSEServer(s, basePath)
+	case TransportHTTP:
+		mux := http.NewServeMux()
+		mux.Handle(basePath, NewStreamableHTTPHandler(s, time.Duration(cfg.SessionIdleTimeoutSecs)*time.Second))
+		handler = mux
+	default:
+		return nil, fmt.Errorf("transport %q is not an HTTP transport", cfg.Type)
+	}
+
+	if cfg.AuthToken != "" {
+		handler = bearerAuth(cfg.AuthToken, handler)
+	}
+	return handler, nil
+}
+
+// serveHTTP runs an HTTP(S) server for handler until ctx is cancelled
+func serveHTTP(ctx context.Context, handler http.Handler, cfg config.Transport) error {
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		return fmt.Errorf("both tlsCertFile and tlsKeyFile must be set to enable TLS")
+	}
+	if cfg.AuthToken == "" {
+		log.Printf("Warning: serving MCP over %s without an auth token", cfg.Type)
+	}
+
+	srv := &http.Server{
+		Addr:              cfg.ListenAddr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		if cfg.TLSCertFile != "" {
+			log.Printf("Serving MCP over %s on https://%s%s", cfg.Type, cfg.ListenAddr, cfg.BasePath)
+			errCh <- srv.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+		} else {
+			log.Printf("Serving MCP over %s on http://%s%s", cfg.Type, cfg.ListenAddr, cfg.BasePath)
+			errCh <- srv.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	}
+}
+
+// bearerAuth rejects requests that do not carry the expected bearer token
+func bearerAuth(token string, next http.Handler) http.Handler {
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		provided := []byte(r.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(provided, expected) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="go-dev-mcp"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// StreamableHTTPHandler implements the MCP streamable HTTP transport.
+// Clients POST JSON-RPC messages to a single endpoint; requests are answered
+// either with a JSON body or, when the client accepts text/event-stream, with
+// an SSE stream carrying notifications emitted while the request runs, and
+// requests the server makes of the client, followed by the final response. Sessions end with a DELETE or, since clients may
+// disconnect without one, once they have been idle for the idle timeout.
+type StreamableHTTPHandler struct {
+	server      *server.MCPServer
+	sessions    sync.Map // session ID -> *httpSession
+	cancels     *cancellations
+	idleTimeout time.Duration
+	sweeper     sync.Once
+}
+
+// NewStreamableHTTPHandler creates a streamable HTTP handler for the MCP
+// server. Sessions idle for longer than idleTimeout expire; zero keeps them
+// until they are deleted.
+func NewStreamableHTTPHandler(s *server.MCPServer, idleTimeout time.Duration) *StreamableHTTPHandler {
+	return &StreamableHTTPHandler{server: s, cancels: newCancellations(), idleTimeout: idleTimeout}
+}
+
+// ServeHTTP implements http.Handler
+func (h *StreamableHTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		h.handlePost(w, r)
+	case http.MethodDelete:
+		h.handleDelete(w, r)
+	default:
+		w.Header().Set("Allow", "POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handlePost processes a single JSON-RPC message
+func (h *StreamableHTTPHandler) handlePost(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBodySize))
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	var envelope struct {
+		ID     any           `json:"id,omitempty"`
+		Method mcp.MCPMethod `json:"method"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		writeJSON(w, http.StatusBadRequest, mcp.NewJSONRPCError(mcp.RequestId{}, mcp.PARSE_ERROR, "Parse error", nil))
+		return
+	}
+
+	// Resolve the session; initialize starts a new one
+	var session *httpSession
+	if envelope.Method == mcp.MethodInitialize {
+		session, err = h.newSession(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// Requests to the client travel on response streams, so only a
+		// client that accepts them can be asked for its roots
+		if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+			session.roots.initialize(body)
+		}
+	} else {
+		id := r.Header.Get(sessionIDHeader)
+		if id == "" {
+			http.Error(w, "missing "+sessionIDHeader+" header", http.StatusBadRequest)
+			return
+		}
+		value, ok := h.sessions.Load(id)
+		if !ok {
+			http.Error(w, "unknown or expired session", http.StatusNotFound)
+			return
+		}
+		session = value.(*httpSession)
+	}
+	session.touch(1)
+	defer session.touch(-1)
+	if _, ok := h.sessions.Load(session.id); !ok {
+		// Expired while the request was arriving
+		http.Error(w, "unknown or expired session", http.StatusNotFound)
+		return
+	}
+	w.Header().Set(sessionIDHeader, session.id)
+
+	// Notifications and responses produce no JSON-RPC reply
+	if envelope.ID == nil {
+		if envelope.Method == methodCancelled {
+			h.cancels.cancel(session.id, body)
+		}
+		h.server.HandleMessage(h.server.WithContext(r.Context(), session), body)
+		if envelope.Method == "notifications/initialized" || envelope.Method == methodRootsListChanged {
+			session.roots.request(session.send)
+		}
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	if envelope.Method == "" {
+		// Responses to the server's own requests
+		session.roots.response(envelope.ID, body)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	// Each request gets its own notification channel so progress and log
+	// messages are delivered on the response of the request that caused them
+	reqSession := &requestSession{httpSession: session, notifications: make(chan mcp.JSONRPCNotification, 100)}
+	ctx := h.server.WithContext(r.Context(), reqSession)
+	if isToolCall(envelope.Method) {
+		var release func()
+		ctx, release = h.cancels.track(ctx, session.id, envelope.ID)
+		defer release()
+	}
+
+	flusher, canStream := w.(http.Flusher)
+	if !canStream || !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		response := h.server.HandleMessage(ctx, body)
+		writeJSON(w, http.StatusOK, response)
+		return
+	}
+
+	done := make(chan mcp.JSONRPCMessage, 1)
+	go func() {
+		done <- h.server.HandleMessage(ctx, body)
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case notification := <-reqSession.notifications:
+			writeSSEEvent(w, notification)
+			flusher.Flush()
+		case request := <-session.requests:
+			writeSSEEvent(w, request)
+			flusher.Flush()
+		case response := <-done:
+			// Deliver anything emitted just before the handler returned
+			for pending := len(reqSession.notifications); pending > 0; pending-- {
+				writeSSEEvent(w, <-reqSession.notifications)
+			}
+			writeSSEEvent(w, response)
+			flusher.Flush()
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// handleDelete terminates a session
+func (h *StreamableHTTPHandler) handleDelete(w http.ResponseWriter, r *http.Request) {
+	if !h.endSession(r.Context(), r.Header.Get(sessionIDHeader)) {
+		http.Error(w, "unknown or expired session", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// endSession unregisters a session and stops its goroutine, reporting whether
+// the session existed
+func (h *StreamableHTTPHandler) endSession(ctx context.Context, id string) bool {
+	value, ok := h.sessions.LoadAndDelete(id)
+	if !ok {
+		return false
+	}
+	h.server.UnregisterSession(ctx, id)
+	tools.SetClientRoots(id, nil)
+	close(value.(*httpSession).done)
+	return true
+}
+
+// sweepIdleSessions ends the sessions that have no request in flight and have
+// been idle for longer than the idle timeout, checking twice per timeout
+func (h *StreamableHTTPHandler) sweepIdleSessions() {
+	ticker := time.NewTicker(h.idleTimeout / 2)
+	defer ticker.Stop()
+	for range ticker.C {
+		h.sessions.Range(func(key, value any) bool {
+			if session := value.(*httpSession); session.idleSince(h.idleTimeout) {
+				log.Printf("HTTP session %s expired after %v without requests", session.id, h.idleTimeout)
+				h.endSession(context.Background(), session.id)
+			}
+			return true
+		})
+	}
+}
+
+// newSession creates and registers a new HTTP session
+func (h *StreamableHTTPHandler) newSession(ctx context.Context) (*httpSession, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate session ID: %v", err)
+	}
+	id := hex.EncodeToString(buf)
+	session := &httpSession{
+		id:            id,
+		notifications: make(chan mcp.JSONRPCNotification, 100),
+		requests:      make(chan any, 10),
+		done:          make(chan struct{}),
+		roots:         &clientRoots{session: id},
+	}
+	session.touch(0)
+	if err := h.server.RegisterSession(ctx, session); err != nil {
+		return nil, err
+	}
+	h.sessions.Store(session.id, session)
+	go session.discardBroadcasts()
+	if h.idleTimeout > 0 {
+		h.sweeper.Do(func() { go h.sweepIdleSessions() })
+	}
+	return session, nil
+}
+
+// httpSession is a client session established over the streamable HTTP transport.
+// Broadcast notifications that arrive outside of a request are dropped since
+// this transport does not keep a standalone stream open. Requests from the
+// server to the client wait for the next request answered with a stream.
+type httpSession struct {
+	id            string
+	initialized   atomic.Bool
+	notifications chan mcp.JSONRPCNotification
+	requests      chan any // Server requests awaiting a response stream
+	done          chan struct{}
+	roots         *clientRoots
+	lastActive    atomic.Int64 // Unix nanoseconds of the last request
+	inFlight      atomic.Int32 // Requests being handled
+}
+
+// touch records activity on the session, adding delta to the requests in flight
+func (s *httpSession) touch(delta int32) {
+	s.inFlight.Add(delta)
+	s.lastActive.Store(time.Now().UnixNano())
+}
+
+// idleSince reports whether the session has had no request for longer than timeout
+func (s *httpSession) idleSince(timeout time.Duration) bool {
+	return s.inFlight.Load() == 0 && time.Since(time.Unix(0, s.lastActive.Load())) > timeout
+}
+
+// send queues a request to the client for the next response stream
+func (s *httpSession) send(request any) {
+	select {
+	case s.requests <- request:
+	default:
+		log.Printf("Dropped a request to HTTP session %s: too many pending requests", s.id)
+	}
+}
+
+func (s *httpSession) SessionID() string { return s.id }
+
+func (s *httpSession) Initialize() { s.initialized.Store(true) }
+
+func (s *httpSession) Initialized() bool { return s.initialized.Load() }
+
+func (s *httpSession) NotificationChannel() chan<- mcp.JSONRPCNotification {
+	return s.notifications
+}
+
+// discardBroadcasts drains session-wide notifications until the session ends
+func (s *httpSession) discardBroadcasts() {
+	for {
+		select {
+		case <-s.notifications:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// requestSession scopes notifications to a single in-flight request
+type requestSession struct {
+	*httpSession
+	notifications chan mcp.JSONRPCNotification
+}
+
+func (s *requestSession) NotificationChannel() chan<- mcp.JSONRPCNotification {
+	return s.notifications
+}
+
+// writeJSON writes v as a JSON response body
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write HTTP response: %v", err)
+	}
+}
+
+// writeSSEEvent writes v as a single SSE message event
+func writeSSEEvent(w io.Writer, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("Failed to marshal SSE event: %v", err)
+		return
+	}
+	fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+}
+
+var (
+	_ server.ClientSession = (*httpSession)(nil)
+	_ server.ClientSession = (*requestSession)(nil)
+)
@@ -0,0 +1,379 @@
+package server_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MrFixit96/go-dev-mcp/internal/config"
+	customServer "github.com/MrFixit96/go-dev-mcp/internal/server"
+	"github.com/MrFixit96/go-dev-mcp/internal/tools"
+)
+
+const testAuthToken = "secret-token"
+
+// newTestMCPServer creates an MCP server with a single echo tool
+func newTestMCPServer() *server.MCPServer {
+	s := server.NewMCPServer("test", "0.0.0", server.WithToolCapabilities(true))
+	s.AddTool(mcp.NewTool("echo", mcp.WithString("text")),
+		func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText(mcp.ParseString(req, "text", "")), nil
+		})
+	return s
+}
+
+// postRPC sends a JSON-RPC message to the streamable HTTP endpoint
+func postRPC(t *testing.T, url, sessionID, accept string, message map[string]interface{}) *http.Response {
+	t.Helper()
+	body, err := json.Marshal(message)
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+testAuthToken)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if sessionID != "" {
+		req.Header.Set("Mcp-Session-Id", sessionID)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+func TestStreamableHTTPTransport(t *testing.T) {
+	handler, err := customServer.NewHTTPHandler(newTestMCPServer(), config.Transport{
+		Type:      customServer.TransportHTTP,
+		BasePath:  "/mcp",
+		AuthToken: testAuthToken,
+	})
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+	endpoint := ts.URL + "/mcp"
+
+	// Requests without the bearer token are rejected
+	resp, err := http.Post(endpoint, "application/json", strings.NewReader(`{}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// Initialize a session
+	resp = postRPC(t, endpoint, "", "", map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "initialize",
+		"params": map[string]interface{}{
+			"protocolVersion": mcp.LATEST_PROTOCOL_VERSION,
+			"clientInfo":      map[string]interface{}{"name": "test", "version": "1.0"},
+		},
+	})
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	sessionID := resp.Header.Get("Mcp-Session-Id")
+	require.NotEmpty(t, sessionID)
+
+	// Notifications are accepted without a reply
+	resp = postRPC(t, endpoint, sessionID, "", map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "notifications/initialized",
+	})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	// Tool calls work with a plain JSON response
+	call := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      2,
+		"method":  "tools/call",
+		"params": map[string]interface{}{
+			"name":      "echo",
+			"arguments": map[string]interface{}{"text": "hello"},
+		},
+	}
+	resp = postRPC(t, endpoint, sessionID, "application/json", call)
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	assert.Contains(t, string(data), `"text":"hello"`)
+
+	// And with an SSE stream when the client accepts one
+	resp = postRPC(t, endpoint, sessionID, "application/json, text/event-stream", call)
+	data, err = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	assert.Contains(t, string(data), "event: message")
+	assert.Contains(t, string(data), `"text":"hello"`)
+
+	// Unknown sessions are rejected
+	resp = postRPC(t, endpoint, "does-not-exist", "", call)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// Sessions can be terminated
+	req, err := http.NewRequest(http.MethodDelete, endpoint, nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+testAuthToken)
+	req.Header.Set("Mcp-Session-Id", sessionID)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp = postRPC(t, endpoint, sessionID, "", call)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestSSETransportRequiresAuth(t *testing.T) {
+	handler, err := customServer.NewHTTPHandler(newTestMCPServer(), config.Transport{
+		Type:      customServer.TransportSSE,
+		BasePath:  "/mcp",
+		AuthToken: testAuthToken,
+	})
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/mcp/sse")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// With the token the SSE stream opens and announces the message endpoint
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/mcp/sse", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+testAuthToken)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	resp, err = http.DefaultClient.Do(req.WithContext(ctx))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	buf := make([]byte, 256)
+	n, _ := resp.Body.Read(buf)
+	assert.Contains(t, string(buf[:n]), "event: endpoint")
+	assert.Contains(t, string(buf[:n]), "/mcp/message")
+}
+
+func TestNewHTTPHandlerRejectsStdio(t *testing.T) {
+	_, err := customServer.NewHTTPHandler(newTestMCPServer(), config.Transport{Type: customServer.TransportStdio})
+	assert.Error(t, err)
+}
+
+func TestStreamableHTTPSessionExpiry(t *testing.T) {
+	ts := httptest.NewServer(customServer.NewStreamableHTTPHandler(newTestMCPServer(), 300*time.Millisecond))
+	defer ts.Close()
+
+	resp := postRPC(t, ts.URL, "", "", map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "initialize",
+		"params": map[string]interface{}{
+			"protocolVersion": mcp.LATEST_PROTOCOL_VERSION,
+			"clientInfo":      map[string]interface{}{"name": "test", "version": "1.0"},
+		},
+	})
+	resp.Body.Close()
+	sessionID := resp.Header.Get("Mcp-Session-Id")
+	require.NotEmpty(t, sessionID)
+	ping := map[string]interface{}{"jsonrpc": "2.0", "id": 2, "method": "ping"}
+
+	// Requests keep the session alive
+	for i := 0; i < 3; i++ {
+		time.Sleep(150 * time.Millisecond)
+		resp = postRPC(t, ts.URL, sessionID, "", ping)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	// An idle session expires
+	time.Sleep(time.Second)
+	resp = postRPC(t, ts.URL, sessionID, "", ping)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+// newRootsTestServer creates an MCP server whose use_path tool is subject to
+// the path policy
+func newRootsTestServer() *server.MCPServer {
+	s := server.NewMCPServer("test", "0.0.0",
+		server.WithToolCapabilities(true),
+		server.WithToolHandlerMiddleware(tools.PathPolicyMiddleware))
+	s.AddTool(mcp.NewTool("use_path", mcp.WithString("project_path")),
+		func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText("ok"), nil
+		})
+	return s
+}
+
+// rootsInitialize is an initialize request from a client with the roots capability
+var rootsInitialize = map[string]interface{}{
+	"jsonrpc": "2.0",
+	"id":      1,
+	"method":  "initialize",
+	"params": map[string]interface{}{
+		"protocolVersion": mcp.LATEST_PROTOCOL_VERSION,
+		"clientInfo":      map[string]interface{}{"name": "test", "version": "1.0"},
+		"capabilities":    map[string]interface{}{"roots": map[string]interface{}{"listChanged": true}},
+	},
+}
+
+// usePath is a call of the use_path tool
+func usePath(id int, path string) map[string]interface{} {
+	return map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"method":  "tools/call",
+		"params": map[string]interface{}{
+			"name":      "use_path",
+			"arguments": map[string]interface{}{"project_path": path},
+		},
+	}
+}
+
+// rootsResult answers the roots/list request with one root
+func rootsResult(request map[string]interface{}, root string) map[string]interface{} {
+	return map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      request["id"],
+		"result":  map[string]interface{}{"roots": []interface{}{map[string]interface{}{"uri": "file://" + root}}},
+	}
+}
+
+// readSSEEvent reads the next event from an SSE stream
+func readSSEEvent(t *testing.T, r *bufio.Reader) (event, data string) {
+	t.Helper()
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		case line == "" && data != "":
+			return event, data
+		}
+	}
+}
+
+// readSSEMessage reads the next JSON-RPC message from an SSE stream
+func readSSEMessage(t *testing.T, r *bufio.Reader) map[string]interface{} {
+	t.Helper()
+	_, data := readSSEEvent(t, r)
+	var message map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(data), &message))
+	return message
+}
+
+// isToolError reports whether a tools/call response carries a tool error
+func isToolError(t *testing.T, response map[string]interface{}) bool {
+	t.Helper()
+	result, ok := response["result"].(map[string]interface{})
+	require.True(t, ok, "expected a result, got %v", response)
+	return result["isError"] == true
+}
+
+func TestStreamableHTTPClientRoots(t *testing.T) {
+	allowed := t.TempDir()
+	outside := t.TempDir()
+	ts := httptest.NewServer(customServer.NewStreamableHTTPHandler(newRootsTestServer(), 0))
+	defer ts.Close()
+	const stream = "application/json, text/event-stream"
+
+	resp := postRPC(t, ts.URL, "", stream, rootsInitialize)
+	resp.Body.Close()
+	sessionID := resp.Header.Get("Mcp-Session-Id")
+	require.NotEmpty(t, sessionID)
+	resp = postRPC(t, ts.URL, sessionID, "", map[string]interface{}{"jsonrpc": "2.0", "method": "notifications/initialized"})
+	resp.Body.Close()
+
+	// The first call waits for the roots, which are requested on its stream
+	resp = postRPC(t, ts.URL, sessionID, stream, usePath(2, outside))
+	defer resp.Body.Close()
+	events := bufio.NewReader(resp.Body)
+	request := readSSEMessage(t, events)
+	require.Equal(t, "roots/list", request["method"])
+	answer := postRPC(t, ts.URL, sessionID, "", rootsResult(request, allowed))
+	answer.Body.Close()
+	assert.Equal(t, http.StatusAccepted, answer.StatusCode)
+	response := readSSEMessage(t, events)
+	assert.EqualValues(t, 2, response["id"])
+	assert.True(t, isToolError(t, response), "path outside the client roots should be rejected")
+
+	resp = postRPC(t, ts.URL, sessionID, "application/json", usePath(3, allowed))
+	defer resp.Body.Close()
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	assert.False(t, isToolError(t, response), "path inside the client roots should be allowed")
+}
+
+func TestSSEClientRoots(t *testing.T) {
+	allowed := t.TempDir()
+	outside := t.TempDir()
+	handler, err := customServer.NewHTTPHandler(newRootsTestServer(), config.Transport{
+		Type:      customServer.TransportSSE,
+		BasePath:  "/mcp",
+		AuthToken: testAuthToken,
+	})
+	require.NoError(t, err)
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/mcp/sse", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+testAuthToken)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	events := bufio.NewReader(resp.Body)
+	event, endpoint := readSSEEvent(t, events)
+	require.Equal(t, "endpoint", event)
+	post := func(message map[string]interface{}) {
+		resp := postRPC(t, ts.URL+endpoint, "", "", message)
+		resp.Body.Close()
+		require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	}
+
+	post(rootsInitialize)
+	assert.EqualValues(t, 1, readSSEMessage(t, events)["id"])
+	post(map[string]interface{}{"jsonrpc": "2.0", "method": "notifications/initialized"})
+
+	// The server asks for the roots once the client is initialized, and calls
+	// made before they arrive wait for them
+	request := readSSEMessage(t, events)
+	require.Equal(t, "roots/list", request["method"])
+	post(usePath(2, outside))
+	post(rootsResult(request, allowed))
+	response := readSSEMessage(t, events)
+	assert.EqualValues(t, 2, response["id"])
+	assert.True(t, isToolError(t, response), "path outside the client roots should be rejected")
+
+	post(usePath(3, allowed))
+	response = readSSEMessage(t, events)
+	assert.EqualValues(t, 3, response["id"])
+	assert.False(t, isToolError(t, response), "path inside the client roots should be allowed")
+}
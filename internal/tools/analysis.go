@@ -0,0 +1,266 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/checker"
+	"golang.org/x/tools/go/analysis/passes/errorsas"
+	"golang.org/x/tools/go/analysis/passes/lostcancel"
+	"golang.org/x/tools/go/analysis/passes/nilness"
+	"golang.org/x/tools/go/analysis/passes/shadow"
+	"golang.org/x/tools/go/analysis/passes/unusedwrite"
+	"golang.org/x/tools/go/packages"
+)
+
+// driverAnalyzers are the analyzers go_analyze can run in-process, by the name
+// diagnostics report them under
+var driverAnalyzers = map[string]*analysis.Analyzer{
+	"nilness":        nilness.Analyzer,
+	"shadow":         shadow.Analyzer,
+	"unusedwrite":    unusedwrite.Analyzer,
+	"errorsas":       errorsas.Analyzer,
+	"error-wrapping": errorWrappingAnalyzer,
+	"defer-in-loop":  deferInLoopAnalyzer,
+	"context-leak":   lostcancel.Analyzer,
+}
+
+// configuredAnalyzers returns the names of the in-process analyzers the
+// configuration enables, sorted; none when analysis.inProcess is off
+func configuredAnalyzers() []string {
+	if !getConfig().Analysis.InProcess {
+		return nil
+	}
+	var names []string
+	for name, enabled := range getConfig().Analysis.Analyzers {
+		if enabled && driverAnalyzers[name] != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// requestAnalyzers returns the in-process analyzers to run for a request and
+// whether go vet runs. analyzers runs only the named analyzers, whether go vet
+// or in-process ones, so go vet is skipped when it names none of go vet's;
+// otherwise the configured analyzers run. disable turns analyzers off, and
+// vet: false skips go vet. The names were validated by vetFlags.
+func requestAnalyzers(req mcp.CallToolRequest) ([]string, bool) {
+	runVet := mcp.ParseBoolean(req, "vet", true)
+	selected := make(map[string]bool)
+	if enable := stringArrayArg(req, "analyzers"); len(enable) > 0 {
+		known, _ := vetAnalyzers()
+		namesVet := false
+		for _, name := range enable {
+			selected[name] = driverAnalyzers[name] != nil
+			namesVet = namesVet || known[name]
+		}
+		runVet = runVet && namesVet
+	} else {
+		for _, name := range configuredAnalyzers() {
+			selected[name] = true
+		}
+	}
+	for _, name := range stringArrayArg(req, "disable") {
+		delete(selected, name)
+	}
+
+	var names []string
+	for name, run := range selected {
+		if run {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, runVet
+}
+
+// runAnalyzers loads the packages of the input with their syntax and types and
+// runs the named in-process analyzers on them. Code input is written to a
+// temporary module, code combined with a project is overlaid onto it, and a
+// workspace is loaded module by module unless module selects packages. Errors
+// loading the packages are returned as error details.
+func runAnalyzers(ctx context.Context, input InputContext, module string, names []string) ([]AnalysisDiagnostic, []ErrorDetail, error) {
+	analyzers := make([]*analysis.Analyzer, len(names))
+	byAnalyzer := make(map[*analysis.Analyzer]string, len(names))
+	for i, name := range names {
+		analyzers[i] = driverAnalyzers[name]
+		byAnalyzer[analyzers[i]] = name
+	}
+
+	type load struct {
+		dir      string
+		patterns []string
+	}
+	var loads []load
+	overlay := map[string][]byte{}
+	root := diagnosticRoot(input)
+
+	switch input.Source {
+	case SourceCode:
+		tmpDir, err := os.MkdirTemp("", "go-analyze-*")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create temp directory: %v", err)
+		}
+		defer os.RemoveAll(tmpDir)
+		if err := writeCodeModule(ctx, tmpDir, input, "analyze"); err != nil {
+			return nil, nil, fmt.Errorf("failed to prepare code: %w", err)
+		}
+		loads = append(loads, load{tmpDir, []string{"./..."}})
+	case SourceHybrid:
+		files, err := input.sourceFiles()
+		if err != nil {
+			return nil, nil, err
+		}
+		// Overlay keys must be absolute
+		project, err := filepath.Abs(input.ProjectPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		for name, content := range files {
+			overlay[filepath.Join(project, filepath.FromSlash(name))] = []byte(content)
+		}
+		loads = append(loads, load{project, input.sourcePackages()})
+	case SourceWorkspace:
+		if module != "" {
+			loads = append(loads, load{input.WorkspacePath, []string{module}})
+			break
+		}
+		for _, dir := range input.WorkspaceModules {
+			loads = append(loads, load{workspaceModuleDir(input.WorkspacePath, dir), []string{"./..."}})
+		}
+	default:
+		loads = append(loads, load{input.ProjectPath, []string{"./..."}})
+	}
+
+	// Analysis is as heavy as a go command, so it takes a worker too
+	release, _, err := workers.acquire(ctx, clientFromContext(ctx))
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return nil, nil, &CancelledError{}
+		}
+		return nil, nil, err
+	}
+	defer release()
+	if limits := resourceLimitsFromContext(ctx); limits.TimeoutSecs > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(limits.TimeoutSecs)*time.Second)
+		defer cancel()
+	}
+
+	var diagnostics []AnalysisDiagnostic
+	var failures []ErrorDetail
+	seen := make(map[string]bool)
+	for _, l := range loads {
+		// go list runs with the same environment and network policy as the go commands
+		cmd := goCommand(input, l.dir)
+		if offlineFromContext(ctx) {
+			prepareOffline(cmd, nil)
+		}
+		pkgs, err := packages.Load(&packages.Config{
+			Mode:    packages.LoadAllSyntax,
+			Context: ctx,
+			Dir:     l.dir,
+			Env:     cmd.Env,
+			Tests:   true,
+			Overlay: overlay,
+		}, l.patterns...)
+		if err != nil {
+			if ctx.Err() == context.Canceled {
+				return nil, nil, &CancelledError{}
+			}
+			return nil, nil, fmt.Errorf("failed to load packages: %v", err)
+		}
+
+		// Test variants of a package report the same errors and findings again,
+		// and the generated test main packages have nothing worth analyzing
+		var errorText strings.Builder
+		var roots []*packages.Package
+		for _, pkg := range pkgs {
+			for _, e := range pkg.Errors {
+				if !seen[e.Error()] {
+					seen[e.Error()] = true
+					fmt.Fprintf(&errorText, "# %s\n%s\n", pkg.PkgPath, e)
+				}
+			}
+			if !strings.HasSuffix(pkg.ID, ".test") {
+				roots = append(roots, pkg)
+			}
+		}
+		failures = append(failures, ParseDiagnostics(errorText.String(), l.dir, root)...)
+
+		graph, err := checker.Analyze(analyzers, roots, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("analysis failed: %v", err)
+		}
+		for _, act := range graph.Roots {
+			if act.Err != nil && len(act.Package.Errors) == 0 {
+				failures = append(failures, ErrorDetail{
+					Type:    ErrorTypeUnknown,
+					Message: fmt.Sprintf("%s: %v", byAnalyzer[act.Analyzer], act.Err),
+					Package: act.Package.PkgPath,
+				})
+			}
+			for _, d := range act.Diagnostics {
+				diagnostic := driverDiagnostic(byAnalyzer[act.Analyzer], act, d, l.dir, root)
+				key := diagnostic.String()
+				if !seen[key] {
+					seen[key] = true
+					diagnostics = append(diagnostics, diagnostic)
+				}
+			}
+		}
+	}
+
+	sortAnalysisDiagnostics(diagnostics)
+	return diagnostics, failures, nil
+}
+
+// driverDiagnostic converts a diagnostic of an in-process analyzer
+func driverDiagnostic(name string, act *checker.Action, d analysis.Diagnostic, dir, root string) AnalysisDiagnostic {
+	fset := act.Package.Fset
+	position := func(posn token.Position) Position {
+		return Position{File: resolveDiagnosticPath(posn.Filename, dir, root), Line: posn.Line, Column: posn.Column}
+	}
+
+	diagnostic := AnalysisDiagnostic{
+		Analyzer: name,
+		Package:  act.Package.PkgPath,
+		Position: position(fset.Position(d.Pos)),
+		Message:  d.Message,
+	}
+	if d.End.IsValid() && d.End != d.Pos {
+		end := position(fset.Position(d.End))
+		diagnostic.End = &end
+	}
+	for _, f := range d.SuggestedFixes {
+		fix := SuggestedFix{Message: f.Message, Edits: []TextEdit{}}
+		for _, e := range f.TextEdits {
+			file := fset.File(e.Pos)
+			if file == nil {
+				continue
+			}
+			end := e.End
+			if !end.IsValid() {
+				end = e.Pos
+			}
+			fix.Edits = append(fix.Edits, TextEdit{
+				File:  resolveDiagnosticPath(file.Name(), dir, root),
+				Start: file.Offset(e.Pos),
+				End:   file.Offset(end),
+				New:   string(e.NewText),
+			})
+		}
+		diagnostic.SuggestedFixes = append(diagnostic.SuggestedFixes, fix)
+	}
+	return diagnostic
+}
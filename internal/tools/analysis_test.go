@@ -0,0 +1,185 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MrFixit96/go-dev-mcp/internal/config"
+)
+
+func TestFormatVerbs(t *testing.T) {
+	verbs, ok := formatVerbs("%d%% of %*s: %+v %w")
+	if !ok || len(verbs) != 4 {
+		t.Fatalf("Expected 4 verbs, got %+v", verbs)
+	}
+	want := []formatVerb{
+		{verb: 'd', offset: 1, arg: 1, plain: true},
+		{verb: 's', offset: 10, arg: 3, plain: false},
+		{verb: 'v', offset: 15, arg: 4, plain: false},
+		{verb: 'w', offset: 18, arg: 5, plain: true},
+	}
+	for i := range want {
+		if verbs[i] != want[i] {
+			t.Errorf("Verb %d: got %+v, want %+v", i, verbs[i], want[i])
+		}
+	}
+	if _, ok := formatVerbs("%[2]d %[1]d"); ok {
+		t.Error("Expected explicit argument indexes to be given up on")
+	}
+}
+
+func TestInProcessAnalyzers(t *testing.T) {
+	SetConfig(config.DefaultConfig())
+	t.Setenv("GOFLAGS", "")
+	code := `package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+func open(names []string) error {
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			return fmt.Errorf("open %s: %v", name, err)
+		}
+		defer f.Close()
+	}
+	return nil
+}
+
+func main() {
+	var err error
+	if err == nil {
+		fmt.Println(err.Error())
+	}
+	fmt.Println(open(nil), errors.New("x"))
+}
+`
+	result, err := ExecuteGoAnalyzeTool(context.Background(), newToolRequest("go_analyze", map[string]interface{}{"code": code, "vet": false}))
+	if err != nil || result.IsError {
+		t.Fatalf("go_analyze failed: %v %s", err, resultText(result))
+	}
+	var response struct {
+		Success     bool                 `json:"success"`
+		Analyzers   []string             `json:"analyzers"`
+		Diagnostics []AnalysisDiagnostic `json:"diagnostics"`
+	}
+	if err := json.Unmarshal([]byte(resultText(result)), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	found := make(map[string]AnalysisDiagnostic)
+	for _, d := range response.Diagnostics {
+		found[d.Analyzer] = d
+	}
+	for _, analyzer := range []string{"defer-in-loop", "nilness"} {
+		if _, ok := found[analyzer]; !ok {
+			t.Errorf("Expected a %s diagnostic, got %s", analyzer, resultText(result))
+		}
+	}
+	for _, analyzer := range []string{"shadow", "error-wrapping"} {
+		if _, ok := found[analyzer]; ok {
+			t.Errorf("Expected %s to be off by default, got %s", analyzer, resultText(result))
+		}
+	}
+	if response.Success {
+		t.Errorf("Expected issues to be found, got %s", resultText(result))
+	}
+
+	// error-wrapping runs when requested and its suggested fix turns the %v into %w
+	result, _ = ExecuteGoAnalyzeTool(context.Background(), newToolRequest("go_analyze", map[string]interface{}{
+		"code":      code,
+		"analyzers": []interface{}{"error-wrapping"},
+	}))
+	if err := json.Unmarshal([]byte(resultText(result)), &response); err != nil || len(response.Diagnostics) != 1 {
+		t.Fatalf("Expected an error-wrapping diagnostic, got %v %s", err, resultText(result))
+	}
+	wrapping := response.Diagnostics[0]
+	if wrapping.Analyzer != "error-wrapping" || len(wrapping.SuggestedFixes) != 1 {
+		t.Fatalf("Expected a suggested fix, got %+v", wrapping)
+	}
+	edit := wrapping.SuggestedFixes[0].Edits[0]
+	if fixed := code[:edit.Start] + edit.New + code[edit.End:]; !strings.Contains(fixed, `"open %s: %w", name, err`) {
+		t.Errorf("Unexpected fix %+v", edit)
+	}
+
+	// A request can run only some analyzers
+	result, _ = ExecuteGoAnalyzeTool(context.Background(), newToolRequest("go_analyze", map[string]interface{}{
+		"code":      code,
+		"analyzers": []interface{}{"defer-in-loop"},
+	}))
+	if err := json.Unmarshal([]byte(resultText(result)), &response); err != nil || len(response.Diagnostics) != 1 || response.Diagnostics[0].Analyzer != "defer-in-loop" {
+		t.Errorf("Expected only the defer-in-loop diagnostic, got %v %s", err, resultText(result))
+	}
+}
+
+func TestInProcessAnalyzersDisabled(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Analysis.InProcess = false
+	SetConfig(cfg)
+	defer SetConfig(config.DefaultConfig())
+	t.Setenv("GOFLAGS", "")
+	code := "package main\n\nimport \"os\"\n\nfunc main() {\n\tfor _, name := range os.Args {\n\t\tf, _ := os.Open(name)\n\t\tdefer f.Close()\n\t}\n}\n"
+
+	// Only go vet runs
+	result, err := ExecuteGoAnalyzeTool(context.Background(), newToolRequest("go_analyze", map[string]interface{}{"code": code}))
+	if err != nil || result.IsError {
+		t.Fatalf("go_analyze failed: %v %s", err, resultText(result))
+	}
+	var response struct {
+		Analyzers   []string             `json:"analyzers"`
+		Diagnostics []AnalysisDiagnostic `json:"diagnostics"`
+	}
+	if err := json.Unmarshal([]byte(resultText(result)), &response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Analyzers) != 0 || len(response.Diagnostics) != 0 {
+		t.Errorf("Expected no in-process analyzers to run, got %s", resultText(result))
+	}
+
+	// and requests naming an in-process analyzer are refused
+	result, err = ExecuteGoAnalyzeTool(context.Background(), newToolRequest("go_analyze", map[string]interface{}{
+		"code":      code,
+		"analyzers": []interface{}{"defer-in-loop"},
+	}))
+	if err != nil || !result.IsError || !strings.Contains(resultText(result), "analysis.inProcess") {
+		t.Errorf("Expected the in-process analyzer to be refused, got %v %s", err, resultText(result))
+	}
+}
+
+func TestInProcessAnalyzersRelativeProject(t *testing.T) {
+	SetConfig(config.DefaultConfig())
+	t.Setenv("GOFLAGS", "")
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"go.mod":  "module example.com/app\n\ngo 1.21\n",
+		"main.go": "package main\n\nfunc main() {}\n",
+	})
+	chdir(t, filepath.Dir(root))
+	relative := filepath.Base(root)
+
+	code := "package tool\n\nimport \"os\"\n\nfunc Open(names []string) {\n\tfor _, name := range names {\n\t\tf, _ := os.Open(name)\n\t\tdefer f.Close()\n\t}\n}\n"
+	result, err := ExecuteGoAnalyzeTool(context.Background(), newToolRequest("go_analyze", map[string]interface{}{
+		"project_path": relative,
+		"files":        map[string]interface{}{"tool/tool.go": code},
+		"vet":          false,
+	}))
+	if err != nil || result.IsError {
+		t.Fatalf("go_analyze failed: %v %s", err, resultText(result))
+	}
+	var response struct {
+		Diagnostics []AnalysisDiagnostic `json:"diagnostics"`
+	}
+	if err := json.Unmarshal([]byte(resultText(result)), &response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Diagnostics) != 1 || response.Diagnostics[0].Analyzer != "defer-in-loop" {
+		t.Errorf("Expected the overlaid tool package to be analyzed, got %s", resultText(result))
+	}
+}
@@ -6,7 +6,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
-	"path/filepath"
+	"strings"
 
 	"github.com/mark3labs/mcp-go/mcp"
 )
@@ -18,68 +18,95 @@ func ExecuteGoAnalyzeTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.Ca
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
+	ctx = withRequestLimits(ctx, req)
 
-	// Use Parse method for optional boolean parameter
-	runVet := mcp.ParseBoolean(req, "vet", true)
 	module := mcp.ParseString(req, "module", "") // For workspace module selection
 
-	// Prepare vet args
-	args := []string{"vet"}
-
-	// Handle different source types
-	switch input.Source {
-	case SourceWorkspace:
-		// For workspace execution, handle module selection
-		if module != "" {
-			// Analyze specific module in workspace
-			args = append(args, module)
-		} else {
-			// Analyze all modules in workspace
-			args = append(args, "./...")
-		}
-	case SourceCode:
-		// For code analysis, we need to use the existing temporary directory approach
-		return executeCodeAnalysis(ctx, input.Code, runVet)
-	default:
-		// For project execution, analyze all packages
-		args = append(args, "./...")
-	}
-
-	// Execute using appropriate strategy
-	strategy := GetExecutionStrategy(input, args...)
-	result, err := strategy.Execute(ctx, input, args)
+	// Prepare vet args; -json reports every diagnostic with its analyzer and fixes
+	flags, err := vetFlags(req)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Execution error: %v", err)), nil
+		return mcp.NewToolResultError(err.Error()), nil
 	}
+	analyzers, runVet := requestAnalyzers(req)
+	args := append([]string{"vet", "-json"}, flags...)
 
-	// Process results
-	issues := []string{}
-	message := "Analysis completed"
-	success := result.Successful
+	var result *ExecutionResult
+	if runVet {
+		// Handle different source types
+		switch input.Source {
+		case SourceWorkspace:
+			// For workspace execution, handle module selection
+			if module != "" {
+				// Analyze specific module in workspace
+				args = append(args, module)
+			} else {
+				// Analyze all modules in workspace
+				args = append(args, "./...")
+			}
+		case SourceCode:
+			// For code analysis, we need to use the existing temporary directory approach
+			args = append(args, "./...")
+		case SourceHybrid:
+			// Analyze the packages the code is overlaid onto
+			args = append(args, input.sourcePackages()...)
+		default:
+			// For project execution, analyze all packages
+			args = append(args, "./...")
+		}
 
-	if result.Stdout != "" {
-		issues = append(issues, result.Stdout)
-	}
-	if result.Stderr != "" {
-		issues = append(issues, result.Stderr)
+		if input.Source == SourceCode {
+			result, err = executeCodeVet(ctx, input, args)
+		} else {
+			// Execute using appropriate strategy
+			result, err = GetExecutionStrategy(input, args...).Execute(ctx, input, args)
+		}
+		if err != nil {
+			return executionErrorResult(err, "Execution error"), nil
+		}
 	}
 
-	if !success {
-		message = "Analysis found issues"
+	// Run the in-process analyzers on the loaded packages
+	var found []AnalysisDiagnostic
+	var failed []ErrorDetail
+	if len(analyzers) > 0 {
+		found, failed, err = runAnalyzers(ctx, input, module, analyzers)
+		if err != nil {
+			return executionErrorResult(err, "Analysis error"), nil
+		}
 	}
 
-	response := map[string]interface{}{
-		"success":  success,
-		"message":  message,
-		"issues":   issues,
-		"duration": result.Duration.String(),
-		"source":   input.Source,
-		"vet": map[string]interface{}{
-			"success": success,
-			"issues":  issues,
-		},
+	response := analysisResponse(result, input, found, failed)
+	response["analyzers"] = analyzers
+
+	// Apply the suggested fixes, writing them only when dry_run is turned off
+	if mcp.ParseBoolean(req, "fix", false) {
+		dryRun := mcp.ParseBoolean(req, "dry_run", true)
+		root := diagnosticRoot(input)
+		diagnostics, _ := response["diagnostics"].([]AnalysisDiagnostic)
+		files, fixed, skipped := applySuggestedFixes(diagnostics, fixFileReader(input, root))
+		if err := writeFixes(input, root, files, fixed, !dryRun); err != nil {
+			return executionErrorResult(err, "Failed to apply fixes"), nil
+		}
+		if skipped == nil {
+			skipped = []SkippedFix{}
+		}
+		response["fixes"] = map[string]interface{}{
+			"dryRun":  dryRun,
+			"files":   files,
+			"skipped": skipped,
+		}
+		switch {
+		case len(files) == 0:
+			response["message"] = "No suggested fixes to apply"
+		case input.Source == SourceCode:
+			// Code input has no files to write; the fixed code is returned instead
+			response["message"] = fmt.Sprintf("Suggested fixes produce new content for %d files", len(files))
+		case dryRun:
+			response["message"] = fmt.Sprintf("Dry run: suggested fixes would change %d files", len(files))
+		default:
+			response["message"] = fmt.Sprintf("Applied suggested fixes to %d files", len(files))
+		}
 	}
-
 	if input.Source == SourceWorkspace {
 		response["workspacePath"] = input.WorkspacePath
 		response["workspaceModules"] = input.WorkspaceModules
@@ -101,72 +128,116 @@ func ExecuteGoAnalyzeTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.Ca
 	return mcp.NewToolResultText(string(jsonBytes)), nil
 }
 
-// executeCodeAnalysis handles analysis for code input using temporary directory
-func executeCodeAnalysis(ctx context.Context, code string, runVet bool) (*mcp.CallToolResult, error) {
-	// Create temporary directory for the operation
-	tmpDir, err := os.MkdirTemp("", "go-analyze-*")
-	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to create temp directory: %v", err)), nil
+// analysisResponse builds the go_analyze response from the go vet result, or
+// from no result when vet was not run, and the findings of the in-process
+// analyzers. Diagnostics are parsed from the JSON of every module's command;
+// issues repeats them as one line each, followed by packages that failed to
+// type-check and any other go command error. Findings go vet already reported
+// and load errors of packages go vet checked are left out.
+func analysisResponse(result *ExecutionResult, input InputContext, found []AnalysisDiagnostic, failed []ErrorDetail) map[string]interface{} {
+	diagnostics := []AnalysisDiagnostic{}
+	failures := []ErrorDetail{}
+	issues := []string{}
+	success := true
+
+	if result != nil {
+		root := diagnosticRoot(input)
+		for _, r := range moduleResults(result) {
+			found, failed := ParseVetOutput(r.Stdout+r.Stderr, r.Dir, root)
+			diagnostics = append(diagnostics, found...)
+			failures = append(failures, failed...)
+			if !r.Successful && len(failed) == 0 && strings.TrimSpace(r.Stderr) != "" {
+				issues = append(issues, strings.TrimSpace(r.Stderr))
+			}
+		}
+		success = result.Successful
+	} else {
+		failures = append(failures, failed...)
 	}
-	defer os.RemoveAll(tmpDir)
 
-	// Create a simple Go module
-	modCmd := exec.Command("go", "mod", "init", "analyze")
-	modCmd.Dir = tmpDir
-	if output, err := modCmd.CombinedOutput(); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to initialize Go module: %v\n%s", err, output)), nil
+	reported := make(map[string]bool, len(diagnostics))
+	for _, d := range diagnostics {
+		reported[d.Position.String()+": "+d.Message] = true
+	}
+	for _, d := range found {
+		if !reported[d.Position.String()+": "+d.Message] {
+			diagnostics = append(diagnostics, d)
+		}
 	}
+	sortAnalysisDiagnostics(diagnostics)
+	success = success && len(diagnostics) == 0 && len(failures) == 0
 
-	// Write code to temporary file
-	sourceFile := filepath.Join(tmpDir, "main.go")
-	if err := os.WriteFile(sourceFile, []byte(code), 0644); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to write source code: %v", err)), nil
+	lines := make([]string, 0, len(diagnostics)+len(failures)+len(issues))
+	for _, d := range diagnostics {
+		lines = append(lines, d.String())
 	}
+	for _, e := range failures {
+		if e.File != "" {
+			lines = append(lines, Position{File: e.File, Line: e.Line, Column: e.Column}.String()+": "+e.Message)
+		} else {
+			lines = append(lines, e.Message)
+		}
+	}
+	issues = append(lines, issues...)
 
-	// Run analysis
-	issues := []string{}
 	message := "Analysis completed"
-	success := true
-
-	if runVet {
-		// Run go vet
-		vetCmd := exec.Command("go", "vet", "./...")
-		vetCmd.Dir = tmpDir
-		vetResult, _ := execute(vetCmd)
-
-		if vetResult.Stdout != "" || vetResult.Stderr != "" {
-			if vetResult.Stdout != "" {
-				issues = append(issues, vetResult.Stdout)
-			}
-			if vetResult.Stderr != "" {
-				issues = append(issues, vetResult.Stderr)
-			}
-
-			if !vetResult.Successful {
-				success = false
-				message = "Analysis found issues"
-			}
-		}
+	if !success {
+		message = "Analysis found issues"
 	}
 
 	response := map[string]interface{}{
-		"success": success,
-		"message": message,
-		"issues":  issues,
-		"source":  SourceCode,
+		"success":     success,
+		"message":     message,
+		"issues":      issues,
+		"diagnostics": diagnostics,
+		"source":      input.Source,
 		"vet": map[string]interface{}{
-			"success": success,
-			"issues":  issues,
+			"success":     success,
+			"issues":      issues,
+			"diagnostics": len(diagnostics),
 		},
 	}
+	if len(failures) > 0 {
+		response["errorDetails"] = failures
+	}
+	if result != nil {
+		response["duration"] = result.Duration.String()
+		response["sandbox"] = result.Sandbox
+		response["cacheHit"] = result.CacheHit
+		if modules := moduleSummaries(result); modules != nil {
+			response["modules"] = modules
+		}
+	}
+	return response
+}
 
-	// Add natural language metadata
-	AddNLMetadata(response, "go_analyze")
+// executeCodeVet runs go vet with args on code input in a temporary module,
+// reusing the cached result for identical code
+func executeCodeVet(ctx context.Context, input InputContext, args []string) (*ExecutionResult, error) {
+	key, cacheable := codeCacheKey(ctx, input, args)
+	if cacheable {
+		if result, _, ok := results.get(key); ok {
+			return result, nil
+		}
+	}
 
-	jsonBytes, err := json.MarshalIndent(response, "", "  ")
+	// Create temporary directory for the operation
+	tmpDir, err := os.MkdirTemp("", "go-analyze-*")
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error marshaling response: %v", err)), nil
+		return nil, fmt.Errorf("failed to create temp directory: %v", err)
 	}
+	defer os.RemoveAll(tmpDir)
 
-	return mcp.NewToolResultText(string(jsonBytes)), nil
+	// Write the code into a module
+	if err := writeCodeModule(ctx, tmpDir, input, "analyze"); err != nil {
+		return nil, fmt.Errorf("failed to prepare code: %w", err)
+	}
+
+	vetCmd := exec.Command("go", args...)
+	vetCmd.Dir = tmpDir
+	result, err := execute(ctx, vetCmd)
+	if err == nil && cacheable && result.Successful {
+		results.put(key, result, nil)
+	}
+	return result, err
 }
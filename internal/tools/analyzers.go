@@ -0,0 +1,148 @@
+package tools
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strconv"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// errorWrappingAnalyzer reports fmt.Errorf calls that format an error with a
+// verb other than %w, which hides the error from errors.Is and errors.As
+var errorWrappingAnalyzer = &analysis.Analyzer{
+	Name:     "errorwrapping",
+	Doc:      "report fmt.Errorf calls that format an error argument without %w",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      runErrorWrapping,
+}
+
+func runErrorWrapping(pass *analysis.Pass) (interface{}, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	errorType := types.Universe.Lookup("error").Type().Underlying().(*types.Interface)
+
+	inspect.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+		call := n.(*ast.CallExpr)
+		fn, ok := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+		if !ok || fn.FullName() != "fmt.Errorf" || len(call.Args) == 0 || call.Ellipsis.IsValid() {
+			return
+		}
+		format := pass.TypesInfo.Types[call.Args[0]].Value
+		if format == nil || format.Kind() != constant.String {
+			return
+		}
+		verbs, ok := formatVerbs(constant.StringVal(format))
+		if !ok {
+			return
+		}
+
+		// The format string can only be edited when its source text is its value
+		lit, _ := call.Args[0].(*ast.BasicLit)
+		editable := lit != nil && len(lit.Value) >= 2
+		if editable {
+			value, err := strconv.Unquote(lit.Value)
+			editable = err == nil && value == lit.Value[1:len(lit.Value)-1]
+		}
+
+		for _, verb := range verbs {
+			if verb.arg >= len(call.Args) || verb.verb == 'w' {
+				continue
+			}
+			arg := call.Args[verb.arg]
+			if t := pass.TypesInfo.TypeOf(arg); t == nil || !types.Implements(t, errorType) {
+				continue
+			}
+			diagnostic := analysis.Diagnostic{
+				Pos:     arg.Pos(),
+				End:     arg.End(),
+				Message: "fmt.Errorf formats the error with %" + string(verb.verb) + "; use %w to wrap it",
+			}
+			if editable && verb.plain && (verb.verb == 'v' || verb.verb == 's') {
+				pos := lit.Pos() + 1 + token.Pos(verb.offset)
+				diagnostic.SuggestedFixes = []analysis.SuggestedFix{{
+					Message:   "Wrap the error with %w",
+					TextEdits: []analysis.TextEdit{{Pos: pos, End: pos + 1, NewText: []byte("w")}},
+				}}
+			}
+			pass.Report(diagnostic)
+		}
+	})
+	return nil, nil
+}
+
+// formatVerb is a verb of a format string and the call argument it formats
+type formatVerb struct {
+	verb   rune
+	offset int  // Byte offset of the verb character in the format string
+	arg    int  // Index of the formatted argument in the call, counting the format
+	plain  bool // The verb has no flags, width or precision
+}
+
+// formatVerbs returns the verbs of a printf format string. It gives up on
+// explicit argument indexes, which cannot be mapped to arguments reliably.
+func formatVerbs(format string) ([]formatVerb, bool) {
+	var verbs []formatVerb
+	arg := 1
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		start := i + 1
+		i = start
+		for i < len(format) {
+			c := format[i]
+			if c == '[' {
+				return nil, false
+			}
+			if c == '*' {
+				arg++
+			} else if !(c == '+' || c == '-' || c == '#' || c == ' ' || c == '0' || c == '.' || (c >= '1' && c <= '9')) {
+				break
+			}
+			i++
+		}
+		if i == len(format) {
+			break
+		}
+		if format[i] == '%' {
+			continue
+		}
+		verbs = append(verbs, formatVerb{verb: rune(format[i]), offset: i, arg: arg, plain: i == start})
+		arg++
+	}
+	return verbs, true
+}
+
+// deferInLoopAnalyzer reports defer statements inside loops, which run only
+// when the function returns and so hold every iteration's resources until then
+var deferInLoopAnalyzer = &analysis.Analyzer{
+	Name:     "deferinloop",
+	Doc:      "report defer statements inside for and range loops",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      runDeferInLoop,
+}
+
+func runDeferInLoop(pass *analysis.Pass) (interface{}, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	inspect.WithStack([]ast.Node{(*ast.DeferStmt)(nil)}, func(n ast.Node, push bool, stack []ast.Node) bool {
+		if !push {
+			return true
+		}
+		for i := len(stack) - 2; i >= 0; i-- {
+			switch stack[i].(type) {
+			case *ast.FuncLit, *ast.FuncDecl:
+				return true
+			case *ast.ForStmt, *ast.RangeStmt:
+				pass.ReportRangef(n, "defer in a loop runs when the function returns, not at the end of each iteration; move the loop body into a function")
+				return true
+			}
+		}
+		return true
+	})
+	return nil, nil
+}
@@ -16,6 +16,7 @@ func ExecuteGoBuildTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.Call
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
+	ctx = withRequestLimits(ctx, req)
 	// Extract parameters
 	outputPath := mcp.ParseString(req, "outputPath", "")
 	buildTags := mcp.ParseString(req, "buildTags", "")
@@ -28,8 +29,8 @@ func ExecuteGoBuildTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.Call
 	}
 	if outputPath != "" {
 		args = append(args, "-o", outputPath)
-	} else if input.Source == SourceCode {
-		// Only set default output for code input
+	} else if input.Source == SourceCode && len(input.Files) == 0 {
+		// Only set default output for single-file code input
 		outputPath = "output"
 		args = append(args, "-o", outputPath)
 	}
@@ -37,8 +38,12 @@ func ExecuteGoBuildTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.Call
 	// Handle different source types
 	switch input.Source {
 	case SourceCode:
-		// For code execution, add the main file
-		args = append(args, input.MainFile)
+		// For code execution, add the main file, or every package of multi-file input
+		if len(input.Files) == 0 {
+			args = append(args, input.MainFile)
+		} else {
+			args = append(args, "./...")
+		}
 	case SourceWorkspace:
 		// For workspace execution, handle module selection
 		if module != "" {
@@ -57,14 +62,14 @@ func ExecuteGoBuildTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.Call
 	strategy := GetExecutionStrategy(input, args...)
 	result, err := strategy.Execute(ctx, input, args)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Execution error: %v", err)), nil
+		return executionErrorResult(err, "Execution error"), nil
 	}
 
 	// Format response with structured error handling
 	if result.Successful {
 		return formatBuildSuccess(result, outputPath, input), nil
 	} else {
-		return formatBuildError(result), nil
+		return formatBuildError(result, input), nil
 	}
 }
 
@@ -85,7 +90,12 @@ func formatBuildSuccess(result *ExecutionResult, outputPath string, input InputC
 		"message":    "Compilation successful",
 		"outputPath": fullOutputPath,
 		"duration":   result.Duration.String(),
+		"sandbox":    result.Sandbox,
 		"source":     input.Source,
+		"cacheHit":   result.CacheHit,
+	}
+	if modules := moduleSummaries(result); modules != nil {
+		response["modules"] = modules
 	}
 
 	// Add natural language metadata
@@ -100,9 +110,9 @@ func formatBuildSuccess(result *ExecutionResult, outputPath string, input InputC
 }
 
 // formatBuildError creates a structured error response
-func formatBuildError(result *ExecutionResult) *mcp.CallToolResult {
+func formatBuildError(result *ExecutionResult, input InputContext) *mcp.CallToolResult {
 	// Parse Go build errors for more context
-	errorDetails := parseGoBuildErrors(result.Stderr)
+	errorDetails := resultDiagnostics(result, diagnosticRoot(input))
 
 	response := map[string]interface{}{
 		"success":      false,
@@ -110,7 +120,13 @@ func formatBuildError(result *ExecutionResult) *mcp.CallToolResult {
 		"stderr":       result.Stderr,
 		"exitCode":     result.ExitCode,
 		"duration":     result.Duration.String(),
+		"sandbox":      result.Sandbox,
+		"cacheHit":     result.CacheHit,
 		"errorDetails": errorDetails,
+		"packages":     GroupDiagnostics(errorDetails),
+	}
+	if modules := moduleSummaries(result); modules != nil {
+		response["modules"] = modules
 	}
 
 	// Add natural language metadata
@@ -123,11 +139,3 @@ func formatBuildError(result *ExecutionResult) *mcp.CallToolResult {
 
 	return mcp.NewToolResultError(string(jsonBytes))
 }
-
-// parseGoBuildErrors extracts meaningful error information from Go build output
-func parseGoBuildErrors(stderr string) string {
-	// In a real implementation, this would parse the error output
-	// and structure it by file, line, error type, etc.
-	// For now, we're just returning the raw stderr
-	return stderr
-}
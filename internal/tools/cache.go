@@ -0,0 +1,228 @@
+package tools
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// resultCache holds the results of deterministic commands run on code input,
+// keyed by a hash of everything that can affect them. The least recently used
+// results are evicted once the configured size is exceeded.
+type resultCache struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List // Most recently used first
+	size    int
+}
+
+// cacheEntry is a cached command result
+type cacheEntry struct {
+	key     string
+	result  ExecutionResult
+	files   map[string]string // Files produced by the command, such as formatted sources
+	size    int
+	expires time.Time
+}
+
+// newResultCache creates an empty result cache
+func newResultCache() *resultCache {
+	return &resultCache{entries: make(map[string]*list.Element), lru: list.New()}
+}
+
+// results is the cache used by the tool handlers
+var results = newResultCache()
+
+// get returns a copy of the cached result for key, marked as a cache hit
+func (c *resultCache) get(key string) (*ExecutionResult, map[string]string, bool) {
+	if !getConfig().Cache.Enabled {
+		return nil, nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		c.remove(elem)
+		return nil, nil, false
+	}
+	c.lru.MoveToFront(elem)
+
+	result := entry.result
+	result.CacheHit = true
+	return &result, entry.files, true
+}
+
+// put stores a result under key, evicting old results to stay within the size limit
+func (c *resultCache) put(key string, result *ExecutionResult, files map[string]string) {
+	cfg := getConfig().Cache
+	if !cfg.Enabled {
+		return
+	}
+	entry := &cacheEntry{
+		key:     key,
+		result:  *result,
+		files:   files,
+		size:    len(result.Stdout) + len(result.Stderr),
+		expires: time.Now().Add(time.Duration(cfg.TTLSecs) * time.Second),
+	}
+	for name, content := range files {
+		entry.size += len(name) + len(content)
+	}
+	maxSize := cfg.MaxSizeMB << 20
+	if entry.size > maxSize {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	c.size += entry.size
+	for c.size > maxSize {
+		c.remove(c.lru.Back())
+	}
+}
+
+// remove drops a cache entry. The caller must hold c.mu.
+func (c *resultCache) remove(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*cacheEntry)
+	delete(c.entries, entry.key)
+	c.size -= entry.size
+}
+
+// cacheableTestFlags are the go test flags whose results go test itself would
+// cache; see 'go help test'. -json only changes how the results are reported.
+var cacheableTestFlags = map[string]bool{
+	"benchtime": true, "cpu": true, "failfast": true, "fullpath": true, "json": true,
+	"list": true, "parallel": true, "run": true, "short": true, "skip": true,
+	"timeout": true, "v": true,
+}
+
+// testFlagsWithValue are the cacheable test flags that take a separate value
+var testFlagsWithValue = map[string]bool{
+	"benchtime": true, "cpu": true, "list": true, "parallel": true, "run": true,
+	"skip": true, "timeout": true,
+}
+
+// cacheableCommand reports whether the result of a go command is fully
+// determined by its inputs. Tests follow go test's own caching rules, so -count
+// or any other flag outside the cacheable set (such as -bench) disables caching.
+// A build that writes its binary outside the temporary module is never cached,
+// since a cached result would not produce the file.
+func cacheableCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	switch args[0] {
+	case "build":
+		for i := 1; i < len(args); i++ {
+			output := ""
+			if args[i] == "-o" && i+1 < len(args) {
+				output = args[i+1]
+			} else if value, ok := strings.CutPrefix(args[i], "-o="); ok {
+				output = value
+			}
+			if output != "" && !filepath.IsLocal(output) {
+				return false
+			}
+		}
+		return true
+	case "vet", "gofmt":
+		return true
+	case "test":
+		for i := 1; i < len(args); i++ {
+			if !strings.HasPrefix(args[i], "-") {
+				continue
+			}
+			name, _, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+			if !cacheableTestFlags[name] {
+				return false
+			}
+			if testFlagsWithValue[name] && !hasValue {
+				i++
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// codeCacheKey returns the cache key for running args on code input: a hash of
+// the source files, the arguments, the Go version, the sandbox, the network
+// policy and the environment. It reports false when the result must not be cached.
+func codeCacheKey(ctx context.Context, input InputContext, args []string) (string, bool) {
+	if input.Source != SourceCode || !getConfig().Cache.Enabled || !cacheableCommand(args) {
+		return "", false
+	}
+	version := goVersion()
+	if version == "" {
+		return "", false
+	}
+	files, err := input.sourceFiles()
+	if err != nil {
+		return "", false
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "go %s\x00sandbox %s\x00offline %t\x00", version, getConfig().SandboxType, offlineFromContext(ctx))
+	for _, arg := range args {
+		fmt.Fprintf(h, "arg %s\x00", arg)
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(h, "file %s %d\x00%s", name, len(files[name]), files[name])
+	}
+	for _, kv := range cacheEnv(input.Env) {
+		fmt.Fprintf(h, "env %s\x00", kv)
+	}
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+// cacheEnv returns the sorted environment that can affect a go command: the
+// request's variables and the server's GO* and CGO_* settings
+func cacheEnv(requestEnv []string) []string {
+	var env []string
+	for _, kv := range os.Environ() {
+		if strings.HasPrefix(kv, "GO") || strings.HasPrefix(kv, "CGO_") {
+			env = append(env, kv)
+		}
+	}
+	env = append(env, requestEnv...)
+	sort.Strings(env)
+	return env
+}
+
+var (
+	goVersionOnce  sync.Once
+	goVersionValue string
+)
+
+// goVersion returns the version of the go command, or "" if it cannot be determined
+func goVersion() string {
+	goVersionOnce.Do(func() {
+		out, err := exec.Command("go", "env", "GOVERSION").Output()
+		if err == nil {
+			goVersionValue = strings.TrimSpace(string(out))
+		}
+	})
+	return goVersionValue
+}
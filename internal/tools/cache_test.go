@@ -0,0 +1,115 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MrFixit96/go-dev-mcp/internal/config"
+)
+
+func TestCacheableCommand(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"build", "-o", "out", "./..."}, true},
+		{[]string{"build", "-o", "/tmp/out", "./..."}, false},
+		{[]string{"build", "-o=../out", "./..."}, false},
+		{[]string{"vet", "./..."}, true},
+		{[]string{"gofmt", "-w", "main.go"}, true},
+		{[]string{"test", "-json", "-v", "-run", "TestX", "./..."}, true},
+		{[]string{"test", "-run=TestX", "./..."}, true},
+		{[]string{"test", "-count=1", "./..."}, false},
+		{[]string{"test", "-cover", "./..."}, false},
+		{[]string{"test", "-bench", ".", "./..."}, false},
+		{[]string{"run", "main.go"}, false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := cacheableCommand(tt.args); got != tt.want {
+			t.Errorf("cacheableCommand(%q) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestBuildResultCached(t *testing.T) {
+	SetConfig(config.DefaultConfig())
+	results = newResultCache()
+
+	code := "package main\n\nfunc main() { println(\"cache\") }\n"
+	for i, want := range []string{`"cacheHit": false`, `"cacheHit": true`} {
+		result, err := ExecuteGoBuildTool(context.Background(), newToolRequest("go_build", map[string]interface{}{
+			"code": code,
+		}))
+		if err != nil || result.IsError {
+			t.Fatalf("go_build %d failed: %v %s", i, err, resultText(result))
+		}
+		if !strings.Contains(resultText(result), want) {
+			t.Errorf("Build %d: expected %s, got %s", i, want, resultText(result))
+		}
+	}
+
+	// Different code is not served from the cache
+	result, err := ExecuteGoBuildTool(context.Background(), newToolRequest("go_build", map[string]interface{}{
+		"code": code + "\n// changed\n",
+	}))
+	if err != nil || result.IsError {
+		t.Fatalf("go_build failed: %v %s", err, resultText(result))
+	}
+	if !strings.Contains(resultText(result), `"cacheHit": false`) {
+		t.Errorf("Expected a cache miss for changed code, got %s", resultText(result))
+	}
+}
+
+func TestBuildOutputNotCached(t *testing.T) {
+	SetConfig(config.DefaultConfig())
+	results = newResultCache()
+
+	output := filepath.Join(t.TempDir(), "bin1")
+	req := newToolRequest("go_build", map[string]interface{}{
+		"code":       "package main\n\nfunc main() { println(\"output\") }\n",
+		"outputPath": output,
+	})
+	for i := 0; i < 2; i++ {
+		result, err := ExecuteGoBuildTool(context.Background(), req)
+		if err != nil || result.IsError || strings.Contains(resultText(result), `"cacheHit": true`) {
+			t.Fatalf("go_build %d: expected an uncached build, got %v %s", i, err, resultText(result))
+		}
+		// The binary is written again after it was deleted
+		if _, err := os.Stat(output); err != nil {
+			t.Fatalf("go_build %d did not write %s: %v", i, output, err)
+		}
+		os.Remove(output)
+	}
+}
+
+func TestResultCacheEviction(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Cache.MaxSizeMB = 1
+	SetConfig(cfg)
+	defer SetConfig(config.DefaultConfig())
+
+	cache := newResultCache()
+	output := strings.Repeat("x", 400<<10)
+	for i := 0; i < 3; i++ {
+		cache.put(fmt.Sprint(i), &ExecutionResult{Stdout: output, Successful: true}, nil)
+	}
+
+	// Only the two most recent results fit in 1MB
+	if _, _, ok := cache.get("0"); ok {
+		t.Error("Expected the oldest result to be evicted")
+	}
+	for _, key := range []string{"1", "2"} {
+		result, _, ok := cache.get(key)
+		if !ok || !result.CacheHit {
+			t.Errorf("Expected result %s to be cached", key)
+		}
+	}
+	if cache.size > 1<<20 {
+		t.Errorf("Cache size %d exceeds the limit", cache.size)
+	}
+}
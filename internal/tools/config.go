@@ -0,0 +1,30 @@
+package tools
+
+import (
+	"sync"
+
+	"github.com/MrFixit96/go-dev-mcp/internal/config"
+)
+
+var (
+	configMu     sync.RWMutex
+	activeConfig = config.DefaultConfig()
+)
+
+// SetConfig sets the server configuration used by the tool handlers.
+// It should be called once during startup before any tools are registered.
+func SetConfig(cfg *config.Config) {
+	if cfg == nil {
+		cfg = config.DefaultConfig()
+	}
+	configMu.Lock()
+	defer configMu.Unlock()
+	activeConfig = cfg
+}
+
+// getConfig returns the active server configuration
+func getConfig() *config.Config {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return activeConfig
+}
@@ -0,0 +1,222 @@
+package tools
+
+import (
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DiagnosticKind classifies compiler and go command errors
+type DiagnosticKind string
+
+const (
+	DiagnosticUndefined      DiagnosticKind = "undefined"
+	DiagnosticTypeMismatch   DiagnosticKind = "type_mismatch"
+	DiagnosticUnusedImport   DiagnosticKind = "unused_import"
+	DiagnosticUnusedVariable DiagnosticKind = "unused_variable"
+	DiagnosticImportCycle    DiagnosticKind = "import_cycle"
+	DiagnosticMissingModule  DiagnosticKind = "missing_module"
+	DiagnosticSyntax         DiagnosticKind = "syntax"
+	DiagnosticOther          DiagnosticKind = "other"
+)
+
+// PackageDiagnostics groups the diagnostics reported for one package
+type PackageDiagnostics struct {
+	Package string        `json:"package"`
+	Errors  []ErrorDetail `json:"errors"`
+}
+
+var (
+	// diagnosticLocation matches "file:line: message" and "file:line:column: message",
+	// including Windows paths that start with a drive letter
+	diagnosticLocation = regexp.MustCompile(`^((?:[A-Za-z]:)?[^:]+):(\d+)(?::(\d+))?: ?(.*)$`)
+	// exitStatusLine matches the trailer go run prints for a failed program
+	exitStatusLine = regexp.MustCompile(`^exit status \d+$`)
+)
+
+// progressPrefixes mark go command status lines that are not diagnostics
+var progressPrefixes = []string{"go: finding module", "go: downloading", "go: found", "go: extracting"}
+
+// ParseDiagnostics parses the output of go build, go run, go test or go vet into
+// structured error details. Paths are resolved against workDir, the directory the
+// command ran in, and reported relative to root (the project or workspace), or
+// relative to workDir when root is empty. Continuation lines are folded into the
+// message of the diagnostic they belong to.
+func ParseDiagnostics(output, workDir, root string) []ErrorDetail {
+	if output == "" {
+		return nil
+	}
+	if root == "" {
+		root = workDir
+	}
+
+	var details []ErrorDetail
+	pkg := ""
+	current := -1
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			current = -1
+			continue
+		}
+
+		// Indented lines continue the previous diagnostic (have/want, imports, go get hints)
+		if current >= 0 && (line[0] == ' ' || line[0] == '\t') {
+			details[current].Message += "\n" + trimmed
+			continue
+		}
+
+		// "# example.com/pkg" or "# example.com/pkg [example.com/pkg.test]" starts a package
+		if strings.HasPrefix(trimmed, "# ") {
+			pkg = strings.Fields(strings.TrimPrefix(trimmed, "# "))[0]
+			current = -1
+			continue
+		}
+		if exitStatusLine.MatchString(trimmed) || hasAnyPrefix(trimmed, progressPrefixes) {
+			continue
+		}
+
+		detail := ErrorDetail{Message: trimmed, Package: pkg}
+		if m := diagnosticLocation.FindStringSubmatch(trimmed); m != nil {
+			detail.File = resolveDiagnosticPath(m[1], workDir, root)
+			detail.Line, _ = strconv.Atoi(m[2])
+			detail.Column, _ = strconv.Atoi(m[3])
+			detail.Message = m[4]
+		} else if strings.HasPrefix(trimmed, "package ") {
+			// Import cycles are reported as "package a" followed by indented imports
+			detail.Package = strings.Fields(trimmed)[1]
+		}
+		details = append(details, detail)
+		current = len(details) - 1
+	}
+
+	for i := range details {
+		classifyDiagnostic(&details[i])
+	}
+	return details
+}
+
+// GroupDiagnostics groups error details by package in order of first appearance.
+// Details without a package are grouped under an empty package name.
+func GroupDiagnostics(details []ErrorDetail) []PackageDiagnostics {
+	var groups []PackageDiagnostics
+	index := make(map[string]int)
+	for _, detail := range details {
+		i, ok := index[detail.Package]
+		if !ok {
+			i = len(groups)
+			index[detail.Package] = i
+			groups = append(groups, PackageDiagnostics{Package: detail.Package})
+		}
+		groups[i].Errors = append(groups[i].Errors, detail)
+	}
+	return groups
+}
+
+// compilerDiagnostics keeps only the details that come from the compiler or the go
+// command, dropping output a program wrote to stderr itself
+func compilerDiagnostics(details []ErrorDetail) []ErrorDetail {
+	var filtered []ErrorDetail
+	for _, detail := range details {
+		if detail.Type == ErrorTypeCompilation {
+			filtered = append(filtered, detail)
+		}
+	}
+	return filtered
+}
+
+// diagnosticRoot returns the directory diagnostic paths are reported relative to
+func diagnosticRoot(input InputContext) string {
+	switch input.Source {
+	case SourceProjectPath, SourceHybrid:
+		return input.ProjectPath
+	case SourceWorkspace:
+		return input.WorkspacePath
+	default:
+		// Code runs in a temporary directory, so paths are kept relative to it
+		return ""
+	}
+}
+
+// resolveDiagnosticPath makes a path from go command output relative to root when
+// it lies inside root, and absolute otherwise
+func resolveDiagnosticPath(file, workDir, root string) string {
+	if workDir == "" {
+		return file
+	}
+	path := file
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workDir, path)
+	}
+	if rel, err := filepath.Rel(root, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return rel
+	}
+	return path
+}
+
+// classifyDiagnostic sets the kind, type and suggestions of a parsed diagnostic
+func classifyDiagnostic(detail *ErrorDetail) {
+	msg := detail.Message
+	switch {
+	case strings.Contains(msg, "import cycle not allowed"):
+		detail.Kind = DiagnosticImportCycle
+		detail.AppendSuggestion("Move the shared code into a separate package that both packages can import")
+	case containsAny(msg, "no required module provides package", "cannot find module providing package",
+		"missing go.sum entry", "is not in std", "cannot find package", "unknown revision",
+		"no matching versions", "module lookup disabled") ||
+		(strings.HasPrefix(msg, "module ") && strings.Contains(msg, ": reading ")):
+		detail.Kind = DiagnosticMissingModule
+		detail.AppendSuggestion("Run go get for the missing module or go mod tidy to update go.mod and go.sum")
+		detail.AppendSuggestion("Check that the import path is spelled correctly")
+	case strings.Contains(msg, "imported and not used") ||
+		(strings.Contains(msg, "imported as") && strings.Contains(msg, "and not used")):
+		detail.Kind = DiagnosticUnusedImport
+		detail.AppendSuggestion("Remove the unused import or use the blank identifier (_) if it is imported for side effects")
+	case strings.Contains(msg, "declared and not used"):
+		detail.Kind = DiagnosticUnusedVariable
+		detail.AppendSuggestion("Remove the unused variable or assign it to the blank identifier (_)")
+	case containsAny(msg, "undefined:", "undeclared name", "has no field or method", " undefined ("):
+		detail.Kind = DiagnosticUndefined
+		detail.AppendSuggestion("Check if you have imported the necessary package")
+		detail.AppendSuggestion("Verify that the variable or function name is spelled correctly")
+	case containsAny(msg, "cannot use", "mismatched types", "does not implement", "cannot convert",
+		"incompatible type", "invalid operation"):
+		detail.Kind = DiagnosticTypeMismatch
+		detail.AppendSuggestion("Convert the value to the expected type or change the declared type")
+	case containsAny(msg, "syntax error", "expected '"):
+		detail.Kind = DiagnosticSyntax
+		detail.AppendSuggestion("Check for missing braces, parentheses, or semicolons")
+		detail.AppendSuggestion("Verify that syntax is correct according to Go language specification")
+	case detail.File != "":
+		detail.Kind = DiagnosticOther
+	}
+
+	if detail.Kind != "" {
+		detail.Type = ErrorTypeCompilation
+	} else {
+		detail.Type = ErrorTypeUnknown
+	}
+}
+
+// containsAny reports whether s contains any of the substrings
+func containsAny(s string, substrings ...string) bool {
+	for _, sub := range substrings {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAnyPrefix reports whether s starts with any of the prefixes
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package tools
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseDiagnostics(t *testing.T) {
+	root := filepath.FromSlash("/work/project")
+	stderr := `# example.com/project/cmd/app
+./main.go:4:2: "os" imported and not used
+./main.go:12:21: cannot use S{} (value of struct type S) as I value in return statement: S does not implement I (missing method M)
+		have m()
+		want M()
+./main.go:16:7: undefined: undefinedThing
+main.go:5:2: no required module provides package github.com/nope/missing; to add it:
+	go get github.com/nope/missing
+package example.com/project/a
+	imports example.com/project/b from a.go
+	imports example.com/project/a from b.go: import cycle not allowed
+`
+	details := ParseDiagnostics(stderr, filepath.Join(root, "cmd", "app"), root)
+	if len(details) != 5 {
+		t.Fatalf("Expected 5 diagnostics, got %d: %+v", len(details), details)
+	}
+
+	want := []struct {
+		kind DiagnosticKind
+		file string
+		line int
+	}{
+		{DiagnosticUnusedImport, filepath.Join("cmd", "app", "main.go"), 4},
+		{DiagnosticTypeMismatch, filepath.Join("cmd", "app", "main.go"), 12},
+		{DiagnosticUndefined, filepath.Join("cmd", "app", "main.go"), 16},
+		{DiagnosticMissingModule, filepath.Join("cmd", "app", "main.go"), 5},
+		{DiagnosticImportCycle, "", 0},
+	}
+	for i, w := range want {
+		d := details[i]
+		if d.Kind != w.kind || d.File != w.file || d.Line != w.line {
+			t.Errorf("Diagnostic %d: expected %s at %s:%d, got %s at %s:%d", i, w.kind, w.file, w.line, d.Kind, d.File, d.Line)
+		}
+		if d.Type != ErrorTypeCompilation {
+			t.Errorf("Diagnostic %d: expected type %s, got %s", i, ErrorTypeCompilation, d.Type)
+		}
+	}
+	if !strings.Contains(details[1].Message, "want M()") {
+		t.Errorf("Expected continuation lines in message, got %q", details[1].Message)
+	}
+	if details[4].Package != "example.com/project/a" {
+		t.Errorf("Expected import cycle in example.com/project/a, got %q", details[4].Package)
+	}
+
+	groups := GroupDiagnostics(details)
+	if len(groups) != 2 || groups[0].Package != "example.com/project/cmd/app" || len(groups[0].Errors) != 4 {
+		t.Errorf("Unexpected grouping: %+v", groups)
+	}
+}
+
+func TestParseGoErrorsWindowsPath(t *testing.T) {
+	details := ParseGoErrors(`C:\src\app\main.go:7:3: syntax error: unexpected newline`)
+	if len(details) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %d", len(details))
+	}
+	d := details[0]
+	if d.File != `C:\src\app\main.go` || d.Line != 7 || d.Column != 3 || d.Kind != DiagnosticSyntax {
+		t.Errorf("Unexpected diagnostic: %+v", d)
+	}
+}
@@ -0,0 +1,231 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 3
+
+// diffOp is one line of an edit script
+type diffOp struct {
+	kind byte // ' ' unchanged, '-' deleted, '+' inserted
+	line string
+}
+
+// UnifiedDiff returns a unified diff between the old and new content of a file,
+// or an empty string when they are identical. oldName and newName label the
+// --- and +++ header lines.
+func UnifiedDiff(oldName, newName string, oldContent, newContent []byte) string {
+	if string(oldContent) == string(newContent) {
+		return ""
+	}
+	ops := diffLines(splitLines(string(oldContent)), splitLines(string(newContent)))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+
+	// Line numbers before each op in the old and new file
+	oldLine, newLine := make([]int, len(ops)+1), make([]int, len(ops)+1)
+	for i, op := range ops {
+		oldLine[i+1], newLine[i+1] = oldLine[i], newLine[i]
+		if op.kind != '+' {
+			oldLine[i+1]++
+		}
+		if op.kind != '-' {
+			newLine[i+1]++
+		}
+	}
+
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		// Extend the hunk while the next change is close enough to share context
+		start := max(i-diffContext, 0)
+		end := i
+		for j := i; j < len(ops); j++ {
+			if ops[j].kind != ' ' {
+				end = j + 1
+			} else if j-end >= 2*diffContext {
+				break
+			}
+		}
+		end = min(end+diffContext, len(ops))
+
+		oldCount, newCount := oldLine[end]-oldLine[start], newLine[end]-newLine[start]
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(oldLine[start], oldCount), hunkRange(newLine[start], newCount))
+		for _, op := range ops[start:end] {
+			b.WriteByte(op.kind)
+			b.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				b.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = end
+	}
+	return b.String()
+}
+
+// hunkRange formats the start,count part of a hunk header
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// splitLines splits text into lines that keep their trailing newline
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// maxDiffLines bounds the lines left to compare once the common prefix and
+// suffix are removed. Beyond it the changed region is reported as one
+// replacement, as the time to find a shortest edit script grows with the
+// product of its size and the number of edits.
+const maxDiffLines = 20000
+
+// diffLines computes a shortest edit script from a to b with the linear-space
+// variant of Myers' algorithm
+func diffLines(a, b []string) []diffOp {
+	d := &lineDiff{a: a, b: b, ops: make([]diffOp, 0, max(len(a), len(b)))}
+	prefix, suffix := commonPrefix(a, b), 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	if len(a)+len(b)-2*(prefix+suffix) > maxDiffLines {
+		d.same(0, prefix)
+		d.deleted(prefix, len(a)-suffix)
+		d.inserted(prefix, len(b)-suffix)
+		d.same(len(a)-suffix, len(a))
+		return d.ops
+	}
+	d.compare(0, len(a), 0, len(b))
+	return d.ops
+}
+
+// commonPrefix returns the number of leading lines a and b share
+func commonPrefix(a, b []string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+// lineDiff collects the edit script of a against b
+type lineDiff struct {
+	a, b []string
+	ops  []diffOp
+}
+
+func (d *lineDiff) same(lo, hi int) {
+	for _, line := range d.a[lo:hi] {
+		d.ops = append(d.ops, diffOp{' ', line})
+	}
+}
+
+func (d *lineDiff) deleted(lo, hi int) {
+	for _, line := range d.a[lo:hi] {
+		d.ops = append(d.ops, diffOp{'-', line})
+	}
+}
+
+func (d *lineDiff) inserted(lo, hi int) {
+	for _, line := range d.b[lo:hi] {
+		d.ops = append(d.ops, diffOp{'+', line})
+	}
+}
+
+// compare appends the edit script from a[aLo:aHi] to b[bLo:bHi], splitting the
+// ranges at the middle snake of a shortest path
+func (d *lineDiff) compare(aLo, aHi, bLo, bHi int) {
+	prefix := commonPrefix(d.a[aLo:aHi], d.b[bLo:bHi])
+	d.same(aLo, aLo+prefix)
+	aLo, bLo = aLo+prefix, bLo+prefix
+	suffix := 0
+	for aLo < aHi-suffix && bLo < bHi-suffix && d.a[aHi-1-suffix] == d.b[bHi-1-suffix] {
+		suffix++
+	}
+	aEnd, bEnd := aHi-suffix, bHi-suffix
+
+	switch {
+	case aLo == aEnd:
+		d.inserted(bLo, bEnd)
+	case bLo == bEnd:
+		d.deleted(aLo, aEnd)
+	default:
+		x, y, u, v := d.middleSnake(aLo, aEnd, bLo, bEnd)
+		d.compare(aLo, x, bLo, y)
+		d.same(x, u)
+		d.compare(u, aEnd, v, bEnd)
+	}
+	d.same(aEnd, aHi)
+}
+
+// middleSnake searches forward from the start and backward from the end of the
+// ranges at once and returns the snake, from (x, y) to (u, v), where the two
+// searches meet on a shortest path. Only the two frontiers are kept.
+func (d *lineDiff) middleSnake(aLo, aHi, bLo, bHi int) (x, y, u, v int) {
+	n, m := aHi-aLo, bHi-bLo
+	delta := n - m
+	odd := delta%2 != 0
+	limit := (n + m + 1) / 2
+	offset := limit + 1
+	forward := make([]int, 2*offset+1)  // Furthest x on each diagonal k = x - y from the start
+	backward := make([]int, 2*offset+1) // Furthest distance from the end on each diagonal, counted backward
+
+	for step := 0; step <= limit; step++ {
+		for k := -step; k <= step; k += 2 {
+			var x int
+			if k == -step || (k != step && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && d.a[aLo+x] == d.b[bLo+y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+			if back := delta - k; odd && back >= -(step-1) && back <= step-1 && x+backward[offset+back] >= n {
+				return aLo + startX, bLo + startY, aLo + x, bLo + y
+			}
+		}
+		for k := -step; k <= step; k += 2 {
+			var x int
+			if k == -step || (k != step && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && d.a[aHi-1-x] == d.b[bHi-1-y] {
+				x++
+				y++
+			}
+			backward[offset+k] = x
+			if ahead := delta - k; !odd && ahead >= -step && ahead <= step && x+forward[offset+ahead] >= n {
+				return aHi - x, bHi - y, aHi - startX, bHi - startY
+			}
+		}
+	}
+	// Unreachable: the searches meet within (n+m+1)/2 steps
+	return aLo, bLo, aLo, bLo
+}
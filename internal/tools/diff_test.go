@@ -0,0 +1,107 @@
+package tools
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	old := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	new := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk"
+
+	want := `--- a/file
++++ b/file
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -8,3 +8,4 @@
+ h
+ i
+ j
++k
+\ No newline at end of file
+`
+	if got := UnifiedDiff("a/file", "b/file", []byte(old), []byte(new)); got != want {
+		t.Errorf("Unexpected diff:\n%s\nwant:\n%s", got, want)
+	}
+
+	if got := UnifiedDiff("a/file", "b/file", []byte(old), []byte(old)); got != "" {
+		t.Errorf("Expected no diff for identical content, got:\n%s", got)
+	}
+
+	want = "--- a/file\n+++ b/file\n@@ -0,0 +1,2 @@\n+x\n+y\n"
+	if got := UnifiedDiff("a/file", "b/file", nil, []byte("x\ny\n")); got != want {
+		t.Errorf("Unexpected diff for a new file:\n%s", got)
+	}
+}
+
+func TestDiffLinesShortest(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	random := func() []string {
+		lines := make([]string, rng.Intn(30))
+		for i := range lines {
+			lines[i] = string(rune('a' + rng.Intn(4)))
+		}
+		return lines
+	}
+	for i := 0; i < 500; i++ {
+		a, b := random(), random()
+		ops := diffLines(a, b)
+
+		var gotA, gotB []string
+		edits := 0
+		for _, op := range ops {
+			if op.kind != '+' {
+				gotA = append(gotA, op.line)
+			}
+			if op.kind != '-' {
+				gotB = append(gotB, op.line)
+			}
+			if op.kind != ' ' {
+				edits++
+			}
+		}
+		if strings.Join(gotA, "") != strings.Join(a, "") || strings.Join(gotB, "") != strings.Join(b, "") {
+			t.Fatalf("Edit script of %q to %q does not reproduce them: %v", a, b, ops)
+		}
+
+		// The length of a longest common subsequence gives the shortest edit count
+		lcs := make([][]int, len(a)+1)
+		for x := range lcs {
+			lcs[x] = make([]int, len(b)+1)
+		}
+		for x := len(a) - 1; x >= 0; x-- {
+			for y := len(b) - 1; y >= 0; y-- {
+				if a[x] == b[y] {
+					lcs[x][y] = lcs[x+1][y+1] + 1
+				} else {
+					lcs[x][y] = max(lcs[x+1][y], lcs[x][y+1])
+				}
+			}
+		}
+		if want := len(a) + len(b) - 2*lcs[0][0]; edits != want {
+			t.Fatalf("Edit script of %q to %q has %d edits, want %d", a, b, edits, want)
+		}
+	}
+}
+
+func TestDiffLinesLargeChange(t *testing.T) {
+	a := make([]string, maxDiffLines)
+	b := make([]string, maxDiffLines)
+	for i := range a {
+		a[i] = fmt.Sprintf("a%d\n", i)
+		b[i] = fmt.Sprintf("b%d\n", i)
+	}
+	a[0], b[0] = "same\n", "same\n"
+
+	ops := diffLines(a, b)
+	if len(ops) != 2*maxDiffLines-1 || ops[0].kind != ' ' || ops[1].kind != '-' || ops[len(ops)-1].kind != '+' {
+		t.Errorf("Expected the changed region as one replacement, got %d ops", len(ops))
+	}
+}
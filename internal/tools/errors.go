@@ -1,10 +1,13 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"strings"
 	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
 )
 
 // ErrorType defines categories of errors that can occur during Go operations
@@ -16,17 +19,27 @@ const (
 	ErrorTypeSystem      ErrorType = "system"
 	ErrorTypeValidation  ErrorType = "validation"
 	ErrorTypeTimeout     ErrorType = "timeout"
-	ErrorTypeUnknown     ErrorType = "unknown"
+	// ErrorTypeResourceLimit indicates a command exceeded its memory or CPU limit
+	ErrorTypeResourceLimit ErrorType = "resource_limit"
+	// ErrorTypeTestFailure indicates a failing test reported by go test
+	ErrorTypeTestFailure ErrorType = "test_failure"
+	// ErrorTypeCancelled indicates the client cancelled the request
+	ErrorTypeCancelled ErrorType = "cancelled"
+	// ErrorTypeServerBusy indicates the server refused the command because too many were queued
+	ErrorTypeServerBusy ErrorType = "server_busy"
+	ErrorTypeUnknown    ErrorType = "unknown"
 )
 
 // ErrorDetail represents a structured error with context
 type ErrorDetail struct {
-	Type        ErrorType `json:"type"`
-	Message     string    `json:"message"`
-	File        string    `json:"file,omitempty"`
-	Line        int       `json:"line,omitempty"`
-	Column      int       `json:"column,omitempty"`
-	Suggestions []string  `json:"suggestions,omitempty"`
+	Type        ErrorType      `json:"type"`
+	Kind        DiagnosticKind `json:"kind,omitempty"`
+	Message     string         `json:"message"`
+	Package     string         `json:"package,omitempty"`
+	File        string         `json:"file,omitempty"`
+	Line        int            `json:"line,omitempty"`
+	Column      int            `json:"column,omitempty"`
+	Suggestions []string       `json:"suggestions,omitempty"`
 }
 
 // AppendSuggestion adds a suggestion to the error detail
@@ -42,6 +55,24 @@ type ErrorResponse struct {
 	Timestamp    time.Time     `json:"timestamp"`
 	Duration     string        `json:"duration,omitempty"`
 	ExitCode     int           `json:"exitCode,omitempty"`
+	// LimitExceeded names the resource limit (timeout, memory, cpu) that stopped the command
+	LimitExceeded string `json:"limitExceeded,omitempty"`
+	// Cancelled is set when the request was cancelled before the command finished
+	Cancelled bool `json:"cancelled,omitempty"`
+}
+
+// CancelledError reports that a command was stopped because its request was cancelled
+type CancelledError struct {
+	Duration time.Duration // How long the command ran before it was stopped
+	Command  string        // Command that was executed
+}
+
+// Error implements the error interface
+func (e *CancelledError) Error() string {
+	if e.Command == "" {
+		return "request cancelled"
+	}
+	return fmt.Sprintf("request cancelled: command was stopped after %v", e.Duration.Round(time.Millisecond))
 }
 
 // NewErrorResponse creates a new error response
@@ -83,72 +114,104 @@ func (r *ErrorResponse) ToJSON() string {
 	return string(jsonBytes)
 }
 
-// ParseGoErrors parses Go compiler error output into structured error details
-func ParseGoErrors(stderr string) []ErrorDetail {
-	if stderr == "" {
-		return nil
+// cancelledResult returns the tool result for a request that was cancelled by
+// the client, or nil if err does not report a cancellation
+func cancelledResult(err error) *mcp.CallToolResult {
+	var cancelErr *CancelledError
+	if !errors.As(err, &cancelErr) {
+		if !errors.Is(err, context.Canceled) {
+			return nil
+		}
+		cancelErr = &CancelledError{}
 	}
 
-	var details []ErrorDetail
+	response := &ErrorResponse{
+		Success: false,
+		Message: "Request cancelled",
+		ErrorDetails: []ErrorDetail{{
+			Type:    ErrorTypeCancelled,
+			Message: cancelErr.Error(),
+		}},
+		Timestamp: time.Now(),
+		Cancelled: true,
+	}
+	response.SetDuration(cancelErr.Duration)
+	response.SetExitCode(-1)
+	return mcp.NewToolResultError(response.ToJSON())
+}
 
-	// Split error message by lines
-	lines := strings.Split(stderr, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
+// executionErrorResult converts an error returned while executing a command into a tool result.
+// Resource limit violations are reported as a structured ErrorResponse so clients can
+// tell them apart from ordinary failures; other errors are prefixed with prefix.
+func executionErrorResult(err error, prefix string) *mcp.CallToolResult {
+	if result := cancelledResult(err); result != nil {
+		return result
+	}
 
-		// Simple parsing of Go errors which typically follow the format:
-		// file.go:line:column: error description
-		parts := strings.SplitN(line, ":", 4)
-		if len(parts) >= 3 {
-			// Try to extract file, line, and column
-			file := parts[0]
-			lineNum := 0
-			colNum := 0
-
-			fmt.Sscanf(parts[1], "%d", &lineNum)
-			if len(parts) > 2 {
-				fmt.Sscanf(parts[2], "%d", &colNum)
-			}
-
-			errorMsg := ""
-			if len(parts) > 3 {
-				errorMsg = strings.TrimSpace(parts[3])
-			} else if len(parts) > 2 {
-				errorMsg = strings.TrimSpace(parts[2])
-			}
-
-			detail := ErrorDetail{
-				Type:    ErrorTypeCompilation,
-				Message: errorMsg,
-				File:    file,
-				Line:    lineNum,
-			}
-
-			if colNum > 0 {
-				detail.Column = colNum
-			}
-
-			// Add suggestions based on common Go errors
-			if strings.Contains(errorMsg, "undefined:") || strings.Contains(errorMsg, "undeclared name:") {
-				detail.AppendSuggestion("Check if you have imported the necessary package")
-				detail.AppendSuggestion("Verify that the variable or function name is spelled correctly")
-			} else if strings.Contains(errorMsg, "syntax error:") {
-				detail.AppendSuggestion("Check for missing braces, parentheses, or semicolons")
-				detail.AppendSuggestion("Verify that syntax is correct according to Go language specification")
-			}
-
-			details = append(details, detail)
-		} else {
-			// For error messages that don't match the expected format
-			details = append(details, ErrorDetail{
-				Type:    ErrorTypeUnknown,
-				Message: line,
-			})
+	var pathErr *PathNotAllowedError
+	if errors.As(err, &pathErr) {
+		suggestion := ""
+		if len(pathErr.Roots) > 0 {
+			suggestion = "Use a path inside one of the allowed roots, or add its directory to roots.allowed in the server configuration"
 		}
+		response := executionErrorResponse(prefix, ErrorTypeValidation, pathErr, suggestion)
+		return mcp.NewToolResultError(response.ToJSON())
+	}
+
+	var busyErr *ServerBusyError
+	if errors.As(err, &busyErr) {
+		response := executionErrorResponse(prefix, ErrorTypeServerBusy, busyErr,
+			"Retry later, or raise concurrency.workers or concurrency.maxQueue in the server configuration")
+		return mcp.NewToolResultError(response.ToJSON())
+	}
+
+	var lockErr *LockTimeoutError
+	if errors.As(err, &lockErr) {
+		response := executionErrorResponse(prefix, ErrorTypeTimeout, lockErr,
+			"Retry once the other call on the project has finished, or raise locks.timeoutSecs in the server configuration")
+		response.SetDuration(lockErr.Wait)
+		return mcp.NewToolResultError(response.ToJSON())
+	}
+
+	var limitErr *LimitExceededError
+	if !errors.As(err, &limitErr) {
+		return mcp.NewToolResultError(fmt.Sprintf("%s: %v", prefix, err))
+	}
+
+	errorType := ErrorTypeResourceLimit
+	if limitErr.Limit == LimitTimeout {
+		errorType = ErrorTypeTimeout
 	}
+	var suggestion string
+	switch limitErr.Limit {
+	case LimitTimeout:
+		suggestion = "Pass a smaller workload or raise resourceLimits.timeoutSecs in the server configuration"
+	case LimitMemory:
+		suggestion = "Reduce memory usage or raise resourceLimits.memoryLimit in the server configuration"
+	case LimitCPU:
+		suggestion = "Reduce CPU usage or raise resourceLimits.cpuLimit in the server configuration"
+	}
+
+	response := executionErrorResponse(prefix, errorType, limitErr, suggestion)
+	response.LimitExceeded = limitErr.Limit
+	response.SetDuration(limitErr.Duration)
+	response.SetExitCode(-1)
+	return mcp.NewToolResultError(response.ToJSON())
+}
 
-	return details
+// executionErrorResponse creates an error response for err with the message
+// prefixed by prefix and an optional suggestion
+func executionErrorResponse(prefix string, errorType ErrorType, err error, suggestion string) *ErrorResponse {
+	response := NewErrorResponse(fmt.Sprintf("%s: %s", prefix, err.Error()), errorType, err.Error())
+	if suggestion != "" {
+		response.ErrorDetails[0].AppendSuggestion(suggestion)
+	}
+	return response
+}
+
+// ParseGoErrors parses Go compiler error output into structured error details.
+// File paths are reported as printed by the go command; use ParseDiagnostics to
+// resolve them against a project or workspace.
+func ParseGoErrors(stderr string) []ErrorDetail {
+	return ParseDiagnostics(stderr, "", "")
 }
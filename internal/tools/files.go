@@ -0,0 +1,146 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"golang.org/x/tools/txtar"
+)
+
+// parseFilesInput reads multi-file code input from the files argument (a map of
+// slash-separated paths to contents) and the txtar argument (a txtar archive).
+// Every path must stay inside the directory the files are written to.
+func parseFilesInput(req mcp.CallToolRequest) (map[string]string, error) {
+	files := make(map[string]string)
+	add := func(name, content string) error {
+		clean, err := validateFilePath(name)
+		if err != nil {
+			return err
+		}
+		if _, dup := files[clean]; dup {
+			return fmt.Errorf("file %s is given more than once", clean)
+		}
+		files[clean] = content
+		return nil
+	}
+
+	if value, ok := req.GetArguments()["files"]; ok && value != nil {
+		entries, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("files must be an object mapping file paths to contents")
+		}
+		for name, content := range entries {
+			text, ok := content.(string)
+			if !ok {
+				return nil, fmt.Errorf("content of file %s must be a string", name)
+			}
+			if err := add(name, text); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if archive := mcp.ParseString(req, "txtar", ""); archive != "" {
+		for _, f := range txtar.Parse([]byte(archive)).Files {
+			if err := add(f.Name, string(f.Data)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return files, nil
+}
+
+// validateFilePath checks that a file path from a request is relative and cannot
+// escape the directory it is written to, and returns it in clean slash form
+func validateFilePath(name string) (string, error) {
+	if strings.Contains(name, `\`) || !filepath.IsLocal(filepath.FromSlash(name)) {
+		return "", fmt.Errorf("invalid file path %q: paths must be relative and stay inside the module", name)
+	}
+	return path.Clean(name), nil
+}
+
+// sourceFiles returns the files that make up code input, keyed by slash-separated
+// path: the files argument, the code at MainFile and the test code beside it
+func (input InputContext) sourceFiles() (map[string]string, error) {
+	files := make(map[string]string, len(input.Files)+2)
+	for name, content := range input.Files {
+		files[name] = content
+	}
+	add := func(name, content string) error {
+		clean, err := validateFilePath(name)
+		if err != nil {
+			return err
+		}
+		if _, dup := files[clean]; dup {
+			return fmt.Errorf("code conflicts with file %s", clean)
+		}
+		files[clean] = content
+		return nil
+	}
+
+	if input.Code != "" {
+		if err := add(input.MainFile, input.Code); err != nil {
+			return nil, err
+		}
+	}
+	if input.TestCode != "" {
+		if err := add(path.Join(path.Dir(input.MainFile), testFileName(path.Base(input.MainFile))), input.TestCode); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// sourcePackages returns the ./-relative directories of the Go files in the code input
+func (input InputContext) sourcePackages() []string {
+	files, _ := input.sourceFiles()
+	seen := make(map[string]bool)
+	var packages []string
+	for name := range files {
+		if !strings.HasSuffix(name, ".go") {
+			continue
+		}
+		pkg := relativePackage(".", path.Dir(name))
+		if !seen[pkg] {
+			seen[pkg] = true
+			packages = append(packages, pkg)
+		}
+	}
+	sort.Strings(packages)
+	return packages
+}
+
+// writeCodeModule writes the code input into dir and initializes a module named
+// moduleName there unless the input brings its own go.mod
+func writeCodeModule(ctx context.Context, dir string, input InputContext, moduleName string) error {
+	files, err := input.sourceFiles()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := files["go.mod"]; !ok {
+		modCmd := exec.Command("go", "mod", "init", moduleName)
+		modCmd.Dir = dir
+		if output, err := executeSetup(ctx, modCmd); err != nil {
+			return fmt.Errorf("failed to initialize Go module: %w\n%s", err, output)
+		}
+	}
+
+	for name, content := range files {
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(target, []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %v", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const filesTestArchive = `A small module with a library package and a command.
+-- go.mod --
+module example.com/multi
+
+go 1.21
+-- greet/greet.go --
+package greet
+
+func Hello(name string) string { return "hello, " + name }
+-- greet/greet_test.go --
+package greet
+
+import "testing"
+
+func TestHello(t *testing.T) {
+	if got := Hello("gopher"); got != "hello, gopher" {
+		t.Fatalf("Hello() = %q", got)
+	}
+}
+-- main.go --
+package main
+
+import (
+	"fmt"
+
+	"example.com/multi/greet"
+)
+
+func main() { fmt.Println(greet.Hello("txtar")) }
+`
+
+func TestParseFilesInputRejectsEscapingPaths(t *testing.T) {
+	for _, name := range []string{"../evil.go", "/etc/passwd", `a\b.go`, "a/../../b.go", ""} {
+		req := newToolRequest("go_build", map[string]interface{}{
+			"files": map[string]interface{}{name: "package main\n"},
+		})
+		if _, err := parseFilesInput(req); err == nil {
+			t.Errorf("parseFilesInput accepted path %q", name)
+		}
+	}
+
+	req := newToolRequest("go_build", map[string]interface{}{
+		"files": map[string]interface{}{"./pkg//a.go": "package pkg\n"},
+		"txtar": "-- pkg/a.go --\npackage pkg\n",
+	})
+	if _, err := parseFilesInput(req); err == nil || !strings.Contains(err.Error(), "pkg/a.go") {
+		t.Errorf("duplicate file error = %v", err)
+	}
+}
+
+func TestSourceFilesConflict(t *testing.T) {
+	input := InputContext{
+		Code:     "package main\n",
+		MainFile: "main.go",
+		Files:    map[string]string{"main.go": "package main\n"},
+	}
+	if _, err := input.sourceFiles(); err == nil {
+		t.Error("expected code at main.go to conflict with files")
+	}
+}
+
+func TestMultiFileInput(t *testing.T) {
+	result, err := ExecuteGoRunTool(context.Background(), newToolRequest("go_run", map[string]interface{}{
+		"txtar": filesTestArchive,
+	}))
+	if err != nil || result.IsError {
+		t.Fatalf("go_run failed: %v %s", err, resultText(result))
+	}
+	if text := resultText(result); !strings.Contains(text, "hello, txtar") {
+		t.Errorf("go_run output missing program output: %s", text)
+	}
+
+	result, err = ExecuteGoTestTool(context.Background(), newToolRequest("go_test", map[string]interface{}{
+		"txtar": filesTestArchive,
+	}))
+	if err != nil || result.IsError {
+		t.Fatalf("go_test failed: %v %s", err, resultText(result))
+	}
+	if text := resultText(result); !strings.Contains(text, "example.com/multi/greet") {
+		t.Errorf("go_test did not test the greet package: %s", text)
+	}
+
+	result, err = ExecuteGoFmtTool(context.Background(), newToolRequest("go_fmt", map[string]interface{}{
+		"files": map[string]interface{}{
+			"go.mod": "module example.com/fmt\n",
+			"a/a.go": "package a\nfunc A( ) {}\n",
+			"b/b.go": "package b\n\nfunc B() {}\n",
+		},
+	}))
+	if err != nil || result.IsError {
+		t.Fatalf("go_fmt failed: %v %s", err, resultText(result))
+	}
+	var response struct {
+		Files        map[string]string `json:"files"`
+		ChangedFiles []string          `json:"changedFiles"`
+	}
+	if err := json.Unmarshal([]byte(resultText(result)), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.ChangedFiles) != 1 || response.ChangedFiles[0] != "a/a.go" {
+		t.Errorf("changedFiles = %v, want [a/a.go]", response.ChangedFiles)
+	}
+	if got := response.Files["a/a.go"]; got != "package a\n\nfunc A() {}\n" {
+		t.Errorf("formatted a/a.go = %q", got)
+	}
+}
+
+func TestModWithFilesAndProject(t *testing.T) {
+	t.Setenv("GOFLAGS", "")
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"go.mod":  "module example.com/project\n\ngo 1.21\n",
+		"main.go": "package main\n\nfunc main() {}\n",
+	})
+
+	result, err := ExecuteGoModTool(context.Background(), newToolRequest("go_mod", map[string]interface{}{
+		"command":      "tidy",
+		"project_path": dir,
+		"files":        map[string]interface{}{"extra.go": "package main\n"},
+	}))
+	if err != nil || result.IsError {
+		t.Fatalf("go_mod with files and a project failed: %v %s", err, resultText(result))
+	}
+	if text := resultText(result); !strings.Contains(text, `"success": true`) {
+		t.Errorf("Expected go mod tidy to run on the project, got %s", text)
+	}
+}
@@ -0,0 +1,180 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// FileFix is the change suggested fixes make to one file
+type FileFix struct {
+	File    string   `json:"file"`
+	Diff    string   `json:"diff"`
+	Fixes   []string `json:"fixes"`             // Messages of the fixes applied to the file
+	Written bool     `json:"written"`           // The file was rewritten on disk
+	Content string   `json:"content,omitempty"` // Fixed content of code input, which is never written
+}
+
+// SkippedFix is a suggested fix that was not applied
+type SkippedFix struct {
+	Diagnostic string `json:"diagnostic"`
+	Fix        string `json:"fix"`
+	Reason     string `json:"reason"`
+}
+
+// fixEdit is an edit accepted for a file, with the fix it belongs to
+type fixEdit struct {
+	TextEdit
+	fix int
+}
+
+// overlaps reports whether two edits touch the same bytes, or insert at the
+// same offset, so that applying both is ambiguous
+func (e TextEdit) overlaps(other TextEdit) bool {
+	if e.Start == e.End || other.Start == other.End {
+		return e.Start == other.Start || (e.Start > other.Start && e.Start < other.End) || (other.Start > e.Start && other.Start < e.End)
+	}
+	return e.Start < other.End && other.Start < e.End
+}
+
+// applySuggestedFixes applies the first suggested fix of every diagnostic, as
+// alternative fixes exclude each other. read returns the current content of a
+// file named by an edit. A fix whose edits overlap those of a fix applied
+// before it, or fall outside the file, is skipped as a whole; an edit identical
+// to one already applied is applied once. The changed files are returned in
+// name order with a unified diff each, and their new content by name.
+func applySuggestedFixes(diagnostics []AnalysisDiagnostic, read func(file string) ([]byte, error)) ([]FileFix, map[string][]byte, []SkippedFix) {
+	contents := make(map[string][]byte)
+	accepted := make(map[string][]fixEdit)
+	var messages []string
+	var skipped []SkippedFix
+
+	for _, d := range diagnostics {
+		if len(d.SuggestedFixes) == 0 {
+			continue
+		}
+		fix := d.SuggestedFixes[0]
+		skip := func(reason string) {
+			skipped = append(skipped, SkippedFix{Diagnostic: d.String(), Fix: fix.Message, Reason: reason})
+		}
+
+		var edits []TextEdit
+		reason := ""
+	check:
+		for _, edit := range fix.Edits {
+			content, ok := contents[edit.File]
+			if !ok {
+				var err error
+				if content, err = read(edit.File); err != nil {
+					reason = err.Error()
+					break
+				}
+				contents[edit.File] = content
+			}
+			if edit.Start < 0 || edit.Start > edit.End || edit.End > len(content) {
+				reason = fmt.Sprintf("edit of %s is outside the file", edit.File)
+				break
+			}
+			for _, other := range accepted[edit.File] {
+				if other.TextEdit == edit {
+					continue check
+				}
+				if other.overlaps(edit) {
+					reason = fmt.Sprintf("conflicts with the fix %q", messages[other.fix])
+					break check
+				}
+			}
+			edits = append(edits, edit)
+		}
+		if reason != "" {
+			skip(reason)
+			continue
+		}
+		if len(edits) == 0 {
+			continue
+		}
+		messages = append(messages, fix.Message)
+		for _, edit := range edits {
+			accepted[edit.File] = append(accepted[edit.File], fixEdit{edit, len(messages) - 1})
+		}
+	}
+
+	names := make([]string, 0, len(accepted))
+	for name := range accepted {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	files := []FileFix{}
+	fixed := make(map[string][]byte, len(names))
+	for _, name := range names {
+		edits := accepted[name]
+		sort.SliceStable(edits, func(i, j int) bool { return edits[i].Start < edits[j].Start })
+
+		content := contents[name]
+		var updated []byte
+		var applied []string
+		last, lastFix := 0, -1
+		for _, edit := range edits {
+			updated = append(append(updated, content[last:edit.Start]...), edit.New...)
+			last = edit.End
+			if edit.fix != lastFix {
+				applied = append(applied, messages[edit.fix])
+				lastFix = edit.fix
+			}
+		}
+		updated = append(updated, content[last:]...)
+
+		diff := UnifiedDiff("a/"+filepath.ToSlash(name), "b/"+filepath.ToSlash(name), content, updated)
+		if diff == "" {
+			continue
+		}
+		fixed[name] = updated
+		files = append(files, FileFix{File: name, Diff: diff, Fixes: applied})
+	}
+	return files, fixed, skipped
+}
+
+// fixFileReader returns the function applySuggestedFixes reads files with. The
+// source files of code input are read from the input, others from below root;
+// edits of files outside root are refused.
+func fixFileReader(input InputContext, root string) func(string) ([]byte, error) {
+	sources, _ := input.sourceFiles()
+	return func(file string) ([]byte, error) {
+		if content, ok := sources[filepath.ToSlash(file)]; ok {
+			return []byte(content), nil
+		}
+		if input.Source == SourceCode || root == "" || !filepath.IsLocal(file) {
+			return nil, fmt.Errorf("%s is outside the project", file)
+		}
+		return os.ReadFile(filepath.Join(root, file))
+	}
+}
+
+// writeFixes sets the fixed content of the files of code input, which exist
+// only in the request, and with write rewrites the other files below root,
+// keeping their permissions
+func writeFixes(input InputContext, root string, files []FileFix, fixed map[string][]byte, write bool) error {
+	sources, _ := input.sourceFiles()
+	for i := range files {
+		name := files[i].File
+		if _, ok := sources[filepath.ToSlash(name)]; ok || input.Source == SourceCode {
+			files[i].Content = string(fixed[name])
+			continue
+		}
+		if !write {
+			continue
+		}
+		path := filepath.Join(root, name)
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, fixed[name], info.Mode().Perm()); err != nil {
+			return fmt.Errorf("failed to write %s: %v", name, err)
+		}
+		files[i].Written = true
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MrFixit96/go-dev-mcp/internal/config"
+)
+
+func TestApplySuggestedFixes(t *testing.T) {
+	content := "abcdefghij\n"
+	diagnostic := func(message string, edits ...TextEdit) AnalysisDiagnostic {
+		return AnalysisDiagnostic{
+			Analyzer:       "test",
+			Position:       Position{File: "f.go", Line: 1},
+			Message:        message,
+			SuggestedFixes: []SuggestedFix{{Message: message, Edits: edits}},
+		}
+	}
+	diagnostics := []AnalysisDiagnostic{
+		diagnostic("upper b", TextEdit{File: "f.go", Start: 1, End: 2, New: "B"}),
+		diagnostic("upper b again", TextEdit{File: "f.go", Start: 1, End: 2, New: "B"}),
+		diagnostic("replace bc", TextEdit{File: "f.go", Start: 1, End: 3, New: "X"}),
+		diagnostic("insert", TextEdit{File: "f.go", Start: 5, End: 5, New: "-"}),
+		diagnostic("past the end", TextEdit{File: "f.go", Start: 9, End: 20, New: ""}),
+		diagnostic("other file", TextEdit{File: "g.go", Start: 0, End: 0, New: "x"}),
+		{Analyzer: "test", Message: "no fix"},
+	}
+	read := func(file string) ([]byte, error) {
+		if file != "f.go" {
+			return nil, os.ErrNotExist
+		}
+		return []byte(content), nil
+	}
+
+	files, fixed, skipped := applySuggestedFixes(diagnostics, read)
+	if len(files) != 1 || string(fixed["f.go"]) != "aBcde-fghij\n" {
+		t.Fatalf("Unexpected fixed files %+v %q", files, fixed["f.go"])
+	}
+	if got := strings.Join(files[0].Fixes, ","); got != "upper b,insert" {
+		t.Errorf("Unexpected applied fixes %s", got)
+	}
+	if !strings.Contains(files[0].Diff, "-abcdefghij\n+aBcde-fghij\n") {
+		t.Errorf("Unexpected diff %s", files[0].Diff)
+	}
+	var reasons []string
+	for _, s := range skipped {
+		reasons = append(reasons, s.Fix)
+	}
+	if got := strings.Join(reasons, ","); got != "replace bc,past the end,other file" {
+		t.Errorf("Unexpected skipped fixes %s", got)
+	}
+}
+
+func TestAnalyzeFixProject(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Analysis.Analyzers["error-wrapping"] = true
+	SetConfig(cfg)
+	defer SetConfig(config.DefaultConfig())
+	t.Setenv("GOFLAGS", "")
+	dir := t.TempDir()
+	source := "package lib\n\nimport \"fmt\"\n\nfunc Wrap(err error) error {\n\treturn fmt.Errorf(\"wrap: %v\", err)\n}\n\nfunc Self(x int) int {\n\tx = x\n\treturn x\n}\n"
+	writeTestFiles(t, dir, map[string]string{
+		"go.mod": "module example.com/lib\n\ngo 1.21\n",
+		"lib.go": source,
+	})
+
+	analyze := func(dryRun bool) map[string]interface{} {
+		t.Helper()
+		result, err := ExecuteGoAnalyzeTool(context.Background(), newToolRequest("go_analyze", map[string]interface{}{
+			"project_path": dir,
+			"fix":          true,
+			"dry_run":      dryRun,
+		}))
+		if err != nil || result.IsError {
+			t.Fatalf("go_analyze failed: %v %s", err, resultText(result))
+		}
+		var response map[string]interface{}
+		if err := json.Unmarshal([]byte(resultText(result)), &response); err != nil {
+			t.Fatal(err)
+		}
+		return response
+	}
+
+	// The dry run only reports the diff
+	response := analyze(true)
+	files := response["fixes"].(map[string]interface{})["files"].([]interface{})
+	if len(files) != 1 {
+		t.Fatalf("Expected fixes for one file, got %v", response["fixes"])
+	}
+	diff := files[0].(map[string]interface{})["diff"].(string)
+	for _, want := range []string{"+++ b/lib.go", "-\treturn fmt.Errorf(\"wrap: %v\", err)", "+\treturn fmt.Errorf(\"wrap: %w\", err)", "-\tx = x"} {
+		if !strings.Contains(diff, want) {
+			t.Errorf("Expected %q in the diff, got %s", want, diff)
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "lib.go")); string(data) != source {
+		t.Error("Expected the dry run to leave the file alone")
+	}
+
+	// Turning off dry_run writes the fixes
+	analyze(false)
+	data, _ := os.ReadFile(filepath.Join(dir, "lib.go"))
+	if !strings.Contains(string(data), "%w") || strings.Contains(string(data), "x = x") {
+		t.Errorf("Expected the fixes to be written, got %s", data)
+	}
+	if response := analyze(true); response["success"] != true {
+		t.Errorf("Expected no issues after the fixes, got %v", response)
+	}
+}
+
+func TestAnalyzeFixCode(t *testing.T) {
+	SetConfig(config.DefaultConfig())
+	t.Setenv("GOFLAGS", "")
+	result, err := ExecuteGoAnalyzeTool(context.Background(), newToolRequest("go_analyze", map[string]interface{}{
+		"code": "package main\n\nfunc main() {\n\tx := 1\n\tx = x\n\t_ = x\n}\n",
+		"fix":  true,
+	}))
+	if err != nil || result.IsError {
+		t.Fatalf("go_analyze failed: %v %s", err, resultText(result))
+	}
+	var response struct {
+		Message string `json:"message"`
+		Fixes   struct {
+			Files []FileFix `json:"files"`
+		} `json:"fixes"`
+	}
+	if err := json.Unmarshal([]byte(resultText(result)), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing is written; the fixed code is returned
+	if response.Message != "Suggested fixes produce new content for 1 files" {
+		t.Errorf("Unexpected message %q", response.Message)
+	}
+	if len(response.Fixes.Files) != 1 || strings.Contains(response.Fixes.Files[0].Content, "x = x") || response.Fixes.Files[0].Written {
+		t.Errorf("Expected the fixed content to be returned, got %s", resultText(result))
+	}
+}
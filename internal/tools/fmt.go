@@ -7,78 +7,50 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
+// Modes of go_fmt
+const (
+	FormatModeWrite = "write" // Rewrite the files
+	FormatModeCheck = "check" // List the files that are not formatted
+	FormatModeDiff  = "diff"  // Show how the files would be reformatted
+)
+
+// FileDiff is the unified diff of one file
+type FileDiff struct {
+	File string `json:"file"`
+	Diff string `json:"diff"`
+}
+
 // ExecuteGoFmtTool handles the go_fmt tool execution
 func ExecuteGoFmtTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) { // Resolve input
 	input, err := ResolveInput(req)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
+	ctx = withRequestLimits(ctx, req)
 
 	module := mcp.ParseString(req, "module", "") // For workspace module selection
+	mode := mcp.ParseString(req, "mode", FormatModeWrite)
+	if mode != FormatModeWrite && mode != FormatModeCheck && mode != FormatModeDiff {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid mode %q: use check, diff or write", mode)), nil
+	}
 
-	// Prepare format args
+	// Prepare format args; without write, go fmt -n only lists the files to check
 	args := []string{"fmt"}
+	if mode != FormatModeWrite {
+		args = append(args, "-n")
+	}
 
 	// Handle different source types
 	switch input.Source {
-	case SourceCode:
-		// For code, we'll use "gofmt" directly as it's better for formatting individual files
-		var formattedCode string
-
-		// Create temporary directory for code-based formatting
-		tmpDir, err := os.MkdirTemp("", "go-fmt-*")
-		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("Failed to create temp directory: %v", err)), nil
-		}
-		defer os.RemoveAll(tmpDir)
-
-		// Write code to temporary file
-		sourceFile := filepath.Join(tmpDir, "input.go")
-		if err := os.WriteFile(sourceFile, []byte(input.Code), 0644); err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("Failed to write source code: %v", err)), nil
-		}
-
-		// Run gofmt
-		cmd := exec.Command("gofmt", "-w", sourceFile)
-		cmd.Dir = tmpDir
-		result, err := execute(cmd)
-		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("Execution error: %v", err)), nil
-		}
-
-		// Read the formatted file
-		formattedBytes, err := os.ReadFile(sourceFile)
-		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("Failed to read formatted code: %v", err)), nil
-		}
-		formattedCode = string(formattedBytes)
-
-		// Determine if code was changed
-		codeChanged := formattedCode != input.Code
-
-		response := map[string]interface{}{
-			"success":     result.Successful,
-			"message":     "Code formatted successfully",
-			"code":        formattedCode,
-			"stdout":      result.Stdout,
-			"stderr":      result.Stderr,
-			"codeChanged": codeChanged,
-		}
-
-		// Add natural language metadata
-		AddNLMetadata(response, "go_fmt")
-
-		jsonBytes, err := json.MarshalIndent(response, "", "  ")
-		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("Error marshaling response: %v", err)), nil
-		}
-
-		return mcp.NewToolResultText(string(jsonBytes)), nil
+	case SourceCode, SourceHybrid:
+		// Code is formatted on its own with gofmt, never the project it belongs to
+		return executeCodeFormat(ctx, input, mode), nil
 
 	case SourceWorkspace:
 		// For workspace execution, handle module selection
@@ -99,17 +71,44 @@ func ExecuteGoFmtTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallTo
 	strategy := GetExecutionStrategy(input, args...)
 	result, err := strategy.Execute(ctx, input, args)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Execution error: %v", err)), nil
+		return executionErrorResult(err, "Execution error"), nil
+	}
+
+	// go fmt prints the files it rewrote; check and diff run gofmt on the listed files
+	root := diagnosticRoot(input)
+	changedFiles := []string{}
+	var diffs []FileDiff
+	if mode == FormatModeWrite {
+		for _, r := range moduleResults(result) {
+			for _, file := range parseFormattedFiles(r.Stdout) {
+				changedFiles = append(changedFiles, resolveDiagnosticPath(file, r.Dir, root))
+			}
+		}
+	} else {
+		changedFiles, diffs, err = checkFormatting(ctx, result, root, mode == FormatModeDiff)
+		if err != nil {
+			return executionErrorResult(err, "Execution error"), nil
+		}
 	}
+	sort.Strings(changedFiles)
 
 	response := map[string]interface{}{
-		"success":  result.Successful,
-		"message":  "Code formatted successfully",
-		"stdout":   result.Stdout,
-		"stderr":   result.Stderr,
-		"exitCode": result.ExitCode,
-		"duration": result.Duration.String(),
-		"source":   input.Source,
+		"success":      result.Successful,
+		"message":      formatMessage(mode, len(changedFiles)),
+		"mode":         mode,
+		"changedFiles": changedFiles,
+		"stdout":       result.Stdout,
+		"stderr":       result.Stderr,
+		"exitCode":     result.ExitCode,
+		"duration":     result.Duration.String(),
+		"sandbox":      result.Sandbox,
+		"source":       input.Source,
+	}
+	if mode != FormatModeWrite {
+		response["formatted"] = len(changedFiles) == 0
+	}
+	if mode == FormatModeDiff {
+		response["diffs"] = diffs
 	}
 
 	if input.Source == SourceWorkspace {
@@ -118,6 +117,9 @@ func ExecuteGoFmtTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallTo
 		if module != "" {
 			response["targetModule"] = module
 		}
+		if modules := moduleSummaries(result); modules != nil {
+			response["modules"] = modules
+		}
 	}
 
 	// Add natural language metadata
@@ -131,18 +133,257 @@ func ExecuteGoFmtTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallTo
 	return mcp.NewToolResultText(string(jsonBytes)), nil
 }
 
-// parseFormattedFiles extracts information about formatted files from go fmt output
+// executeCodeFormat formats code input with gofmt in a temporary directory. In
+// write mode it returns the formatted code and files, in diff mode a unified
+// diff of each changed file, and in check mode only which files would change.
+func executeCodeFormat(ctx context.Context, input InputContext, mode string) *mcp.CallToolResult {
+	// A lone snippet is formatted as input.go; multi-file input keeps its layout
+	mainFile := "input.go"
+	files := map[string]string{mainFile: input.Code}
+	if len(input.Files) > 0 {
+		var err error
+		mainFile = input.MainFile
+		if files, err = input.sourceFiles(); err != nil {
+			return mcp.NewToolResultError(err.Error())
+		}
+	}
+
+	// Formatting identical code gives identical results
+	key, cacheable := codeCacheKey(ctx, input, []string{"gofmt"})
+	result, formatted, ok := results.get(key)
+	if !cacheable || !ok {
+		var err error
+		result, formatted, err = formatFiles(ctx, files)
+		if err != nil {
+			return executionErrorResult(err, "Execution error")
+		}
+		if cacheable && result.Successful {
+			results.put(key, result, formatted)
+		}
+	}
+
+	changedFiles := []string{}
+	for name, content := range formatted {
+		if content != files[name] {
+			changedFiles = append(changedFiles, name)
+		}
+	}
+	sort.Strings(changedFiles)
+
+	response := map[string]interface{}{
+		"success":      result.Successful,
+		"message":      formatMessage(mode, len(changedFiles)),
+		"mode":         mode,
+		"changedFiles": changedFiles,
+		"stdout":       result.Stdout,
+		"stderr":       result.Stderr,
+		"sandbox":      result.Sandbox,
+		"cacheHit":     result.CacheHit,
+	}
+	switch mode {
+	case FormatModeWrite:
+		if input.Code != "" {
+			response["code"] = formatted[mainFile]
+			response["codeChanged"] = formatted[mainFile] != input.Code
+		}
+		if len(input.Files) > 0 {
+			response["files"] = formatted
+		}
+	case FormatModeDiff:
+		diffs := []FileDiff{}
+		for _, name := range changedFiles {
+			diffs = append(diffs, FileDiff{File: name, Diff: UnifiedDiff("a/"+name, "b/"+name, []byte(files[name]), []byte(formatted[name]))})
+		}
+		response["diffs"] = diffs
+		response["formatted"] = len(changedFiles) == 0
+	default:
+		response["formatted"] = len(changedFiles) == 0
+	}
+
+	// Add natural language metadata
+	AddNLMetadata(response, "go_fmt")
+
+	jsonBytes, err := json.MarshalIndent(response, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error marshaling response: %v", err))
+	}
+
+	return mcp.NewToolResultText(string(jsonBytes))
+}
+
+// formatFiles runs gofmt -w on the Go files among files in a temporary directory
+// and returns the formatted files keyed by slash-separated path
+func formatFiles(ctx context.Context, files map[string]string) (*ExecutionResult, map[string]string, error) {
+	// Create temporary directory for code-based formatting
+	tmpDir, err := os.MkdirTemp("", "go-fmt-*")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	var goFiles []string
+	for name, content := range files {
+		if !strings.HasSuffix(name, ".go") {
+			continue
+		}
+		target := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, nil, fmt.Errorf("failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(target, []byte(content), 0644); err != nil {
+			return nil, nil, fmt.Errorf("failed to write source code: %v", err)
+		}
+		goFiles = append(goFiles, filepath.FromSlash(name))
+	}
+	sort.Strings(goFiles)
+
+	// Run gofmt
+	cmd := exec.Command("gofmt", append([]string{"-w"}, goFiles...)...)
+	cmd.Dir = tmpDir
+	result, err := execute(ctx, cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Read the formatted files back
+	formatted := make(map[string]string, len(goFiles))
+	for _, name := range goFiles {
+		data, err := os.ReadFile(filepath.Join(tmpDir, name))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read formatted code: %v", err)
+		}
+		formatted[filepath.ToSlash(name)] = string(data)
+	}
+	return result, formatted, nil
+}
+
+// parseFormattedFiles returns the files listed in the output of go fmt or
+// gofmt -l, one per line
 func parseFormattedFiles(output string) []string {
-	// In a real implementation, this would parse go fmt output
-	// to determine which files were formatted
-	lines := strings.Split(output, "\n")
 	var files []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); strings.HasSuffix(line, ".go") {
+			files = append(files, line)
+		}
+	}
+	return files
+}
+
+// gofmtCommand returns the gofmt binary and files of the gofmt command lines
+// printed by go fmt -n
+func gofmtCommand(output string) (string, []string) {
+	gofmt := ""
+	var files []string
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || !strings.HasPrefix(filepath.Base(fields[0]), "gofmt") {
+			continue
+		}
+		gofmt = fields[0]
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "-") {
+				files = append(files, field)
+			}
+		}
+	}
+	return gofmt, files
+}
+
+// checkFormatting runs gofmt -l, or gofmt -d with diff, on the files go fmt -n
+// listed in each module of result, without rewriting them. result is updated
+// to report gofmt's output and status. It returns the files that are not
+// formatted, relative to root, and with diff their unified diffs.
+func checkFormatting(ctx context.Context, result *ExecutionResult, root string, diff bool) ([]string, []FileDiff, error) {
+	changedFiles := []string{}
+	diffs := []FileDiff{}
+	flag := "-l"
+	if diff {
+		flag = "-d"
+	}
+
+	for _, r := range moduleResults(result) {
+		gofmt, files := gofmtCommand(r.Stdout)
+		r.Stdout = ""
+		if !r.Successful || len(files) == 0 {
+			continue
+		}
+
+		cmd := exec.Command(gofmt, append([]string{flag}, files...)...)
+		cmd.Dir = r.Dir
+		check, err := execute(ctx, cmd)
+		if err != nil {
+			return nil, nil, err
+		}
+		r.Stdout = check.Stdout
+		r.Stderr += check.Stderr
+		// gofmt -d exits with status 1 when it prints a diff
+		r.Successful = check.Successful || (diff && check.ExitCode == 1 && check.Stderr == "")
+		if !r.Successful {
+			r.ExitCode = check.ExitCode
+		}
 
-	for _, line := range lines {
-		if strings.Contains(line, ".go") {
-			files = append(files, strings.TrimSpace(line))
+		if !diff {
+			for _, file := range parseFormattedFiles(check.Stdout) {
+				changedFiles = append(changedFiles, resolveDiagnosticPath(file, r.Dir, root))
+			}
+			continue
+		}
+		for _, d := range splitGofmtDiff(check.Stdout) {
+			file := resolveDiagnosticPath(d.File, r.Dir, root)
+			changedFiles = append(changedFiles, file)
+			diffs = append(diffs, FileDiff{File: file, Diff: d.Diff})
 		}
 	}
 
-	return files
+	// A fanned-out command reports the combined output of its modules
+	if len(result.Modules) > 0 {
+		var stdout, stderr strings.Builder
+		result.Successful = true
+		for _, m := range result.Modules {
+			stdout.WriteString(m.Stdout)
+			stderr.WriteString(m.Stderr)
+			result.Successful = result.Successful && m.Successful
+		}
+		result.Stdout, result.Stderr = stdout.String(), stderr.String()
+	}
+	return changedFiles, diffs, nil
+}
+
+// splitGofmtDiff splits the output of gofmt -d into the diff of each file,
+// which starts with a "diff file.orig file" line
+func splitGofmtDiff(output string) []FileDiff {
+	var diffs []FileDiff
+	for _, line := range strings.SplitAfter(output, "\n") {
+		if strings.HasPrefix(line, "diff ") {
+			fields := strings.Fields(line)
+			diffs = append(diffs, FileDiff{File: fields[len(fields)-1]})
+			continue
+		}
+		if len(diffs) > 0 {
+			diffs[len(diffs)-1].Diff += line
+		}
+	}
+	return diffs
+}
+
+// formatMessage describes the outcome of go_fmt in mode for the number of
+// changed files
+func formatMessage(mode string, changed int) string {
+	files := fmt.Sprintf("%d files", changed)
+	if changed == 1 {
+		files = "1 file"
+	}
+	switch {
+	case mode == FormatModeWrite && changed == 0:
+		return "Code formatted successfully"
+	case mode == FormatModeWrite:
+		return "Formatted " + files
+	case changed == 0:
+		return "All files are formatted"
+	case mode == FormatModeDiff:
+		return files + " would be reformatted"
+	case changed == 1:
+		return files + " needs formatting"
+	}
+	return files + " need formatting"
 }
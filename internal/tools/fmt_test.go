@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/MrFixit96/go-dev-mcp/internal/config"
+)
+
+func TestFormatModesProject(t *testing.T) {
+	SetConfig(config.DefaultConfig())
+	t.Setenv("GOFLAGS", "")
+	dir := t.TempDir()
+	unformatted := "package lib\nfunc  A() {}\n"
+	writeTestFiles(t, dir, map[string]string{
+		"go.mod":        "module example.com/lib\n\ngo 1.21\n",
+		"lib.go":        "package lib\n\nfunc B() {}\n",
+		"sub/sub.go":    unformatted,
+		"sub/x_test.go": "package lib\n\nimport \"testing\"\n\nfunc TestA(t *testing.T) {   }\n",
+	})
+
+	format := func(mode string) map[string]interface{} {
+		t.Helper()
+		result, err := ExecuteGoFmtTool(context.Background(), newToolRequest("go_fmt", map[string]interface{}{
+			"project_path": dir,
+			"mode":         mode,
+		}))
+		if err != nil || result.IsError {
+			t.Fatalf("go_fmt %s failed: %v %s", mode, err, resultText(result))
+		}
+		var response map[string]interface{}
+		if err := json.Unmarshal([]byte(resultText(result)), &response); err != nil {
+			t.Fatal(err)
+		}
+		return response
+	}
+	wantChanged := []interface{}{filepath.Join("sub", "sub.go"), filepath.Join("sub", "x_test.go")}
+
+	response := format(FormatModeCheck)
+	if !reflect.DeepEqual(response["changedFiles"], wantChanged) || response["formatted"] != false {
+		t.Errorf("Expected check to list the unformatted files, got %v", response)
+	}
+
+	response = format(FormatModeDiff)
+	diffs := response["diffs"].([]interface{})
+	if len(diffs) != 2 || !reflect.DeepEqual(response["changedFiles"], wantChanged) || response["success"] != true {
+		t.Fatalf("Expected a diff per unformatted file, got %v", response)
+	}
+	first := diffs[0].(map[string]interface{})
+	if first["file"] != wantChanged[0] || !strings.Contains(first["diff"].(string), "-func  A() {}\n+\n+func A() {}\n") {
+		t.Errorf("Unexpected diff %v", first)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "sub", "sub.go")); string(data) != unformatted {
+		t.Error("Expected check and diff to leave the files alone")
+	}
+
+	response = format(FormatModeWrite)
+	if !reflect.DeepEqual(response["changedFiles"], wantChanged) || response["message"] != "Formatted 2 files" {
+		t.Errorf("Expected write to list the files it rewrote, got %v", response)
+	}
+	if response = format(FormatModeCheck); response["formatted"] != true || len(response["changedFiles"].([]interface{})) != 0 {
+		t.Errorf("Expected everything to be formatted after write, got %v", response)
+	}
+}
+
+func TestFormatModeCode(t *testing.T) {
+	SetConfig(config.DefaultConfig())
+	code := "package main\nfunc main() {  }\n"
+
+	result, err := ExecuteGoFmtTool(context.Background(), newToolRequest("go_fmt", map[string]interface{}{"code": code, "mode": "diff"}))
+	if err != nil || result.IsError {
+		t.Fatalf("go_fmt failed: %v %s", err, resultText(result))
+	}
+	var response struct {
+		ChangedFiles []string   `json:"changedFiles"`
+		Diffs        []FileDiff `json:"diffs"`
+		Code         *string    `json:"code"`
+	}
+	if err := json.Unmarshal([]byte(resultText(result)), &response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.ChangedFiles) != 1 || len(response.Diffs) != 1 || !strings.Contains(response.Diffs[0].Diff, "+++ b/input.go") || response.Code != nil {
+		t.Errorf("Expected a diff of the snippet and no code, got %s", resultText(result))
+	}
+
+	result, _ = ExecuteGoFmtTool(context.Background(), newToolRequest("go_fmt", map[string]interface{}{"code": code, "mode": "tidy"}))
+	if !result.IsError {
+		t.Errorf("Expected an invalid mode to be rejected, got %s", resultText(result))
+	}
+}
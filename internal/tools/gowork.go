@@ -0,0 +1,178 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// GoWork is a parsed go.work file. It can be inspected, modified and written back;
+// comments in the original file are preserved.
+type GoWork struct {
+	Path string // Location of the go.work file
+	file *modfile.WorkFile
+}
+
+// GoWorkUse is a use directive
+type GoWorkUse struct {
+	Path       string `json:"path"`
+	ModulePath string `json:"modulePath,omitempty"` // Module path recorded in a trailing comment
+	Comment    string `json:"comment,omitempty"`
+}
+
+// GoWorkReplace is a replace directive
+type GoWorkReplace struct {
+	Old        string `json:"old"`
+	OldVersion string `json:"oldVersion,omitempty"`
+	New        string `json:"new"`
+	NewVersion string `json:"newVersion,omitempty"`
+	Local      bool   `json:"local"` // The replacement is a directory rather than a module
+}
+
+// GoWorkGodebug is a godebug key=value setting
+type GoWorkGodebug struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// GoWorkModel is the content of a go.work file in a form suitable for tool responses
+type GoWorkModel struct {
+	Go        string          `json:"go,omitempty"`
+	Toolchain string          `json:"toolchain,omitempty"`
+	Godebug   []GoWorkGodebug `json:"godebug,omitempty"`
+	Use       []GoWorkUse     `json:"use"`
+	Replace   []GoWorkReplace `json:"replace,omitempty"`
+}
+
+// LoadGoWork reads and parses the go.work file at path
+func LoadGoWork(path string) (*GoWork, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGoWork(path, data)
+}
+
+// ParseGoWork parses go.work content; path is used in error messages and by Save
+func ParseGoWork(path string, data []byte) (*GoWork, error) {
+	file, err := modfile.ParseWork(path, data, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &GoWork{Path: path, file: file}, nil
+}
+
+// Model returns the directives of the go.work file
+func (w *GoWork) Model() GoWorkModel {
+	model := GoWorkModel{Use: []GoWorkUse{}}
+	if w.file.Go != nil {
+		model.Go = w.file.Go.Version
+	}
+	if w.file.Toolchain != nil {
+		model.Toolchain = w.file.Toolchain.Name
+	}
+	for _, g := range w.file.Godebug {
+		model.Godebug = append(model.Godebug, GoWorkGodebug{Key: g.Key, Value: g.Value})
+	}
+	for _, u := range w.file.Use {
+		model.Use = append(model.Use, GoWorkUse{
+			Path:       u.Path,
+			ModulePath: u.ModulePath,
+			Comment:    lineComment(u.Syntax),
+		})
+	}
+	for _, r := range w.file.Replace {
+		model.Replace = append(model.Replace, GoWorkReplace{
+			Old:        r.Old.Path,
+			OldVersion: r.Old.Version,
+			New:        r.New.Path,
+			NewVersion: r.New.Version,
+			Local:      r.New.Version == "",
+		})
+	}
+	return model
+}
+
+// UsePaths returns the directories listed in use directives, in file order
+func (w *GoWork) UsePaths() []string {
+	paths := make([]string, 0, len(w.file.Use))
+	for _, u := range w.file.Use {
+		paths = append(paths, u.Path)
+	}
+	return paths
+}
+
+// AddUse adds a use directive for dir unless it is already present
+func (w *GoWork) AddUse(dir string) error {
+	return w.file.AddUse(dir, "")
+}
+
+// DropUse removes the use directive for dir
+func (w *GoWork) DropUse(dir string) error {
+	return w.file.DropUse(dir)
+}
+
+// AddReplace adds or updates a replace directive. oldVersion may be empty to
+// replace every version; newVersion must be empty when newPath is a directory.
+func (w *GoWork) AddReplace(oldPath, oldVersion, newPath, newVersion string) error {
+	return w.file.AddReplace(oldPath, oldVersion, newPath, newVersion)
+}
+
+// DropReplace removes the replace directive for oldPath at oldVersion
+func (w *GoWork) DropReplace(oldPath, oldVersion string) error {
+	return w.file.DropReplace(oldPath, oldVersion)
+}
+
+// SetGo sets the go directive; an empty version removes it
+func (w *GoWork) SetGo(version string) error {
+	if version == "" {
+		w.file.DropGoStmt()
+		return nil
+	}
+	return w.file.AddGoStmt(strings.TrimPrefix(version, "go"))
+}
+
+// SetToolchain sets the toolchain directive; an empty name removes it
+func (w *GoWork) SetToolchain(name string) error {
+	if name == "" {
+		w.file.DropToolchainStmt()
+		return nil
+	}
+	return w.file.AddToolchainStmt(name)
+}
+
+// SetGodebug sets a godebug key; an empty value removes it
+func (w *GoWork) SetGodebug(key, value string) error {
+	if value == "" {
+		return w.file.DropGodebug(key)
+	}
+	return w.file.AddGodebug(key, value)
+}
+
+// Format returns the go.work content including any modifications
+func (w *GoWork) Format() []byte {
+	w.file.Cleanup()
+	return modfile.Format(w.file.Syntax)
+}
+
+// Save writes the go.work file back to its path
+func (w *GoWork) Save() error {
+	if err := os.WriteFile(w.Path, w.Format(), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %v", w.Path, err)
+	}
+	return nil
+}
+
+// lineComment returns the trailing comment of a directive without the comment marker
+func lineComment(line *modfile.Line) string {
+	if line == nil {
+		return ""
+	}
+	var parts []string
+	for _, c := range line.Comments.Suffix {
+		parts = append(parts, strings.TrimSpace(strings.TrimPrefix(c.Token, "//")))
+	}
+	return strings.Join(parts, " ")
+}
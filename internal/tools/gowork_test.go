@@ -0,0 +1,112 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+const testGoWork = `// Workspace for the service and its tools
+go 1.22.1
+
+toolchain go1.23.4
+
+godebug default=go1.21
+
+use ./service // main service
+
+use (
+	// Shared libraries
+	./lib
+	./tools // build tooling
+)
+
+replace example.com/dep v1.2.0 => ../dep
+
+replace example.com/old => example.com/new v1.0.0
+`
+
+func TestParseGoWork(t *testing.T) {
+	work, err := ParseGoWork("go.work", []byte(testGoWork))
+	if err != nil {
+		t.Fatalf("ParseGoWork failed: %v", err)
+	}
+
+	model := work.Model()
+	if model.Go != "1.22.1" || model.Toolchain != "go1.23.4" {
+		t.Errorf("go/toolchain = %q/%q, want 1.22.1/go1.23.4", model.Go, model.Toolchain)
+	}
+	if len(model.Godebug) != 1 || model.Godebug[0].Key != "default" || model.Godebug[0].Value != "go1.21" {
+		t.Errorf("unexpected godebug: %+v", model.Godebug)
+	}
+
+	wantUse := []GoWorkUse{
+		{Path: "./service", Comment: "main service"},
+		{Path: "./lib"},
+		{Path: "./tools", Comment: "build tooling"},
+	}
+	if len(model.Use) != len(wantUse) {
+		t.Fatalf("got %d use directives, want %d: %+v", len(model.Use), len(wantUse), model.Use)
+	}
+	for i, want := range wantUse {
+		if model.Use[i] != want {
+			t.Errorf("use[%d] = %+v, want %+v", i, model.Use[i], want)
+		}
+	}
+
+	wantReplace := []GoWorkReplace{
+		{Old: "example.com/dep", OldVersion: "v1.2.0", New: "../dep", Local: true},
+		{Old: "example.com/old", New: "example.com/new", NewVersion: "v1.0.0"},
+	}
+	if len(model.Replace) != len(wantReplace) {
+		t.Fatalf("got %d replace directives, want %d", len(model.Replace), len(wantReplace))
+	}
+	for i, want := range wantReplace {
+		if model.Replace[i] != want {
+			t.Errorf("replace[%d] = %+v, want %+v", i, model.Replace[i], want)
+		}
+	}
+}
+
+func TestGoWorkEditPreservesComments(t *testing.T) {
+	work, err := ParseGoWork("go.work", []byte(testGoWork))
+	if err != nil {
+		t.Fatalf("ParseGoWork failed: %v", err)
+	}
+
+	if string(work.Format()) != testGoWork {
+		t.Errorf("unmodified go.work did not round-trip:\n%s", work.Format())
+	}
+
+	if err := work.SetGo("1.23.0"); err != nil {
+		t.Fatalf("SetGo failed: %v", err)
+	}
+	if err := work.AddUse("./cli"); err != nil {
+		t.Fatalf("AddUse failed: %v", err)
+	}
+	if err := work.DropUse("./lib"); err != nil {
+		t.Fatalf("DropUse failed: %v", err)
+	}
+	if err := work.DropReplace("example.com/old", ""); err != nil {
+		t.Fatalf("DropReplace failed: %v", err)
+	}
+
+	formatted := string(work.Format())
+	for _, want := range []string{"// Workspace for the service and its tools", "// main service", "// build tooling", "go 1.23.0", "./cli", "example.com/dep v1.2.0 => ../dep"} {
+		if !strings.Contains(formatted, want) {
+			t.Errorf("formatted go.work is missing %q:\n%s", want, formatted)
+		}
+	}
+	for _, gone := range []string{"./lib", "example.com/old"} {
+		if strings.Contains(formatted, gone) {
+			t.Errorf("formatted go.work still contains %q:\n%s", gone, formatted)
+		}
+	}
+
+	reparsed, err := ParseGoWork("go.work", []byte(formatted))
+	if err != nil {
+		t.Fatalf("formatted go.work does not parse: %v", err)
+	}
+	if paths := reparsed.UsePaths(); len(paths) != 3 {
+		t.Errorf("use paths after edit = %v", paths)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/mark3labs/mcp-go/mcp"
 )
@@ -32,8 +31,13 @@ type InputContext struct {
 	ProjectPath      string
 	WorkspacePath    string   // Path to go.work file or workspace root
 	WorkspaceModules []string // Discovered module paths within workspace
+	Parallelism      int      // Number of workspace modules processed concurrently
 	MainFile         string
 	TestCode         string
+	Files            map[string]string // Additional code files keyed by slash-separated path
+	Stdin            string            // Standard input passed to the command
+	Env              []string          // Extra KEY=VALUE environment variables for the command
+	WorkingDir       string            // Directory to run in instead of the project or workspace root
 }
 
 // ResolveInput determines whether the request contains code or a project path
@@ -46,6 +50,16 @@ func ResolveInput(req mcp.CallToolRequest) (InputContext, error) {
 		ctx.Source = SourceCode
 	}
 
+	// Extract multi-file code input (files map or txtar archive)
+	files, err := parseFilesInput(req)
+	if err != nil {
+		return ctx, err
+	}
+	if len(files) > 0 {
+		ctx.Files = files
+		ctx.Source = SourceCode
+	}
+
 	// Extract workspace_path if provided
 	if workspacePath, ok := req.GetArguments()["workspace_path"].(string); ok && workspacePath != "" {
 		ctx.WorkspacePath = workspacePath
@@ -60,6 +74,7 @@ func ResolveInput(req mcp.CallToolRequest) (InputContext, error) {
 			return ctx, fmt.Errorf("failed to detect workspace modules: %v", err)
 		}
 		ctx.WorkspaceModules = modules
+		ctx.Parallelism = int(mcp.ParseFloat64(req, "parallel", 1))
 		ctx.Source = SourceWorkspace
 	}
 
@@ -74,7 +89,7 @@ func ResolveInput(req mcp.CallToolRequest) (InputContext, error) {
 		// If workspace_path is also provided, it takes precedence
 		if ctx.Source != SourceWorkspace {
 			// If both code and project_path are provided, use hybrid source
-			if ctx.Code != "" {
+			if ctx.Code != "" || len(ctx.Files) > 0 {
 				ctx.Source = SourceHybrid
 			} else {
 				ctx.Source = SourceProjectPath
@@ -88,8 +103,9 @@ func ResolveInput(req mcp.CallToolRequest) (InputContext, error) {
 	}
 
 	// Validate input
-	if ctx.Source == SourceUnknown {
-		return ctx, fmt.Errorf("at least one of 'code', 'project_path', or 'workspace_path' must be provided")
+	session := mcp.ParseString(req, "session_id", "")
+	if ctx.Source == SourceUnknown && session == "" {
+		return ctx, fmt.Errorf("at least one of 'code', 'files', 'txtar', 'project_path', 'workspace_path', or 'session_id' must be provided")
 	}
 
 	// Set default main file
@@ -99,6 +115,20 @@ func ResolveInput(req mcp.CallToolRequest) (InputContext, error) {
 		ctx.MainFile = "main.go"
 	}
 
+	// Check that the code, test code and files fit together in one directory tree
+	if ctx.Source == SourceCode || ctx.Source == SourceHybrid {
+		if _, err := ctx.sourceFiles(); err != nil {
+			return ctx, err
+		}
+	}
+
+	// A scratch session is used like a project once the code is saved into it
+	if session != "" {
+		if err := resolveSession(&ctx, session); err != nil {
+			return ctx, err
+		}
+	}
+
 	return ctx, nil
 }
 
@@ -152,68 +182,15 @@ func detectWorkspaceModules(workspacePath string) ([]string, error) {
 }
 
 // ParseGoWorkFile parses a go.work file and returns the module paths.
-// It handles both single-line use directives (use ./module) and multi-line use blocks:
-//
-//	use (
-//	    ./module1
-//	    ./module2
-//	)
-//
-// The function ignores comments and empty lines, extracting only valid module paths.
-// Returns a slice of module paths as specified in the go.work file.
+// It returns the directories of all use directives, single-line (use ./module)
+// or in a block, exactly as written in the go.work file. Trailing comments are
+// ignored. See LoadGoWork for the other directives.
 func ParseGoWorkFile(goWorkPath string) ([]string, error) {
-	content, err := os.ReadFile(goWorkPath)
+	work, err := LoadGoWork(goWorkPath)
 	if err != nil {
 		return nil, err
 	}
-
-	var modules []string
-	lines := strings.Split(string(content), "\n")
-
-	inUseBlock := false
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		// Skip comments and empty lines
-		if strings.HasPrefix(line, "//") || line == "" {
-			continue
-		}
-
-		// Handle single-line use directive: "use ./module"
-		if strings.HasPrefix(line, "use ") && !strings.Contains(line, "(") {
-			parts := strings.Fields(line)
-			if len(parts) >= 2 {
-				modulePath := parts[1]
-				modules = append(modules, modulePath)
-			}
-			continue
-		}
-
-		// Handle parenthesized use block: "use ("
-		if strings.HasPrefix(line, "use (") || line == "use (" {
-			inUseBlock = true
-			continue
-		}
-
-		// Handle end of parenthesized block
-		if inUseBlock && line == ")" {
-			inUseBlock = false
-			continue
-		}
-
-		// Handle module paths inside parenthesized block
-		if inUseBlock {
-			// Remove any trailing comments
-			if idx := strings.Index(line, "//"); idx != -1 {
-				line = strings.TrimSpace(line[:idx])
-			}
-			if line != "" {
-				modules = append(modules, line)
-			}
-		}
-	}
-
-	return modules, nil
+	return work.UsePaths(), nil
 }
 
 // IsWorkspace checks if a given path contains a workspace (go.work file or multiple modules).
@@ -0,0 +1,383 @@
+package tools
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Job states reported by go_job_status
+const (
+	JobRunning    = "running"
+	JobCompleted  = "completed"  // The tool returned a result
+	JobFailed     = "failed"     // The tool returned an error result
+	JobCancelling = "cancelling" // Cancelled, but the tool call has not returned yet
+	JobCancelled  = "cancelled"
+)
+
+// maxJobOutput bounds the live output kept per job; the oldest output is dropped first
+const maxJobOutput = 1 << 20
+
+// Job is a tool call running in the background
+type Job struct {
+	ID        string
+	Tool      string
+	StartedAt time.Time
+	client    string // Client that started the job and may access it
+
+	mu         sync.Mutex
+	status     string
+	finishedAt time.Time
+	result     *mcp.CallToolResult
+	err        error
+	cancel     context.CancelFunc
+	output     []byte
+	dropped    int // Bytes dropped from the front of output
+	done       chan struct{}
+}
+
+// Write appends live command output to the job
+func (j *Job) Write(p []byte) (int, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.output = append(j.output, p...)
+	if excess := len(j.output) - maxJobOutput; excess > 0 {
+		j.output = append([]byte(nil), j.output[excess:]...)
+		j.dropped += excess
+	}
+	return len(p), nil
+}
+
+// Status returns the current state of the job
+func (j *Job) Status() string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status
+}
+
+// Done returns a channel that is closed when the tool call has returned
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// Output returns the output produced from offset on and the offset to continue
+// reading from. Output older than the retained window is skipped.
+func (j *Job) Output(offset int) (string, int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	end := j.dropped + len(j.output)
+	if offset < j.dropped {
+		offset = j.dropped
+	}
+	if offset > end {
+		offset = end
+	}
+	return string(j.output[offset-j.dropped:]), end
+}
+
+// finish records the result of the tool call
+func (j *Job) finish(result *mcp.CallToolResult, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.result, j.err = result, err
+	j.finishedAt = time.Now()
+	switch {
+	case j.status == JobCancelling:
+		j.status = JobCancelled
+	case err != nil || result == nil || result.IsError:
+		j.status = JobFailed
+	default:
+		j.status = JobCompleted
+	}
+	close(j.done)
+}
+
+// active reports whether the tool call has not returned yet. The caller must hold j.mu.
+func (j *Job) active() bool {
+	return j.status == JobRunning || j.status == JobCancelling
+}
+
+// finished reports whether the tool call has returned and when it did
+func (j *Job) finished() (bool, time.Time) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return !j.active(), j.finishedAt
+}
+
+// summary returns the job state for a tool response. The tool result is
+// included once the call has returned.
+func (j *Job) summary() map[string]interface{} {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	summary := map[string]interface{}{
+		"jobId":       j.ID,
+		"tool":        j.Tool,
+		"status":      j.status,
+		"startedAt":   j.StartedAt.Format(time.RFC3339),
+		"outputBytes": j.dropped + len(j.output),
+	}
+	if j.active() {
+		summary["duration"] = time.Since(j.StartedAt).Round(time.Millisecond).String()
+		return summary
+	}
+	summary["finishedAt"] = j.finishedAt.Format(time.RFC3339)
+	summary["duration"] = j.finishedAt.Sub(j.StartedAt).Round(time.Millisecond).String()
+	if j.err != nil {
+		summary["error"] = j.err.Error()
+	}
+	if j.result != nil {
+		summary["isError"] = j.result.IsError
+		if len(j.result.Content) > 0 {
+			if text, ok := j.result.Content[0].(mcp.TextContent); ok {
+				// Tool results are JSON documents; embed them as such when possible
+				if json.Valid([]byte(text.Text)) {
+					summary["result"] = json.RawMessage(text.Text)
+				} else {
+					summary["result"] = text.Text
+				}
+			}
+		}
+	}
+	return summary
+}
+
+// JobRegistry keeps track of background jobs. Finished jobs are kept for the
+// configured retention period, up to the configured number of jobs.
+type JobRegistry struct {
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+// NewJobRegistry creates an empty job registry
+func NewJobRegistry() *JobRegistry {
+	return &JobRegistry{jobs: make(map[string]*Job)}
+}
+
+// jobs is the registry used by the job tools
+var jobs = NewJobRegistry()
+
+// Start runs fn in the background as a job for tool. The job outlives ctx but
+// keeps its values; it is stopped only by Cancel or the limits carried by ctx.
+func (r *JobRegistry) Start(ctx context.Context, tool string, fn func(ctx context.Context) (*mcp.CallToolResult, error)) (*Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prune()
+
+	cfg := getConfig().Jobs
+	running := 0
+	for _, job := range r.jobs {
+		if done, _ := job.finished(); !done {
+			running++
+		}
+	}
+	if cfg.MaxRunning > 0 && running >= cfg.MaxRunning {
+		return nil, fmt.Errorf("too many jobs running (limit %d); wait for a job to finish or cancel one with go_job_cancel", cfg.MaxRunning)
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+	jobCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	job := &Job{
+		ID:        id,
+		Tool:      tool,
+		StartedAt: time.Now(),
+		client:    clientFromContext(ctx),
+		status:    JobRunning,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+	r.jobs[id] = job
+
+	go func() {
+		defer cancel()
+		job.finish(fn(withOutputWriter(jobCtx, job)))
+	}()
+	return job, nil
+}
+
+// Running returns the number of jobs that have not finished
+func (r *JobRegistry) Running() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	running := 0
+	for _, job := range r.jobs {
+		if done, _ := job.finished(); !done {
+			running++
+		}
+	}
+	return running
+}
+
+// Get returns the job with the given ID if it was started by the client of ctx
+func (r *JobRegistry) Get(ctx context.Context, id string) (*Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prune()
+	job, ok := r.jobs[id]
+	// Jobs of other clients are reported as unknown
+	if !ok || job.client != clientFromContext(ctx) {
+		return nil, fmt.Errorf("job %s not found; finished jobs are kept for %d seconds", id, getConfig().Jobs.RetentionSecs)
+	}
+	return job, nil
+}
+
+// Cancel stops a running job of the client of ctx. The job is cancelling until
+// its tool call returns. Cancelling a finished job has no effect.
+func (r *JobRegistry) Cancel(ctx context.Context, id string) (*Job, error) {
+	job, err := r.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	job.mu.Lock()
+	if job.status == JobRunning {
+		job.status = JobCancelling
+		job.cancel()
+	}
+	job.mu.Unlock()
+	return job, nil
+}
+
+// prune drops finished jobs past the retention period and the oldest finished
+// jobs beyond the retention count. The caller must hold r.mu.
+func (r *JobRegistry) prune() {
+	cfg := getConfig().Jobs
+	type finishedJob struct {
+		id string
+		at time.Time
+	}
+	var finished []finishedJob
+	for id, job := range r.jobs {
+		done, at := job.finished()
+		if !done {
+			continue
+		}
+		if cfg.RetentionSecs > 0 && time.Since(at) > time.Duration(cfg.RetentionSecs)*time.Second {
+			delete(r.jobs, id)
+			continue
+		}
+		finished = append(finished, finishedJob{id, at})
+	}
+	if cfg.MaxRetained <= 0 || len(finished) <= cfg.MaxRetained {
+		return
+	}
+	sort.Slice(finished, func(i, k int) bool { return finished[i].at.Before(finished[k].at) })
+	for _, job := range finished[:len(finished)-cfg.MaxRetained] {
+		delete(r.jobs, job.id)
+	}
+}
+
+// newJobID returns a random job identifier
+func newJobID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate job ID: %v", err)
+	}
+	return "job-" + hex.EncodeToString(b), nil
+}
+
+// WithAsync wraps a tool handler so that requests with async set run as a
+// background job. The job's commands use the jobs timeout instead of the
+// regular one, and per-request limits can still tighten it.
+func WithAsync(handler func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error)) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if !mcp.ParseBoolean(req, "async", false) {
+			return handler(ctx, req)
+		}
+
+		// The progress token belongs to this request, which returns right away
+		limits := getConfig().ResourceLimits
+		limits.TimeoutSecs = getConfig().Jobs.TimeoutSecs
+		ctx = WithResourceLimits(withoutProgress(ctx), limits)
+
+		job, err := jobs.Start(ctx, req.Params.Name, func(ctx context.Context) (*mcp.CallToolResult, error) {
+			return handler(ctx, req)
+		})
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		response := job.summary()
+		response["success"] = true
+		response["message"] = fmt.Sprintf("Started %s as job %s; poll go_job_status and go_job_output for progress", job.Tool, job.ID)
+		return jsonResult(response)
+	}
+}
+
+// ExecuteGoJobStatusTool handles the go_job_status tool execution
+func ExecuteGoJobStatusTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	job, err := jobFromRequest(ctx, req)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	response := job.summary()
+	response["success"] = true
+	return jsonResult(response)
+}
+
+// ExecuteGoJobOutputTool handles the go_job_output tool execution
+func ExecuteGoJobOutputTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	job, err := jobFromRequest(ctx, req)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// Output is read incrementally by passing back the returned nextOffset
+	output, next := job.Output(int(mcp.ParseFloat64(req, "offset", 0)))
+	response := map[string]interface{}{
+		"success":    true,
+		"jobId":      job.ID,
+		"status":     job.Status(),
+		"output":     output,
+		"nextOffset": next,
+	}
+	return jsonResult(response)
+}
+
+// ExecuteGoJobCancelTool handles the go_job_cancel tool execution
+func ExecuteGoJobCancelTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id, err := req.RequireString("job_id")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	job, err := jobs.Cancel(ctx, id)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	response := job.summary()
+	response["success"] = true
+	if status := job.Status(); status == JobCancelling || status == JobCancelled {
+		response["message"] = fmt.Sprintf("Job %s cancelled", job.ID)
+	} else {
+		response["message"] = fmt.Sprintf("Job %s already finished", job.ID)
+	}
+	return jsonResult(response)
+}
+
+// jobFromRequest looks up the job named by the job_id argument
+func jobFromRequest(ctx context.Context, req mcp.CallToolRequest) (*Job, error) {
+	id, err := req.RequireString("job_id")
+	if err != nil {
+		return nil, err
+	}
+	return jobs.Get(ctx, id)
+}
+
+// jsonResult marshals a tool response
+func jsonResult(response map[string]interface{}) (*mcp.CallToolResult, error) {
+	jsonBytes, err := json.MarshalIndent(response, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error marshaling response: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(jsonBytes)), nil
+}
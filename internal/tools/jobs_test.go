@@ -0,0 +1,189 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/MrFixit96/go-dev-mcp/internal/config"
+)
+
+// startTestJob runs a tool call with async set and returns the job ID
+func startTestJob(t *testing.T, handler func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error), name string, args map[string]interface{}) string {
+	t.Helper()
+	args["async"] = true
+	result, err := WithAsync(handler)(context.Background(), newToolRequest(name, args))
+	if err != nil || result.IsError {
+		t.Fatalf("%s failed to start: %v %s", name, err, resultText(result))
+	}
+	var response struct {
+		JobID  string `json:"jobId"`
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal([]byte(resultText(result)), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.JobID == "" || response.Status != JobRunning {
+		t.Fatalf("Unexpected start response: %s", resultText(result))
+	}
+	return response.JobID
+}
+
+// waitForJob waits until the job's tool call has returned
+func waitForJob(t *testing.T, id string) {
+	t.Helper()
+	job, err := jobs.Get(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-job.Done():
+	case <-time.After(time.Minute):
+		t.Fatalf("job %s did not finish", id)
+	}
+}
+
+func TestAsyncJob(t *testing.T) {
+	SetConfig(config.DefaultConfig())
+	id := startTestJob(t, ExecuteGoBuildTool, "go_build", map[string]interface{}{
+		"code": "package main\n\nfunc main() {}\n",
+	})
+	waitForJob(t, id)
+
+	result, err := ExecuteGoJobStatusTool(context.Background(), newToolRequest("go_job_status", map[string]interface{}{"job_id": id}))
+	if err != nil || result.IsError {
+		t.Fatalf("go_job_status failed: %v %s", err, resultText(result))
+	}
+	var status struct {
+		Status string `json:"status"`
+		Tool   string `json:"tool"`
+		Result struct {
+			Success bool `json:"success"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal([]byte(resultText(result)), &status); err != nil {
+		t.Fatalf("Failed to decode status: %v", err)
+	}
+	if status.Status != JobCompleted || status.Tool != "go_build" || !status.Result.Success {
+		t.Errorf("Unexpected job status: %s", resultText(result))
+	}
+
+	result, err = ExecuteGoJobOutputTool(context.Background(), newToolRequest("go_job_output", map[string]interface{}{"job_id": id}))
+	if err != nil || result.IsError {
+		t.Fatalf("go_job_output failed: %v %s", err, resultText(result))
+	}
+	var output struct {
+		Output     string `json:"output"`
+		NextOffset int    `json:"nextOffset"`
+	}
+	if err := json.Unmarshal([]byte(resultText(result)), &output); err != nil {
+		t.Fatalf("Failed to decode output: %v", err)
+	}
+	if !strings.Contains(output.Output, "go build") || output.NextOffset != len(output.Output) {
+		t.Errorf("Unexpected job output: %s", resultText(result))
+	}
+}
+
+func TestCancelJob(t *testing.T) {
+	SetConfig(config.DefaultConfig())
+	registry := NewJobRegistry()
+	ctx := withTestClient(context.Background(), "owner")
+	stopping := make(chan struct{})
+	release := make(chan struct{})
+	job, err := registry.Start(ctx, "go_test", func(ctx context.Context) (*mcp.CallToolResult, error) {
+		<-ctx.Done()
+		close(stopping)
+		<-release
+		return nil, ctx.Err()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Other clients cannot see or cancel the job
+	other := withTestClient(context.Background(), "other")
+	if _, err := registry.Get(other, job.ID); err == nil {
+		t.Error("Expected the job to be hidden from another client")
+	}
+	if _, err := registry.Cancel(other, job.ID); err == nil || job.Status() != JobRunning {
+		t.Error("Expected another client to be unable to cancel the job")
+	}
+
+	// The job counts as running until its tool call returns
+	if _, err := registry.Cancel(ctx, job.ID); err != nil {
+		t.Fatal(err)
+	}
+	<-stopping
+	if status, running := job.Status(), registry.Running(); status != JobCancelling || running != 1 {
+		t.Errorf("Expected a cancelling job that still runs, got %s with %d running", status, running)
+	}
+	close(release)
+	select {
+	case <-job.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("cancelled job did not stop")
+	}
+	if status := job.Status(); status != JobCancelled || registry.Running() != 0 {
+		t.Errorf("Expected job to be cancelled, got %s", status)
+	}
+}
+
+// testClient is a client session for tests that need one
+type testClient string
+
+func (c testClient) SessionID() string { return string(c) }
+
+func (c testClient) Initialize() {}
+
+func (c testClient) Initialized() bool { return true }
+
+func (c testClient) NotificationChannel() chan<- mcp.JSONRPCNotification { return nil }
+
+// withTestClient returns ctx as seen by a tool called by the client with the given session ID
+func withTestClient(ctx context.Context, id string) context.Context {
+	return server.NewMCPServer("test", "0.0.0").WithContext(ctx, testClient(id))
+}
+
+func TestJobRegistryRetention(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Jobs.MaxRunning = 1
+	cfg.Jobs.MaxRetained = 1
+	SetConfig(cfg)
+	defer SetConfig(config.DefaultConfig())
+
+	registry := NewJobRegistry()
+	release := make(chan struct{})
+	block := func(ctx context.Context) (*mcp.CallToolResult, error) {
+		<-release
+		return mcp.NewToolResultText("{}"), nil
+	}
+
+	first, err := registry.Start(context.Background(), "go_test", block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := registry.Start(context.Background(), "go_test", block); err == nil {
+		t.Error("Expected the running job limit to be enforced")
+	}
+	close(release)
+	<-first.Done()
+
+	second, err := registry.Start(context.Background(), "go_test", block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-second.Done()
+
+	// Only the most recently finished job is retained
+	if _, err := registry.Get(context.Background(), second.ID); err != nil {
+		t.Error(err)
+	}
+	if _, err := registry.Get(context.Background(), first.ID); err == nil {
+		t.Error("Expected the oldest finished job to be dropped")
+	}
+}
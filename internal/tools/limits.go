@@ -0,0 +1,121 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/MrFixit96/go-dev-mcp/internal/config"
+)
+
+// Resource limit kinds reported when a command is stopped for exceeding a limit
+const (
+	LimitTimeout = "timeout"
+	LimitMemory  = "memory"
+	LimitCPU     = "cpu"
+)
+
+// LimitExceededError reports that a command was stopped because it exceeded a resource limit
+type LimitExceededError struct {
+	Limit    string                // One of LimitTimeout, LimitMemory or LimitCPU
+	Limits   config.ResourceLimits // Limits that were in effect for the command
+	Duration time.Duration         // How long the command ran before it was stopped
+	Command  string                // Command that was executed
+}
+
+// Error implements the error interface
+func (e *LimitExceededError) Error() string {
+	switch e.Limit {
+	case LimitTimeout:
+		return fmt.Sprintf("timeout limit exceeded: command was stopped after %v", e.Duration.Round(time.Millisecond))
+	case LimitMemory:
+		return fmt.Sprintf("memory limit exceeded: command used more than %d MB", e.Limits.MemoryLimit)
+	case LimitCPU:
+		return fmt.Sprintf("cpu limit exceeded: command used more CPU time than %d core(s) allow", e.Limits.CPULimit)
+	default:
+		return fmt.Sprintf("%s limit exceeded", e.Limit)
+	}
+}
+
+// resourceLimitsKey is the context key for per-request resource limits
+type resourceLimitsKey struct{}
+
+// WithResourceLimits returns a context carrying the resource limits applied to
+// every command executed with it
+func WithResourceLimits(ctx context.Context, limits config.ResourceLimits) context.Context {
+	return context.WithValue(ctx, resourceLimitsKey{}, limits)
+}
+
+// resourceLimitsFromContext returns the limits stored in ctx, falling back to the configured defaults
+func resourceLimitsFromContext(ctx context.Context) config.ResourceLimits {
+	if limits, ok := ctx.Value(resourceLimitsKey{}).(config.ResourceLimits); ok {
+		return limits
+	}
+	return getConfig().ResourceLimits
+}
+
+// withRequestLimits attaches the resource limits for a tool request to ctx
+func withRequestLimits(ctx context.Context, req mcp.CallToolRequest) context.Context {
+	return WithResourceLimits(ctx, resolveResourceLimits(ctx, req))
+}
+
+// resolveResourceLimits combines the limits already carried by ctx (the
+// configured limits by default) with the per-request timeoutSecs, memoryLimitMB
+// and cpuLimit overrides. Overrides can tighten those limits but never loosen them.
+func resolveResourceLimits(ctx context.Context, req mcp.CallToolRequest) config.ResourceLimits {
+	limits := resourceLimitsFromContext(ctx)
+	limits.TimeoutSecs = tightenLimit(limits.TimeoutSecs, int(mcp.ParseFloat64(req, "timeoutSecs", 0)))
+	limits.MemoryLimit = tightenLimit(limits.MemoryLimit, int(mcp.ParseFloat64(req, "memoryLimitMB", 0)))
+	limits.CPULimit = tightenLimit(limits.CPULimit, int(mcp.ParseFloat64(req, "cpuLimit", 0)))
+	return limits
+}
+
+// tightenLimit returns the requested limit when it is stricter than the configured one.
+// A value of zero means unlimited.
+func tightenLimit(configured, requested int) int {
+	if requested <= 0 {
+		return configured
+	}
+	if configured <= 0 || requested < configured {
+		return requested
+	}
+	return configured
+}
+
+// limitEnv returns the environment for a limited command. With a CPU limit it
+// sets GOMAXPROCS to the limit unless the environment already sets it, which
+// caps the threads running Go code in each Go process that inherits it and the
+// go tool's default build parallelism. It does not bound the command's total
+// CPU use, which is left to the cgroup or RLIMIT_CPU.
+func limitEnv(env []string, limits config.ResourceLimits) []string {
+	if limits.CPULimit <= 0 {
+		return env
+	}
+	if env == nil {
+		env = os.Environ()
+	}
+	for _, kv := range env {
+		if strings.HasPrefix(kv, "GOMAXPROCS=") {
+			return env
+		}
+	}
+	return append(env, "GOMAXPROCS="+strconv.Itoa(limits.CPULimit))
+}
+
+// limitFromOutput detects limit violations reported by the Go runtime or the go tool
+// on behalf of a child process
+func limitFromOutput(stderr string, limits config.ResourceLimits) string {
+	if limits.MemoryLimit > 0 &&
+		(strings.Contains(stderr, "runtime: out of memory") || strings.Contains(stderr, "cannot allocate memory")) {
+		return LimitMemory
+	}
+	if limits.CPULimit > 0 && strings.Contains(stderr, "CPU time limit exceeded") {
+		return LimitCPU
+	}
+	return ""
+}
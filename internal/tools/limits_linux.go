@@ -0,0 +1,332 @@
+//go:build linux
+
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/MrFixit96/go-dev-mcp/internal/config"
+)
+
+// cgroupRoot is where the unified cgroup v2 hierarchy is expected to be mounted
+const cgroupRoot = "/sys/fs/cgroup"
+
+// rlimitHelperArg marks a re-execution of the server binary that sets rlimits
+// on itself before replacing itself with the limited command, so that nothing
+// the command starts runs without them
+const rlimitHelperArg = "__go-dev-mcp-rlimit"
+
+// rlimitSpec is passed from the server to the rlimit helper
+type rlimitSpec struct {
+	Path       string `json:"path"`       // Executable to run once the limits are set
+	DataBytes  uint64 `json:"dataBytes"`  // RLIMIT_DATA, 0 for none
+	CPUSeconds uint64 `json:"cpuSeconds"` // RLIMIT_CPU, 0 for none
+}
+
+func init() {
+	if len(os.Args) > 2 && os.Args[1] == rlimitHelperArg {
+		runRlimitHelper(os.Args[2], os.Args[3:])
+	}
+}
+
+// processLimiter enforces resource limits on a single command. On hosts with a
+// delegated cgroup v2 hierarchy each command runs in its own cgroup with
+// memory.max and cpu.max set; otherwise per-process rlimits are applied by the
+// rlimit helper before the command is executed.
+type processLimiter struct {
+	limits      config.ResourceLimits
+	cgroup      string   // Per-command cgroup directory, empty when using rlimits
+	cgroupFD    *os.File // Open handle used to start the process inside the cgroup
+	lateRlimits bool     // Apply rlimits with prlimit once started, without the helper
+}
+
+// newProcessLimiter prepares cmd so that limits can be enforced once it starts
+func newProcessLimiter(cmd *exec.Cmd, limits config.ResourceLimits) *processLimiter {
+	l := &processLimiter{limits: limits}
+	if limits.MemoryLimit <= 0 && limits.CPULimit <= 0 {
+		return l
+	}
+
+	parent, err := cgroupParent()
+	if err != nil {
+		l.useRlimits(cmd)
+		return l
+	}
+	if err := l.createCgroup(parent); err != nil {
+		log.Printf("Warning: failed to create cgroup, falling back to rlimits: %v", err)
+		l.cleanupCgroup()
+		l.useRlimits(cmd)
+		return l
+	}
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(l.cgroupFD.Fd())
+	return l
+}
+
+// createCgroup creates the per-command cgroup below parent and writes its limits
+func (l *processLimiter) createCgroup(parent string) error {
+	dir, err := os.MkdirTemp(parent, "exec-")
+	if err != nil {
+		return err
+	}
+	l.cgroup = dir
+
+	if l.limits.MemoryLimit > 0 {
+		memory := strconv.FormatInt(int64(l.limits.MemoryLimit)*1024*1024, 10)
+		if err := os.WriteFile(filepath.Join(dir, "memory.max"), []byte(memory), 0644); err != nil {
+			return fmt.Errorf("failed to set memory.max: %v", err)
+		}
+		// Without this the kernel swaps instead of enforcing the limit
+		_ = os.WriteFile(filepath.Join(dir, "memory.swap.max"), []byte("0"), 0644)
+	}
+	if l.limits.CPULimit > 0 {
+		const period = 100000
+		quota := fmt.Sprintf("%d %d", l.limits.CPULimit*period, period)
+		if err := os.WriteFile(filepath.Join(dir, "cpu.max"), []byte(quota), 0644); err != nil {
+			return fmt.Errorf("failed to set cpu.max: %v", err)
+		}
+	}
+
+	l.cgroupFD, err = os.Open(dir)
+	return err
+}
+
+// rlimits returns the rlimits that stand in for the cgroup limits
+func (l *processLimiter) rlimits() rlimitSpec {
+	var spec rlimitSpec
+	if l.limits.MemoryLimit > 0 {
+		spec.DataBytes = uint64(l.limits.MemoryLimit) * 1024 * 1024
+	}
+	if l.limits.CPULimit > 0 && l.limits.TimeoutSecs > 0 {
+		// Budget of CPU seconds equivalent to CPULimit cores busy for the whole timeout
+		spec.CPUSeconds = uint64(l.limits.CPULimit * l.limits.TimeoutSecs)
+	}
+	return spec
+}
+
+// useRlimits rewrites cmd to start the rlimit helper, which sets the rlimits
+// and then executes the command, so that they hold from its first instruction.
+// Without the server binary the rlimits are applied once the command started.
+func (l *processLimiter) useRlimits(cmd *exec.Cmd) {
+	spec := l.rlimits()
+	if spec.DataBytes == 0 && spec.CPUSeconds == 0 {
+		return
+	}
+	spec.Path = cmd.Path
+
+	executable, err := os.Executable()
+	if err != nil {
+		log.Printf("Warning: rlimit helper unavailable, applying rlimits after start: %v", err)
+		l.lateRlimits = true
+		return
+	}
+	encoded, err := json.Marshal(spec)
+	if err != nil {
+		l.lateRlimits = true
+		return
+	}
+	cmd.Path = executable
+	cmd.Args = append([]string{executable, rlimitHelperArg, string(encoded)}, cmd.Args...)
+}
+
+// started applies rlimits to the freshly started process when neither a cgroup
+// nor the rlimit helper is in use. Only processes spawned after this inherit them.
+func (l *processLimiter) started(pid int) {
+	if !l.lateRlimits {
+		return
+	}
+	spec := l.rlimits()
+	if spec.DataBytes > 0 {
+		if err := prlimit(pid, syscall.RLIMIT_DATA, &syscall.Rlimit{Cur: spec.DataBytes, Max: spec.DataBytes}); err != nil {
+			log.Printf("Warning: failed to apply memory limit to pid %d: %v", pid, err)
+		}
+	}
+	if spec.CPUSeconds > 0 {
+		if err := prlimit(pid, syscall.RLIMIT_CPU, &syscall.Rlimit{Cur: spec.CPUSeconds, Max: spec.CPUSeconds + 1}); err != nil {
+			log.Printf("Warning: failed to apply CPU limit to pid %d: %v", pid, err)
+		}
+	}
+}
+
+// runRlimitHelper sets the rlimits of spec on the current process and then
+// executes the command, which inherits them. It never returns.
+func runRlimitHelper(encoded string, argv []string) {
+	var spec rlimitSpec
+	if err := json.Unmarshal([]byte(encoded), &spec); err != nil {
+		rlimitFail("invalid rlimit spec: %v", err)
+	}
+	if len(argv) == 0 {
+		rlimitFail("no command given")
+	}
+
+	if spec.DataBytes > 0 {
+		if err := syscall.Setrlimit(syscall.RLIMIT_DATA, &syscall.Rlimit{Cur: spec.DataBytes, Max: spec.DataBytes}); err != nil {
+			rlimitFail("failed to set the memory limit: %v", err)
+		}
+	}
+	if spec.CPUSeconds > 0 {
+		if err := syscall.Setrlimit(syscall.RLIMIT_CPU, &syscall.Rlimit{Cur: spec.CPUSeconds, Max: spec.CPUSeconds + 1}); err != nil {
+			rlimitFail("failed to set the CPU limit: %v", err)
+		}
+	}
+	err := syscall.Exec(spec.Path, argv, os.Environ())
+	rlimitFail("failed to execute %s: %v", spec.Path, err)
+}
+
+// rlimitFail reports an rlimit helper error on stderr and exits with status 126
+func rlimitFail(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "limits: "+format+"\n", args...)
+	os.Exit(126)
+}
+
+// finish releases the limiter's resources and reports which limit, if any, was exceeded
+func (l *processLimiter) finish(state *os.ProcessState) string {
+	exceeded := ""
+	if l.cgroup != "" && l.cgroupOOMKilled() {
+		exceeded = LimitMemory
+	}
+	if state != nil {
+		if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() && status.Signal() == syscall.SIGXCPU {
+			exceeded = LimitCPU
+		}
+	}
+	l.cleanupCgroup()
+	return exceeded
+}
+
+// cgroupOOMKilled reports whether the kernel OOM killer fired inside the command's cgroup
+func (l *processLimiter) cgroupOOMKilled() bool {
+	data, err := os.ReadFile(filepath.Join(l.cgroup, "memory.events"))
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "oom_kill" && fields[1] != "0" {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanupCgroup kills anything left in the command's cgroup and removes it
+func (l *processLimiter) cleanupCgroup() {
+	if l.cgroupFD != nil {
+		l.cgroupFD.Close()
+		l.cgroupFD = nil
+	}
+	if l.cgroup == "" {
+		return
+	}
+	_ = os.WriteFile(filepath.Join(l.cgroup, "cgroup.kill"), []byte("1"), 0644)
+	for i := 0; i < 10; i++ {
+		if err := os.Remove(l.cgroup); err == nil || os.IsNotExist(err) {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	l.cgroup = ""
+}
+
+var (
+	cgroupOnce   sync.Once
+	cgroupBase   string
+	cgroupSetErr error
+)
+
+// cgroupParent returns the cgroup under which per-command cgroups are created.
+// It is resolved once; failures are logged and rlimits are used instead.
+func cgroupParent() (string, error) {
+	cgroupOnce.Do(func() {
+		cgroupBase, cgroupSetErr = setupCgroupParent()
+		if cgroupSetErr != nil {
+			log.Printf("cgroup v2 limits unavailable, using rlimits instead: %v", cgroupSetErr)
+		} else {
+			log.Printf("Enforcing resource limits with cgroup v2 under %s", cgroupBase)
+		}
+	})
+	return cgroupBase, cgroupSetErr
+}
+
+// setupCgroupParent prepares the server's own cgroup to delegate the memory and
+// cpu controllers to per-command children. Because a non-root cgroup with
+// enabled controllers may not contain processes, the server first moves itself
+// into a leaf cgroup.
+func setupCgroupParent() (string, error) {
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err != nil {
+		return "", fmt.Errorf("cgroup v2 is not mounted at %s", cgroupRoot)
+	}
+
+	data, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+	own := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "0::") {
+			own = strings.TrimPrefix(line, "0::")
+		}
+	}
+	if own == "" {
+		return "", fmt.Errorf("server is not in a cgroup v2 hierarchy")
+	}
+	ownDir := filepath.Join(cgroupRoot, own)
+
+	controllers, err := os.ReadFile(filepath.Join(ownDir, "cgroup.controllers"))
+	if err != nil {
+		return "", err
+	}
+	available := strings.Fields(string(controllers))
+	if !containsString(available, "memory") || !containsString(available, "cpu") {
+		return "", fmt.Errorf("memory and cpu controllers are not delegated to %s", ownDir)
+	}
+
+	if own != "/" {
+		serverDir := filepath.Join(ownDir, "go-dev-mcp-server")
+		if err := os.Mkdir(serverDir, 0755); err != nil && !os.IsExist(err) {
+			return "", err
+		}
+		if err := os.WriteFile(filepath.Join(serverDir, "cgroup.procs"), []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+			return "", fmt.Errorf("failed to move server into leaf cgroup: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(ownDir, "cgroup.subtree_control"), []byte("+memory +cpu"), 0644); err != nil {
+		return "", fmt.Errorf("failed to enable controllers: %v", err)
+	}
+	return ownDir, nil
+}
+
+// prlimit sets a resource limit on another process
+func prlimit(pid int, resource int, limit *syscall.Rlimit) error {
+	_, _, errno := syscall.RawSyscall6(syscall.SYS_PRLIMIT64,
+		uintptr(pid), uintptr(resource), uintptr(unsafe.Pointer(limit)), 0, 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+//go:build linux
+
+package tools
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/MrFixit96/go-dev-mcp/internal/config"
+)
+
+func TestRlimitHelper(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "ulimit -d; ulimit -t")
+	l := &processLimiter{limits: config.ResourceLimits{MemoryLimit: 512, CPULimit: 2, TimeoutSecs: 30}}
+	l.useRlimits(cmd)
+	if l.lateRlimits {
+		t.Skip("rlimit helper unavailable")
+	}
+
+	// The limits are in place before the command runs its first instruction
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Limited command failed: %v\n%s", err, output)
+	}
+	if got := strings.Fields(string(output)); len(got) != 2 || got[0] != "524288" || got[1] != "60" {
+		t.Errorf("Expected a 512 MB data limit and 60 CPU seconds, got %q", output)
+	}
+}
@@ -0,0 +1,27 @@
+//go:build !linux
+
+package tools
+
+import (
+	"os"
+	"os/exec"
+
+	"github.com/MrFixit96/go-dev-mcp/internal/config"
+)
+
+// processLimiter is a no-op outside Linux. The timeout and GOMAXPROCS are still
+// applied by execute; memory and CPU usage are not enforced by the OS.
+type processLimiter struct{}
+
+// newProcessLimiter prepares cmd so that limits can be enforced once it starts
+func newProcessLimiter(cmd *exec.Cmd, limits config.ResourceLimits) *processLimiter {
+	return &processLimiter{}
+}
+
+// started is called with the pid of the started process
+func (l *processLimiter) started(pid int) {}
+
+// finish reports which limit, if any, was exceeded
+func (l *processLimiter) finish(state *os.ProcessState) string {
+	return ""
+}
@@ -0,0 +1,76 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/MrFixit96/go-dev-mcp/internal/config"
+)
+
+func TestResolveResourceLimits(t *testing.T) {
+	SetConfig(config.DefaultConfig())
+	defaults := config.DefaultConfig().ResourceLimits
+
+	// Without overrides the configured limits apply
+	limits := resolveResourceLimits(context.Background(), newToolRequest("go_build", map[string]interface{}{}))
+	if limits != defaults {
+		t.Errorf("Expected default limits %+v, got %+v", defaults, limits)
+	}
+
+	// Overrides can tighten limits but not loosen them
+	limits = resolveResourceLimits(context.Background(), newToolRequest("go_build", map[string]interface{}{
+		"timeoutSecs":   float64(5),
+		"memoryLimitMB": float64(4096),
+		"cpuLimit":      float64(1),
+	}))
+	if limits.TimeoutSecs != 5 {
+		t.Errorf("Expected timeout override of 5, got %d", limits.TimeoutSecs)
+	}
+	if limits.MemoryLimit != defaults.MemoryLimit {
+		t.Errorf("Expected memory limit to stay at %d, got %d", defaults.MemoryLimit, limits.MemoryLimit)
+	}
+	if limits.CPULimit != 1 {
+		t.Errorf("Expected CPU override of 1, got %d", limits.CPULimit)
+	}
+
+	// Limits travel with the context
+	ctx := WithResourceLimits(context.Background(), limits)
+	if got := resourceLimitsFromContext(ctx); got != limits {
+		t.Errorf("Expected limits from context %+v, got %+v", limits, got)
+	}
+}
+
+func TestExecutionErrorResultReportsLimit(t *testing.T) {
+	err := &LimitExceededError{
+		Limit:    LimitMemory,
+		Limits:   config.ResourceLimits{MemoryLimit: 256},
+		Duration: time.Second,
+	}
+	result := executionErrorResult(err, "Execution error")
+	if !result.IsError {
+		t.Fatal("Expected an error result")
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	for _, want := range []string{`"limitExceeded":"memory"`, `"type":"resource_limit"`, "256 MB"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected response to contain %s, got %s", want, text)
+		}
+	}
+}
+
+// newToolRequest builds a tool call request with the given arguments
+func newToolRequest(name string, args map[string]interface{}) mcp.CallToolRequest {
+	req := mcp.CallToolRequest{}
+	req.Params.Name = name
+	req.Params.Arguments = args
+	return req
+}
+
+// resultText returns the text of a tool result
+func resultText(result *mcp.CallToolResult) string {
+	return result.Content[0].(mcp.TextContent).Text
+}
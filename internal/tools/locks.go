@@ -0,0 +1,349 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// lockMode is how a tool call uses the project or workspace it runs on
+type lockMode int
+
+const (
+	// lockRead is taken by commands that only read the module files (build, test, vet);
+	// any number of them share a root
+	lockRead lockMode = iota
+	// lockWrite is taken by commands that rewrite go.mod, go.sum, go.work or sources
+	// (mod, fmt, workspace); it excludes every other command on the root
+	lockWrite
+)
+
+// String implements fmt.Stringer
+func (m lockMode) String() string {
+	if m == lockWrite {
+		return "write"
+	}
+	return "read"
+}
+
+// LockTimeoutError reports that a tool call gave up waiting for the lock on its root
+type LockTimeoutError struct {
+	Root string        // Project or workspace root that was locked
+	Mode string        // Lock mode that was requested
+	Wait time.Duration // How long the call waited
+}
+
+// Error implements the error interface
+func (e *LockTimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %v waiting for the %s lock on %s; another call is modifying it", e.Wait.Round(time.Millisecond), e.Mode, e.Root)
+}
+
+// rootLock is a readers-writer lock that grants waiters in arrival order, so a
+// queued writer is not starved by a stream of readers
+type rootLock struct {
+	readers int
+	writing bool
+	waiters []*lockWaiter
+}
+
+// lockWaiter is a tool call queued for a root lock
+type lockWaiter struct {
+	mode    lockMode
+	granted chan struct{}
+}
+
+// available reports whether mode can be granted now
+func (l *rootLock) available(mode lockMode) bool {
+	if mode == lockWrite {
+		return !l.writing && l.readers == 0
+	}
+	return !l.writing
+}
+
+// take records that mode was granted
+func (l *rootLock) take(mode lockMode) {
+	if mode == lockWrite {
+		l.writing = true
+	} else {
+		l.readers++
+	}
+}
+
+// grant hands the lock to waiters at the front of the queue for as long as they fit
+func (l *rootLock) grant() {
+	for len(l.waiters) > 0 && l.available(l.waiters[0].mode) {
+		waiter := l.waiters[0]
+		l.waiters = l.waiters[1:]
+		l.take(waiter.mode)
+		close(waiter.granted)
+	}
+}
+
+// lockManager serializes tool calls that modify a project or workspace with the
+// other calls on the same root, keyed by the resolved root directory
+type lockManager struct {
+	mu    sync.Mutex
+	roots map[string]*rootLock
+}
+
+// newLockManager creates a lock manager without any locks
+func newLockManager() *lockManager {
+	return &lockManager{roots: make(map[string]*rootLock)}
+}
+
+// workspaceLocks is the lock manager used by WithWorkspaceLock
+var workspaceLocks = newLockManager()
+
+// acquire locks root in mode, waiting in line for at most timeout, and returns
+// the function that releases the lock along with the time spent waiting
+func (m *lockManager) acquire(ctx context.Context, root string, mode lockMode, timeout time.Duration) (func(), time.Duration, error) {
+	start := time.Now()
+	m.mu.Lock()
+	lock := m.lock(root)
+	release := m.releaser(root, lock, mode)
+
+	if len(lock.waiters) == 0 && lock.available(mode) {
+		lock.take(mode)
+		m.mu.Unlock()
+		return release, 0, nil
+	}
+	waiter := &lockWaiter{mode: mode, granted: make(chan struct{})}
+	lock.waiters = append(lock.waiters, waiter)
+	m.mu.Unlock()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	var err error
+	select {
+	case <-waiter.granted:
+		return release, time.Since(start), nil
+	case <-expired:
+		err = &LockTimeoutError{Root: root, Mode: mode.String(), Wait: time.Since(start)}
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	m.mu.Lock()
+	select {
+	case <-waiter.granted:
+		// The lock was granted while giving up; hand it back
+		m.mu.Unlock()
+		release()
+		return nil, time.Since(start), err
+	default:
+	}
+	for i, w := range lock.waiters {
+		if w == waiter {
+			lock.waiters = append(lock.waiters[:i], lock.waiters[i+1:]...)
+			break
+		}
+	}
+	// A writer leaving the front of the queue may let the readers behind it in
+	lock.grant()
+	if lock.readers == 0 && !lock.writing && len(lock.waiters) == 0 {
+		delete(m.roots, root)
+	}
+	m.mu.Unlock()
+	return nil, time.Since(start), err
+}
+
+// tryAcquire locks root in mode only if that needs no waiting, and then
+// returns the function that releases the lock
+func (m *lockManager) tryAcquire(root string, mode lockMode) (func(), bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lock := m.lock(root)
+	if len(lock.waiters) > 0 || !lock.available(mode) {
+		return nil, false
+	}
+	lock.take(mode)
+	return m.releaser(root, lock, mode), true
+}
+
+// lock returns the lock of root, creating it if needed. The caller must hold m.mu.
+func (m *lockManager) lock(root string) *rootLock {
+	lock, ok := m.roots[root]
+	if !ok {
+		lock = &rootLock{}
+		m.roots[root] = lock
+	}
+	return lock
+}
+
+// releaser returns the function that releases lock, held in mode, on root
+func (m *lockManager) releaser(root string, lock *rootLock, mode lockMode) func() {
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if mode == lockWrite {
+			lock.writing = false
+		} else {
+			lock.readers--
+		}
+		lock.grant()
+		if lock.readers == 0 && !lock.writing && len(lock.waiters) == 0 {
+			delete(m.roots, root)
+		}
+	}
+}
+
+// resolveLockRoot returns the directory that a tool call on path locks: the
+// enclosing workspace (go.work) if there is one, otherwise the enclosing module,
+// with symlinks resolved so that every spelling of a path shares one lock
+func resolveLockRoot(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+
+	module := ""
+	for dir := abs; ; dir = filepath.Dir(dir) {
+		if fileExists(filepath.Join(dir, "go.work")) {
+			return dir
+		}
+		if module == "" && fileExists(filepath.Join(dir, "go.mod")) {
+			module = dir
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			break
+		}
+	}
+	if module != "" {
+		return module
+	}
+	return abs
+}
+
+// requestLockRoot returns the root a tool call runs on, or "" for calls on
+// code input alone, which get a temporary module of their own
+func requestLockRoot(req mcp.CallToolRequest) string {
+	if path := mcp.ParseString(req, "workspace_path", ""); path != "" {
+		return resolveLockRoot(path)
+	}
+	if path := mcp.ParseString(req, "project_path", ""); path != "" {
+		return resolveLockRoot(path)
+	}
+	if id := mcp.ParseString(req, "session_id", ""); id != "" {
+		if session, err := sessions.Get(id); err == nil {
+			return resolveLockRoot(session.Dir)
+		}
+	}
+	return ""
+}
+
+// requestLockMode returns how a tool call uses its root
+func requestLockMode(req mcp.CallToolRequest) lockMode {
+	// Code sent along with a session is written into the session directory
+	if mcp.ParseString(req, "session_id", "") != "" && hasCodeInput(req) {
+		return lockWrite
+	}
+	switch req.Params.Name {
+	case "go_mod":
+		return lockWrite
+	case "go_fmt":
+		// Checking and diffing leave the files alone
+		if mode := mcp.ParseString(req, "mode", FormatModeWrite); mode == FormatModeWrite {
+			return lockWrite
+		}
+	case "go_analyze":
+		// Fixes are written to the project unless they are only previewed
+		if mcp.ParseBoolean(req, "fix", false) && !mcp.ParseBoolean(req, "dry_run", true) {
+			return lockWrite
+		}
+	case "go_workspace":
+		if mcp.ParseString(req, "command", "") == "info" {
+			return lockRead
+		}
+		return lockWrite
+	}
+	return lockRead
+}
+
+// hasCodeInput reports whether a tool call carries code, test code or files
+func hasCodeInput(req mcp.CallToolRequest) bool {
+	for _, key := range []string{"code", "testCode", "txtar"} {
+		if mcp.ParseString(req, key, "") != "" {
+			return true
+		}
+	}
+	files, ok := req.GetArguments()["files"].(map[string]interface{})
+	return ok && len(files) > 0
+}
+
+// WithWorkspaceLock wraps a tool handler so that calls on the same project or
+// workspace root are serialized: reading calls (build, test, vet) run together,
+// while calls that modify the root (mod, fmt, workspace, written fixes, code
+// sent to a session) run alone. The time spent waiting is reported as lockWait in the response.
+func WithWorkspaceLock(handler func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error)) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		root := requestLockRoot(req)
+		if root == "" {
+			return handler(ctx, req)
+		}
+
+		mode := requestLockMode(req)
+		timeout := time.Duration(getConfig().Locks.TimeoutSecs) * time.Second
+		release, wait, err := workspaceLocks.acquire(ctx, root, mode, timeout)
+		if err != nil {
+			return executionErrorResult(err, "Lock error"), nil
+		}
+		defer release()
+		if wait > 0 {
+			reportPhase(ctx, fmt.Sprintf("acquired %s lock on %s after %v", mode, root, wait.Round(time.Millisecond)))
+		}
+
+		result, err := handler(ctx, req)
+		if result != nil {
+			addResultField(result, "lockWait", wait.Round(time.Microsecond).String())
+		}
+		return result, err
+	}
+}
+
+// addResultField sets a field of a tool result whose text is a JSON object.
+// Other results are left as they are.
+func addResultField(result *mcp.CallToolResult, key string, value interface{}) {
+	if len(result.Content) == 0 {
+		return
+	}
+	text, ok := result.Content[0].(mcp.TextContent)
+	if !ok {
+		return
+	}
+	// Numbers are kept as written
+	var response map[string]interface{}
+	decoder := json.NewDecoder(strings.NewReader(text.Text))
+	decoder.UseNumber()
+	if decoder.Decode(&response) != nil {
+		return
+	}
+	response[key] = value
+
+	// Keep the layout of the original response
+	indent := ""
+	if len(text.Text) > 1 && text.Text[1] == '\n' {
+		indent = "  "
+	}
+	data, err := json.MarshalIndent(response, "", indent)
+	if indent == "" {
+		data, err = json.Marshal(response)
+	}
+	if err != nil {
+		return
+	}
+	text.Text = string(data)
+	result.Content[0] = text
+}
@@ -0,0 +1,153 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MrFixit96/go-dev-mcp/internal/config"
+)
+
+func TestLockManagerReadWrite(t *testing.T) {
+	m := newLockManager()
+	ctx := context.Background()
+
+	// Readers share the root
+	releaseA, _, err := m.acquire(ctx, "/root", lockRead, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	releaseB, wait, err := m.acquire(ctx, "/root", lockRead, time.Second)
+	if err != nil || wait != 0 {
+		t.Fatalf("Expected a second reader to get the lock at once, got %v after %v", err, wait)
+	}
+
+	// A writer waits for the readers and gives up after its timeout
+	_, _, err = m.acquire(ctx, "/root", lockWrite, 50*time.Millisecond)
+	var timeoutErr *LockTimeoutError
+	if !errors.As(err, &timeoutErr) || timeoutErr.Mode != "write" {
+		t.Fatalf("Expected a lock timeout, got %v", err)
+	}
+
+	// A queued writer gets the lock when the readers are done, ahead of later readers
+	granted := make(chan time.Duration)
+	go func() {
+		release, wait, err := m.acquire(ctx, "/root", lockWrite, 5*time.Second)
+		if err != nil {
+			t.Error(err)
+			close(granted)
+			return
+		}
+		release()
+		granted <- wait
+	}()
+	for {
+		m.mu.Lock()
+		queued := len(m.roots["/root"].waiters)
+		m.mu.Unlock()
+		if queued == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, _, err := m.acquire(ctx, "/root", lockRead, 20*time.Millisecond); err == nil {
+		t.Error("Expected a reader behind a queued writer to wait")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	releaseA()
+	releaseB()
+	if wait := <-granted; wait < 20*time.Millisecond {
+		t.Errorf("Expected the writer to report its wait, got %v", wait)
+	}
+
+	// Other roots are independent, and idle roots are forgotten
+	release, wait, err := m.acquire(ctx, "/other", lockWrite, time.Second)
+	if err != nil || wait != 0 {
+		t.Fatalf("Expected an unrelated root to be free, got %v after %v", err, wait)
+	}
+	release()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.roots) != 0 {
+		t.Errorf("Expected no locks to be left, got %d", len(m.roots))
+	}
+}
+
+func TestResolveLockRoot(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"go.work":         "go 1.23\n\nuse ./app\n",
+		"app/go.mod":      "module example.com/app\n\ngo 1.23\n",
+		"app/cmd/main.go": "package main\n\nfunc main() {}\n",
+	})
+	link := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(filepath.Join(dir, "app"), link); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+
+	want, _ := filepath.EvalSymlinks(dir)
+	for _, path := range []string{dir, filepath.Join(dir, "app", "cmd"), link} {
+		if got := resolveLockRoot(path); got != want {
+			t.Errorf("resolveLockRoot(%s) = %s, want %s", path, got, want)
+		}
+	}
+}
+
+func TestRequestLockMode(t *testing.T) {
+	tests := []struct {
+		name string
+		tool string
+		args map[string]interface{}
+		want lockMode
+	}{
+		{"build", "go_build", map[string]interface{}{"project_path": "."}, lockRead},
+		{"session build", "go_build", map[string]interface{}{"session_id": "s"}, lockRead},
+		{"session code", "go_build", map[string]interface{}{"session_id": "s", "code": "package main"}, lockWrite},
+		{"session test code", "go_test", map[string]interface{}{"session_id": "s", "testCode": "package main"}, lockWrite},
+		{"session files", "go_run", map[string]interface{}{"session_id": "s", "files": map[string]interface{}{"a.go": "package main"}}, lockWrite},
+		{"format check", "go_fmt", map[string]interface{}{"project_path": ".", "mode": "check"}, lockRead},
+		{"mod", "go_mod", map[string]interface{}{"project_path": "."}, lockWrite},
+	}
+	for _, tt := range tests {
+		if got := requestLockMode(newToolRequest(tt.tool, tt.args)); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWithWorkspaceLock(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Locks.TimeoutSecs = 1
+	SetConfig(cfg)
+	defer SetConfig(config.DefaultConfig())
+	t.Setenv("GOFLAGS", "")
+
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"go.mod":  "module example.com/locked\n\ngo 1.23\n",
+		"main.go": "package main\n\nfunc main() {}\n",
+	})
+	build := WithWorkspaceLock(ExecuteGoBuildTool)
+	req := newToolRequest("go_build", map[string]interface{}{"project_path": dir})
+
+	result, err := build(context.Background(), req)
+	if err != nil || result.IsError || !strings.Contains(resultText(result), `"lockWait"`) {
+		t.Fatalf("Expected a build with lockWait, got %v %s", err, resultText(result))
+	}
+
+	// A build waits for a call that is modifying the project
+	release, _, err := workspaceLocks.acquire(context.Background(), resolveLockRoot(dir), lockWrite, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err = build(context.Background(), req)
+	release()
+	if err != nil || !result.IsError || !strings.Contains(resultText(result), "waiting for the read lock") {
+		t.Errorf("Expected a lock timeout, got %v %s", err, resultText(result))
+	}
+}
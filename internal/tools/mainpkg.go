@@ -0,0 +1,155 @@
+package tools
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// MainPackageError reports that go_run could not pick a main package. Candidates
+// lists the main packages that were found, if any.
+type MainPackageError struct {
+	Message    string
+	Candidates []string
+}
+
+func (e *MainPackageError) Error() string {
+	if len(e.Candidates) == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("%s (candidates: %s)", e.Message, strings.Join(e.Candidates, ", "))
+}
+
+// resolveMainPackage returns the package go run should execute, relative to root
+// (the project or workspace directory), or as an absolute directory for a
+// workspace module outside root. Packages are looked up in the given workspace
+// modules, or in root itself when modules is empty. An explicit pkg may be a
+// directory relative to the module or an import path; without one, the main
+// package is discovered and must be unique.
+func resolveMainPackage(root string, modules []string, pkg string) (string, error) {
+	if len(modules) == 0 {
+		modules = []string{"."}
+	}
+
+	if pkg != "" {
+		if len(modules) > 1 {
+			// Directories are relative to the workspace root when no module is selected
+			modules = []string{"."}
+		}
+		local := isLocalPackage(pkg)
+		if filepath.IsAbs(pkg) {
+			local = true
+			if !insideDir(root, pkg) && !insideDir(workspaceModuleDir(root, modules[0]), pkg) {
+				return "", fmt.Errorf("package %s is outside %s", pkg, root)
+			}
+			pkg, modules = ".", []string{filepath.Clean(pkg)}
+		}
+		dir := filepath.Join(workspaceModuleDir(root, modules[0]), filepath.FromSlash(pkg))
+		if !local && !dirExists(dir) {
+			// An import path, which go run resolves itself
+			return pkg, nil
+		}
+		if !dirExists(dir) {
+			return "", &MainPackageError{Message: fmt.Sprintf("package directory %s does not exist", pkg), Candidates: findMainPackages(root, modules)}
+		}
+		if !isMainPackage(dir) {
+			return "", &MainPackageError{Message: fmt.Sprintf("%s is not a main package", pkg), Candidates: findMainPackages(root, modules)}
+		}
+		return modulePackage(root, modules[0], pkg), nil
+	}
+
+	candidates := findMainPackages(root, modules)
+	switch len(candidates) {
+	case 0:
+		return "", &MainPackageError{Message: "no main package found; expected main.go in the module root or a main package under cmd/"}
+	case 1:
+		return candidates[0], nil
+	default:
+		return "", &MainPackageError{Message: "multiple main packages found; set package to choose one", Candidates: candidates}
+	}
+}
+
+// findMainPackages lists the main packages at the root of each module and in the
+// directories directly under its cmd directory, in the form modulePackage returns
+func findMainPackages(root string, modules []string) []string {
+	var found []string
+	for _, module := range modules {
+		moduleDir := workspaceModuleDir(root, module)
+		if isMainPackage(moduleDir) {
+			found = append(found, modulePackage(root, module, "."))
+		}
+		entries, err := os.ReadDir(filepath.Join(moduleDir, "cmd"))
+		if err != nil {
+			continue
+		}
+		var cmds []string
+		for _, entry := range entries {
+			if entry.IsDir() && isMainPackage(filepath.Join(moduleDir, "cmd", entry.Name())) {
+				cmds = append(cmds, modulePackage(root, module, "cmd/"+entry.Name()))
+			}
+		}
+		sort.Strings(cmds)
+		found = append(found, cmds...)
+	}
+	return found
+}
+
+// modulePackage returns package directory pkg of a workspace module as go run
+// expects it from root: ./-relative when it is inside root, absolute otherwise
+func modulePackage(root, module, pkg string) string {
+	dir := filepath.Join(workspaceModuleDir(root, module), filepath.FromSlash(pkg))
+	if insideDir(root, dir) {
+		rel, _ := filepath.Rel(root, dir)
+		return relativePackage(".", rel)
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		return abs
+	}
+	return dir
+}
+
+// insideDir reports whether path is dir or inside it
+func insideDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && filepath.IsLocal(rel)
+}
+
+// isMainPackage reports whether dir contains a non-test Go file declaring package main
+func isMainPackage(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	fset := token.NewFileSet()
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.PackageClauseOnly)
+		if err == nil && file.Name.Name == "main" {
+			return true
+		}
+	}
+	return false
+}
+
+// relativePackage joins a package directory onto a module directory and returns
+// it in the ./-prefixed form go run expects for local packages
+func relativePackage(module, pkg string) string {
+	joined := path.Join(filepath.ToSlash(module), filepath.ToSlash(pkg))
+	if joined == "." || joined == ".." || strings.HasPrefix(joined, "../") {
+		return joined
+	}
+	return "./" + joined
+}
+
+// isLocalPackage reports whether pkg is written as a directory rather than an import path
+func isLocalPackage(pkg string) bool {
+	return pkg == "." || pkg == ".." || strings.HasPrefix(pkg, "./") || strings.HasPrefix(pkg, "../")
+}
@@ -0,0 +1,129 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeTestFiles creates files below dir from a map of slash-separated paths to contents
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+}
+
+func TestResolveMainPackage(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"go.mod":                 "module example.com/app\n\ngo 1.21\n",
+		"main.go":                "package main\n\nfunc main() {}\n",
+		"cmd/server/main.go":     "package main\n\nfunc main() {}\n",
+		"cmd/worker/main.go":     "package main\n\nfunc main() {}\n",
+		"cmd/shared/shared.go":   "package shared\n",
+		"cmd/testonly/x_test.go": "package main\n",
+	})
+
+	_, err := resolveMainPackage(root, nil, "")
+	var pkgErr *MainPackageError
+	if !errors.As(err, &pkgErr) {
+		t.Fatalf("Expected an ambiguity error, got %v", err)
+	}
+	want := []string{".", "./cmd/server", "./cmd/worker"}
+	if !reflect.DeepEqual(pkgErr.Candidates, want) {
+		t.Errorf("Candidates = %v, want %v", pkgErr.Candidates, want)
+	}
+
+	tests := []struct {
+		pkg  string
+		want string
+	}{
+		{"./cmd/server", "./cmd/server"},
+		{"cmd/worker", "./cmd/worker"},
+		{filepath.Join(root, "cmd", "worker"), "./cmd/worker"},
+		{"example.com/other/cmd/tool", "example.com/other/cmd/tool"},
+	}
+	for _, tt := range tests {
+		got, err := resolveMainPackage(root, nil, tt.pkg)
+		if err != nil || got != tt.want {
+			t.Errorf("resolveMainPackage(%q) = %q, %v; want %q", tt.pkg, got, err, tt.want)
+		}
+	}
+
+	if _, err := resolveMainPackage(root, nil, "./cmd/shared"); !errors.As(err, &pkgErr) || len(pkgErr.Candidates) != 3 {
+		t.Errorf("Expected a not-main error with candidates, got %v", err)
+	}
+
+	// Workspace modules are searched relative to the workspace root
+	if err := os.Remove(filepath.Join(root, "main.go")); err != nil {
+		t.Fatal(err)
+	}
+	got, err := resolveMainPackage(filepath.Dir(root), []string{"./" + filepath.Base(root)}, "./server")
+	if err == nil || got != "" {
+		t.Errorf("Expected an error for a missing package directory, got %q", got)
+	}
+	got, err = resolveMainPackage(filepath.Dir(root), []string{"./" + filepath.Base(root)}, "./cmd/server")
+	if err != nil || got != "./"+filepath.Base(root)+"/cmd/server" {
+		t.Errorf("Unexpected workspace package %q, %v", got, err)
+	}
+}
+
+func TestResolveMainPackageAbsoluteModule(t *testing.T) {
+	root := t.TempDir()
+	ext := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"app/go.mod":  "module example.com/app\n\ngo 1.21\n",
+		"app/main.go": "package main\n\nfunc main() {}\n",
+	})
+	writeTestFiles(t, ext, map[string]string{
+		"tool/go.mod":  "module example.com/tool\n\ngo 1.21\n",
+		"tool/main.go": "package main\n\nfunc main() {}\n",
+	})
+	tool := filepath.Join(ext, "tool")
+
+	// A module outside the workspace root takes part in discovery
+	_, err := resolveMainPackage(root, []string{"./app", tool}, "")
+	var pkgErr *MainPackageError
+	if !errors.As(err, &pkgErr) || !reflect.DeepEqual(pkgErr.Candidates, []string{"./app", tool}) {
+		t.Fatalf("Expected an ambiguity between ./app and %s, got %v", tool, err)
+	}
+
+	// and is run by its absolute directory
+	if got, err := resolveMainPackage(root, []string{tool}, ""); err != nil || got != tool {
+		t.Errorf("resolveMainPackage for %s = %q, %v", tool, got, err)
+	}
+	if got, err := resolveMainPackage(root, []string{tool}, "."); err != nil || got != tool {
+		t.Errorf("resolveMainPackage for . in %s = %q, %v", tool, got, err)
+	}
+}
+
+func TestRunDiscoversMainPackage(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"go.mod":            "module example.com/app\n\ngo 1.21\n",
+		"cmd/hello/main.go": "package main\n\nimport \"fmt\"\n\nfunc main() { fmt.Println(\"hello from cmd\") }\n",
+		"greet/greet.go":    "package greet\n",
+	})
+
+	result, err := ExecuteGoRunTool(context.Background(), newToolRequest("go_run", map[string]interface{}{
+		"project_path": root,
+	}))
+	if err != nil || result.IsError {
+		t.Fatalf("go_run failed: %v %+v", err, result)
+	}
+	text := resultText(result)
+	if !strings.Contains(text, "hello from cmd") || !strings.Contains(text, `"package": "./cmd/hello"`) {
+		t.Errorf("Unexpected go_run response:\n%s", text)
+	}
+}
@@ -17,6 +17,7 @@ func ExecuteGoModTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallTo
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
+	ctx = withRequestLimits(ctx, req)
 
 	// Extract parameters using new v0.29.0 API
 	command, ok := req.GetArguments()["command"].(string)
@@ -54,6 +55,16 @@ func ExecuteGoModTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallTo
 		args = append(args, modulePath)
 	}
 
+	// Module commands cannot use a code overlay; with a project path the code and
+	// files are only context and the command runs on the project itself
+	if input.Source == SourceHybrid {
+		input.Source = SourceProjectPath
+		input.Code = ""
+		input.Files = nil
+		input.MainFile = ""
+		input.TestCode = ""
+	}
+
 	// Handle workspace-specific module operations
 	if input.Source == SourceWorkspace && module != "" {
 		// For workspace operations with specific module, we need to change to that module directory
@@ -63,7 +74,7 @@ func ExecuteGoModTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallTo
 	strategy := GetExecutionStrategy(input, args...)
 	result, err := strategy.Execute(ctx, input, args)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Execution error: %v", err)), nil
+		return executionErrorResult(err, "Execution error"), nil
 	}
 
 	// For certain commands, try to read go.mod content from the working directory
@@ -102,6 +113,7 @@ func ExecuteGoModTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallTo
 		"stderr":   result.Stderr,
 		"exitCode": result.ExitCode,
 		"duration": result.Duration.String(),
+		"sandbox":  result.Sandbox,
 		"source":   input.Source,
 	}
 
@@ -0,0 +1,132 @@
+package tools
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// offlineEnv are the variables that keep an offline go command off the network:
+// modules come only from the module cache, no checksum database is consulted
+// and no toolchain is downloaded
+var offlineEnv = []string{
+	"GOPROXY=off",
+	"GOSUMDB=off",
+	"GOTOOLCHAIN=local",
+}
+
+// offlineKey is the context key for the network policy of a request
+type offlineKey struct{}
+
+// withOffline returns a context whose commands run offline when offline is set
+func withOffline(ctx context.Context, offline bool) context.Context {
+	return context.WithValue(ctx, offlineKey{}, offline)
+}
+
+// offlineFromContext reports whether the commands of a request must run offline
+func offlineFromContext(ctx context.Context) bool {
+	offline, _ := ctx.Value(offlineKey{}).(bool)
+	return offline
+}
+
+// toolOffline reports whether a tool runs offline under the configuration:
+// network.tools overrides network.offline for the tools it names
+func toolOffline(tool string) bool {
+	cfg := getConfig().Network
+	if offline, ok := cfg.Tools[tool]; ok {
+		return offline
+	}
+	return cfg.Offline
+}
+
+// NetworkPolicyMiddleware runs the commands of a tool call offline when the
+// configuration asks for it for the tool, or when the request sets offline.
+// A request can take a tool offline but never bring it back online.
+func NetworkPolicyMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		offline := toolOffline(req.Params.Name) || mcp.ParseBoolean(req, "offline", false)
+		return next(withOffline(ctx, offline), req)
+	}
+}
+
+// prepareOffline scrubs the environment of cmd down to the configured
+// allowlist (plus the variables go_run requests may set) and adds the offline
+// settings. When the sandbox can, the command is also cut off the network;
+// the namespace sandbox always is.
+func prepareOffline(cmd *exec.Cmd, sandbox Sandbox) {
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	cfg := getConfig()
+	allowlist := append(append([]string(nil), cfg.Network.EnvAllowlist...), cfg.Run.EnvAllowlist...)
+
+	scrubbed := make([]string, 0, len(env)+len(offlineEnv))
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		if envAllowed(name, allowlist) {
+			scrubbed = append(scrubbed, kv)
+		}
+	}
+	cmd.Env = append(append(scrubbed, offlineEnv...), "GOFLAGS="+offlineGoFlags(cmd.Dir, scrubbed))
+
+	if isolator, ok := sandbox.(networkIsolator); ok {
+		if err := isolator.isolateNetwork(cmd); err != nil {
+			log.Printf("Warning: %s sandbox cannot remove network access, relying on the offline environment: %v", sandbox.Name(), err)
+		}
+	}
+}
+
+// offlineGoFlags returns the GOFLAGS of an offline command run in dir, which
+// replace any the server sets, such as -modcacherw. A plain module gets
+// -mod=mod so that missing go.sum entries are filled in from the module cache.
+// Workspaces only accept -mod=readonly or -mod=vendor and vendored modules
+// must keep building from vendor/, so both keep the go command's default.
+func offlineGoFlags(dir string, env []string) string {
+	gowork := ""
+	for _, kv := range env {
+		if value, ok := strings.CutPrefix(kv, "GOWORK="); ok {
+			gowork = value
+		}
+	}
+	if gowork != "" && gowork != "off" {
+		return ""
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "-mod=mod"
+	}
+	module := false
+	for d := abs; ; d = filepath.Dir(d) {
+		if gowork == "" && fileExists(filepath.Join(d, "go.work")) {
+			return ""
+		}
+		if !module && fileExists(filepath.Join(d, "go.mod")) {
+			if info, err := os.Stat(filepath.Join(d, "vendor")); err == nil && info.IsDir() {
+				return ""
+			}
+			module = true
+		}
+		if parent := filepath.Dir(d); parent == d {
+			break
+		}
+	}
+	return "-mod=mod"
+}
+
+// networkIsolator is implemented by sandboxes that can remove network access
+// from a command
+type networkIsolator interface {
+	isolateNetwork(cmd *exec.Cmd) error
+}
+
+func (processSandbox) isolateNetwork(cmd *exec.Cmd) error {
+	return isolateNetwork(cmd)
+}
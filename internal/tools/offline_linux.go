@@ -0,0 +1,71 @@
+//go:build linux
+
+package tools
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// isolateNetwork starts cmd in new user and network namespaces. The network
+// namespace has no interfaces besides a down loopback; the user namespace maps
+// the server's own user, so file access is unchanged apart from the module
+// cache, which the sandbox helper binds read-only in a new mount namespace
+// before running the command.
+func isolateNetwork(cmd *exec.Cmd) error {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	if cmd.SysProcAttr.Cloneflags&syscall.CLONE_NEWNET != 0 {
+		return nil
+	}
+	if max, err := os.ReadFile("/proc/sys/user/max_user_namespaces"); err == nil && strings.TrimSpace(string(max)) == "0" {
+		return fmt.Errorf("user namespaces are disabled")
+	}
+
+	if cache := goModCache(); cache != "" {
+		executable, err := os.Executable()
+		if err == nil {
+			dir := cmd.Dir
+			if dir == "" {
+				dir, _ = os.Getwd()
+			}
+			return runInSandboxHelper(cmd, executable, sandboxSpec{Dir: dir, Path: cmd.Path, ReadOnly: []string{cache}})
+		}
+		log.Printf("Warning: module cache stays writable for offline commands: %v", err)
+	}
+
+	uid, gid := os.Getuid(), os.Getgid()
+	cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWUSER | syscall.CLONE_NEWNET
+	cmd.SysProcAttr.UidMappings = []syscall.SysProcIDMap{{ContainerID: uid, HostID: uid, Size: 1}}
+	cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{{ContainerID: gid, HostID: gid, Size: 1}}
+	cmd.SysProcAttr.GidMappingsEnableSetgroups = false
+	return nil
+}
+
+var (
+	goModCacheOnce sync.Once
+	goModCacheDir  string
+)
+
+// goModCache returns the module cache directory, or "" when it does not exist
+// yet and there is nothing to protect
+func goModCache() string {
+	goModCacheOnce.Do(func() {
+		output, err := exec.Command("go", "env", "GOMODCACHE").Output()
+		if err != nil {
+			log.Printf("Warning: failed to locate Go module cache: %v", err)
+			return
+		}
+		dir := strings.TrimSpace(string(output))
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			goModCacheDir = dir
+		}
+	})
+	return goModCacheDir
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package tools
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// isolateNetwork fails outside Linux, where network namespaces are unavailable
+func isolateNetwork(cmd *exec.Cmd) error {
+	return fmt.Errorf("network isolation is only supported on Linux")
+}
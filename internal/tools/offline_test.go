@@ -0,0 +1,148 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MrFixit96/go-dev-mcp/internal/config"
+)
+
+func TestOfflineEnvironment(t *testing.T) {
+	SetConfig(config.DefaultConfig())
+	t.Setenv("SECRET_TOKEN", "hunter2")
+	t.Setenv("GOFLAGS", "-modcacherw")
+
+	code := "package main\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n\nfunc main() {\n\tfor _, name := range []string{\"SECRET_TOKEN\", \"GOPROXY\", \"GOFLAGS\", \"APP_MODE\"} {\n\t\tfmt.Printf(\"%s=%s\\n\", name, os.Getenv(name))\n\t}\n}\n"
+	req := newToolRequest("go_run", map[string]interface{}{
+		"code": code,
+		"env":  map[string]interface{}{"APP_MODE": "test"},
+	})
+
+	result, err := ExecuteGoRunTool(withOffline(context.Background(), true), req)
+	if err != nil || result.IsError {
+		t.Fatalf("Offline go_run failed: %v %s", err, resultText(result))
+	}
+	output := resultText(result)
+	for _, want := range []string{`SECRET_TOKEN=\n`, `GOPROXY=off\n`, `GOFLAGS=-mod=mod\n`, `APP_MODE=test\n`} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %s in the offline output, got %s", want, output)
+		}
+	}
+
+	result, err = ExecuteGoRunTool(context.Background(), req)
+	if err != nil || !strings.Contains(resultText(result), `SECRET_TOKEN=hunter2`) {
+		t.Errorf("Expected the server environment online, got %v %s", err, resultText(result))
+	}
+}
+
+func TestOfflineRemovesNetwork(t *testing.T) {
+	SetConfig(config.DefaultConfig())
+	probe := exec.Command("true")
+	if err := isolateNetwork(probe); err != nil {
+		t.Skip("network isolation unavailable:", err)
+	}
+	if err := probe.Run(); err != nil {
+		t.Skip("network isolation unavailable:", err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	code := fmt.Sprintf("package main\n\nimport (\n\t\"fmt\"\n\t\"net\"\n)\n\nfunc main() {\n\t_, err := net.Dial(\"tcp\", %q)\n\tfmt.Println(\"dial error:\", err != nil)\n}\n", listener.Addr().String())
+	req := newToolRequest("go_run", map[string]interface{}{"code": code})
+
+	result, err := ExecuteGoRunTool(withOffline(context.Background(), true), req)
+	if err != nil || !strings.Contains(resultText(result), "dial error: true") {
+		t.Errorf("Expected the offline program to have no network, got %v %s", err, resultText(result))
+	}
+	result, err = ExecuteGoRunTool(context.Background(), req)
+	if err != nil || !strings.Contains(resultText(result), "dial error: false") {
+		t.Errorf("Expected the program to reach the listener online, got %v %s", err, resultText(result))
+	}
+}
+
+func TestToolOffline(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Network.Offline = true
+	cfg.Network.Tools = map[string]bool{"go_mod": false}
+	SetConfig(cfg)
+	defer SetConfig(config.DefaultConfig())
+
+	if !toolOffline("go_run") || toolOffline("go_mod") {
+		t.Errorf("Expected go_run offline and go_mod online, got %v and %v", toolOffline("go_run"), toolOffline("go_mod"))
+	}
+}
+
+func TestOfflineGoFlags(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"plain/go.mod":                "module example.com/plain\n\ngo 1.21\n",
+		"vendored/go.mod":             "module example.com/vendored\n\ngo 1.21\n",
+		"vendored/vendor/modules.txt": "",
+		"work/go.work":                "go 1.21\n\nuse ./mod\n",
+		"work/mod/go.mod":             "module example.com/mod\n\ngo 1.21\n",
+	})
+
+	tests := []struct {
+		dir  string
+		env  []string
+		want string
+	}{
+		{"plain", nil, "-mod=mod"},
+		{"vendored", nil, ""},
+		{"work/mod", nil, ""},
+		{"work/mod", []string{"GOWORK=off"}, "-mod=mod"},
+		{"plain", []string{"GOWORK=" + filepath.Join(dir, "work", "go.work")}, ""},
+	}
+	for _, tt := range tests {
+		if got := offlineGoFlags(filepath.Join(dir, tt.dir), tt.env); got != tt.want {
+			t.Errorf("offlineGoFlags(%s, %v) = %q, want %q", tt.dir, tt.env, got, tt.want)
+		}
+	}
+}
+
+func TestOfflineWorkspaceAndModuleCache(t *testing.T) {
+	SetConfig(config.DefaultConfig())
+	t.Setenv("GOFLAGS", "")
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"go.work":     "go 1.21\n\nuse ./app\n",
+		"app/go.mod":  "module example.com/app\n\ngo 1.21\n",
+		"app/main.go": "package main\n\nimport (\n\t\"fmt\"\n\t\"os\"\n\t\"path/filepath\"\n)\n\nfunc main() {\n\terr := os.WriteFile(filepath.Join(os.Args[1], \"go-dev-mcp-probe\"), nil, 0644)\n\tfmt.Println(\"write error:\", err != nil)\n}\n",
+	})
+
+	cache := goModCache()
+	if cache == "" {
+		cache = t.TempDir()
+	}
+	req := newToolRequest("go_run", map[string]interface{}{"workspace_path": dir, "args": []interface{}{cache}})
+	result, err := ExecuteGoRunTool(withOffline(context.Background(), true), req)
+	if err != nil || result.IsError {
+		t.Fatalf("Offline go_run in a workspace failed: %v %s", err, resultText(result))
+	}
+
+	probe := exec.Command("true")
+	if goModCache() == "" || isolateNetwork(probe) != nil || probe.Run() != nil {
+		return
+	}
+	if !strings.Contains(resultText(result), "write error: true") {
+		t.Errorf("Expected the module cache to be read-only offline, got %s", resultText(result))
+	}
+}
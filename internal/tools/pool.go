@@ -0,0 +1,223 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ServerBusyError reports that a command was refused because the queue of
+// commands waiting for a worker is full
+type ServerBusyError struct {
+	Queued   int // Commands waiting when the command was refused
+	MaxQueue int // Configured queue limit
+}
+
+// Error implements the error interface
+func (e *ServerBusyError) Error() string {
+	return fmt.Sprintf("server busy: %d commands are already waiting for a worker (limit %d)", e.Queued, e.MaxQueue)
+}
+
+// poolWaiter is a command queued for a worker
+type poolWaiter struct {
+	granted chan struct{}
+	since   time.Time
+}
+
+// workerPool bounds the number of go commands running at once. Commands that
+// find every worker busy wait in a queue per client, and freed workers go to
+// the clients in turn so a burst from one client cannot starve the others.
+type workerPool struct {
+	mu      sync.Mutex
+	running int
+	queues  map[string][]*poolWaiter
+	order   []string // Clients with queued commands, next to be served first
+
+	// Counters reported by go_server_status
+	completed int64
+	rejected  int64
+	peakQueue int
+	totalWait time.Duration
+	waited    int64
+}
+
+// newWorkerPool creates an idle worker pool
+func newWorkerPool() *workerPool {
+	return &workerPool{queues: make(map[string][]*poolWaiter)}
+}
+
+// workers is the pool every command started by the tools runs in
+var workers = newWorkerPool()
+
+// size returns the configured number of workers: concurrency.workers, or
+// resourceLimits.cpuLimit when it is not set, or the number of CPUs
+func (p *workerPool) size() int {
+	cfg := getConfig()
+	switch {
+	case cfg.Concurrency.Workers > 0:
+		return cfg.Concurrency.Workers
+	case cfg.ResourceLimits.CPULimit > 0:
+		return cfg.ResourceLimits.CPULimit
+	}
+	return runtime.NumCPU()
+}
+
+// queued returns the number of waiting commands. The caller must hold p.mu.
+func (p *workerPool) queued() int {
+	n := 0
+	for _, queue := range p.queues {
+		n += len(queue)
+	}
+	return n
+}
+
+// acquire waits for a worker for a command of client and returns the function
+// that frees it along with the time spent in the queue. Commands are refused
+// with ServerBusyError when concurrency.maxQueue commands are already waiting.
+func (p *workerPool) acquire(ctx context.Context, client string) (func(), time.Duration, error) {
+	p.mu.Lock()
+	// Workers added by a configuration change go to the queued commands first
+	p.dispatch()
+	queued := p.queued()
+	if queued == 0 && p.running < p.size() {
+		p.running++
+		p.mu.Unlock()
+		return p.release, 0, nil
+	}
+	if maxQueue := getConfig().Concurrency.MaxQueue; maxQueue > 0 && queued >= maxQueue {
+		p.rejected++
+		p.mu.Unlock()
+		return nil, 0, &ServerBusyError{Queued: queued, MaxQueue: maxQueue}
+	}
+
+	waiter := &poolWaiter{granted: make(chan struct{}), since: time.Now()}
+	if len(p.queues[client]) == 0 {
+		p.order = append(p.order, client)
+	}
+	p.queues[client] = append(p.queues[client], waiter)
+	if queued+1 > p.peakQueue {
+		p.peakQueue = queued + 1
+	}
+	p.mu.Unlock()
+	reportPhase(ctx, fmt.Sprintf("waiting for a worker (%d queued)", queued+1))
+
+	select {
+	case <-waiter.granted:
+		return p.release, time.Since(waiter.since), nil
+	case <-ctx.Done():
+	}
+
+	p.mu.Lock()
+	select {
+	case <-waiter.granted:
+		// A worker was handed over while giving up; pass it on without
+		// counting a completed command
+		p.running--
+		p.dispatch()
+		p.mu.Unlock()
+		return nil, time.Since(waiter.since), ctx.Err()
+	default:
+	}
+	queue := p.queues[client]
+	for i, w := range queue {
+		if w == waiter {
+			p.queues[client] = append(queue[:i], queue[i+1:]...)
+			break
+		}
+	}
+	if len(p.queues[client]) == 0 {
+		p.dropClient(client)
+	}
+	p.mu.Unlock()
+	return nil, time.Since(waiter.since), ctx.Err()
+}
+
+// release frees a worker and hands it to the next client in turn
+func (p *workerPool) release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.running--
+	p.completed++
+	p.dispatch()
+}
+
+// dispatch hands free workers to the queued commands, one client at a time.
+// The caller must hold p.mu.
+func (p *workerPool) dispatch() {
+	for p.running < p.size() && len(p.order) > 0 {
+		client := p.order[0]
+		waiter := p.queues[client][0]
+		p.queues[client] = p.queues[client][1:]
+		p.order = p.order[1:]
+		if len(p.queues[client]) > 0 {
+			p.order = append(p.order, client)
+		} else {
+			delete(p.queues, client)
+		}
+		p.running++
+		p.totalWait += time.Since(waiter.since)
+		p.waited++
+		close(waiter.granted)
+	}
+}
+
+// dropClient removes a client without queued commands. The caller must hold p.mu.
+func (p *workerPool) dropClient(client string) {
+	delete(p.queues, client)
+	for i, c := range p.order {
+		if c == client {
+			p.order = append(p.order[:i], p.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// stats returns the current state and counters of the pool
+func (p *workerPool) stats() map[string]interface{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	byClient := make(map[string]int, len(p.queues))
+	for client, queue := range p.queues {
+		byClient[client] = len(queue)
+	}
+	averageWait := time.Duration(0)
+	if p.waited > 0 {
+		averageWait = p.totalWait / time.Duration(p.waited)
+	}
+	return map[string]interface{}{
+		"workers":          p.size(),
+		"running":          p.running,
+		"queued":           p.queued(),
+		"queuedByClient":   byClient,
+		"maxQueue":         getConfig().Concurrency.MaxQueue,
+		"peakQueued":       p.peakQueue,
+		"completed":        p.completed,
+		"rejected":         p.rejected,
+		"averageQueueWait": averageWait.Round(time.Millisecond).String(),
+	}
+}
+
+// clientFromContext identifies the client of the current request for fair
+// queuing: its MCP session, or "" outside of a session
+func clientFromContext(ctx context.Context) string {
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		return session.SessionID()
+	}
+	return ""
+}
+
+// ExecuteGoServerStatusTool handles the go_server_status tool execution
+func ExecuteGoServerStatusTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	response := map[string]interface{}{
+		"success":     true,
+		"workerPool":  workers.stats(),
+		"runningJobs": jobs.Running(),
+		"sessions":    len(sessions.List()),
+	}
+	return jsonResult(response)
+}
@@ -0,0 +1,168 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MrFixit96/go-dev-mcp/internal/config"
+)
+
+// queueCommand queues a command of client in p, which sends client on granted
+// once it gets a worker and frees the worker shortly after
+func queueCommand(t *testing.T, p *workerPool, client string, granted chan<- string) {
+	t.Helper()
+	before := p.stats()["queued"].(int)
+	go func() {
+		release, _, err := p.acquire(context.Background(), client)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		granted <- client
+		<-time.After(10 * time.Millisecond)
+		release()
+	}()
+	for p.stats()["queued"].(int) == before {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestWorkerPoolFairQueuing(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Concurrency.Workers = 1
+	cfg.Concurrency.MaxQueue = 4
+	SetConfig(cfg)
+	defer SetConfig(config.DefaultConfig())
+
+	p := newWorkerPool()
+	release, wait, err := p.acquire(context.Background(), "a")
+	if err != nil || wait != 0 {
+		t.Fatalf("Expected a free worker, got %v after %v", err, wait)
+	}
+
+	// A burst from one client does not hold back the other
+	granted := make(chan string, 4)
+	for _, client := range []string{"a", "a", "a", "b"} {
+		queueCommand(t, p, client, granted)
+	}
+	if _, _, err := p.acquire(context.Background(), "c"); !errors.As(err, new(*ServerBusyError)) {
+		t.Errorf("Expected a server busy error with a full queue, got %v", err)
+	}
+	if stats := p.stats(); stats["queued"] != 4 || stats["queuedByClient"].(map[string]int)["a"] != 3 {
+		t.Errorf("Unexpected queue metrics %v", stats)
+	}
+
+	release()
+	var order []string
+	for i := 0; i < 4; i++ {
+		order = append(order, <-granted)
+	}
+	if got := strings.Join(order, ""); got != "abaa" {
+		t.Errorf("Workers were handed out in order %s, want abaa", got)
+	}
+
+	for p.stats()["running"].(int) != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	stats := p.stats()
+	if stats["completed"].(int64) != 5 || stats["rejected"].(int64) != 1 || stats["peakQueued"].(int) != 4 {
+		t.Errorf("Unexpected pool counters %v", stats)
+	}
+}
+
+func TestWorkerPoolCancelledWait(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Concurrency.Workers = 1
+	SetConfig(cfg)
+	defer SetConfig(config.DefaultConfig())
+
+	p := newWorkerPool()
+	release, _, err := p.acquire(context.Background(), "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, _, err := p.acquire(ctx, "b"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the wait to end with the context, got %v", err)
+	}
+	if queued := p.stats()["queued"].(int); queued != 0 {
+		t.Errorf("Expected the abandoned command to leave the queue, got %d queued", queued)
+	}
+}
+
+func TestWorkerPoolCancelledHandoff(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Concurrency.Workers = 1
+	SetConfig(cfg)
+	defer SetConfig(config.DefaultConfig())
+
+	p := newWorkerPool()
+	if _, _, err := p.acquire(context.Background(), "a"); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, _, err := p.acquire(ctx, "b")
+		done <- err
+	}()
+	for p.stats()["queued"].(int) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The worker is freed while the waiter is giving up, as release does
+	p.mu.Lock()
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	p.running--
+	p.completed++
+	p.dispatch()
+	p.mu.Unlock()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the wait to be cancelled, got %v", err)
+	}
+
+	// The worker is passed on without counting the abandoned command
+	if stats := p.stats(); stats["running"] != 0 || stats["completed"].(int64) != 1 {
+		t.Errorf("Unexpected pool counters %v", stats)
+	}
+}
+
+func TestWorkerPoolRaisedWorkers(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Concurrency.Workers = 1
+	SetConfig(cfg)
+	defer SetConfig(config.DefaultConfig())
+
+	p := newWorkerPool()
+	release, _, err := p.acquire(context.Background(), "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	granted := make(chan string, 1)
+	queueCommand(t, p, "b", granted)
+
+	// Raising the worker count starts the queued command with the next one
+	raised := config.DefaultConfig()
+	raised.Concurrency.Workers = 3
+	SetConfig(raised)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	releaseC, wait, err := p.acquire(ctx, "c")
+	if err != nil || wait != 0 {
+		t.Fatalf("Expected a free worker, got %v after %v", err, wait)
+	}
+	releaseC()
+	select {
+	case <-granted:
+	case <-time.After(time.Second):
+		t.Error("Expected the queued command to get one of the new workers")
+	}
+}
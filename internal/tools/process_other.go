@@ -0,0 +1,9 @@
+//go:build !unix
+
+package tools
+
+import "os/exec"
+
+// killProcessGroupOnCancel keeps the default cancellation outside Unix, which
+// kills only the go command itself
+func killProcessGroupOnCancel(cmd *exec.Cmd) {}
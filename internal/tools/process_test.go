@@ -0,0 +1,72 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MrFixit96/go-dev-mcp/internal/config"
+)
+
+// signalWriter closes ready once the output written to it contains want
+type signalWriter struct {
+	want  string
+	ready chan struct{}
+
+	mu     sync.Mutex
+	output strings.Builder
+	once   sync.Once
+}
+
+func (w *signalWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.output.Write(p)
+	if strings.Contains(w.output.String(), w.want) {
+		w.once.Do(func() { close(w.ready) })
+	}
+	return len(p), nil
+}
+
+func TestCancelStopsRunningProgram(t *testing.T) {
+	SetConfig(config.DefaultConfig())
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	started := &signalWriter{want: "started", ready: make(chan struct{})}
+	ctx, cancel := context.WithCancel(withOutputWriter(context.Background(), started))
+	defer cancel()
+	go func() {
+		select {
+		case <-started.ready:
+			cancel()
+		case <-time.After(time.Minute):
+		}
+	}()
+
+	begin := time.Now()
+	result, err := ExecuteGoRunTool(ctx, newToolRequest("go_run", map[string]interface{}{
+		"code": "package main\n\nimport (\n\t\"fmt\"\n\t\"time\"\n)\n\nfunc main() {\n\tfmt.Println(\"started\")\n\ttime.Sleep(time.Hour)\n}\n",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(begin); elapsed > 30*time.Second {
+		t.Errorf("Cancelled run took %v to return", elapsed)
+	}
+	if !result.IsError || !strings.Contains(resultText(result), `"cancelled":true`) {
+		t.Errorf("Expected a cancelled result, got %s", resultText(result))
+	}
+
+	// The temporary module and the go command's work directory are gone
+	entries, err := os.ReadDir(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		t.Errorf("Temporary file left behind: %s", entry.Name())
+	}
+}
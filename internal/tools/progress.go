@@ -0,0 +1,156 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// progressNotifier sends notifications to the client that made the current request
+type progressNotifier interface {
+	SendNotificationToClient(ctx context.Context, method string, params map[string]any) error
+}
+
+// progressReporter streams the phases and output of a tool call to a client
+// that supplied a progress token. Phases such as "go mod init temp" or
+// "go test ./pkg" are sent as notifications/progress; output lines are sent as
+// notifications/message log entries.
+type progressReporter struct {
+	notifier progressNotifier
+	token    mcp.ProgressToken
+	tool     string
+
+	mu       sync.Mutex
+	progress int
+}
+
+// progressKey is the context key for the progress reporter of a request
+type progressKey struct{}
+
+// ProgressMiddleware streams command phases and output for tool calls whose
+// request carries a progress token
+func ProgressMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if req.Params.Meta != nil && req.Params.Meta.ProgressToken != nil {
+			if srv := server.ServerFromContext(ctx); srv != nil {
+				ctx = withProgress(ctx, srv, req.Params.Meta.ProgressToken, req.Params.Name)
+			}
+		}
+		return next(ctx, req)
+	}
+}
+
+// withProgress returns a context whose commands report their progress to the client
+func withProgress(ctx context.Context, notifier progressNotifier, token mcp.ProgressToken, tool string) context.Context {
+	return context.WithValue(ctx, progressKey{}, &progressReporter{notifier: notifier, token: token, tool: tool})
+}
+
+// withoutProgress returns a context that reports no progress, for work that
+// outlives the request the progress token belongs to
+func withoutProgress(ctx context.Context) context.Context {
+	return context.WithValue(ctx, progressKey{}, (*progressReporter)(nil))
+}
+
+// progressFromContext returns the progress reporter stored in ctx, if any
+func progressFromContext(ctx context.Context) *progressReporter {
+	p, _ := ctx.Value(progressKey{}).(*progressReporter)
+	return p
+}
+
+// reportPhase tells the client that the request entered a new phase. It does
+// nothing when the client did not ask for progress.
+func reportPhase(ctx context.Context, phase string) {
+	p := progressFromContext(ctx)
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.progress++
+	progress := p.progress
+	p.mu.Unlock()
+
+	p.send(ctx, "notifications/progress", map[string]any{
+		"progressToken": p.token,
+		"progress":      progress,
+		"message":       phase,
+	})
+}
+
+// lines returns a writer that sends each line written to it as a log
+// notification for stream (stdout or stderr). Call flush once the command has
+// finished to send a final line without a trailing newline.
+func (p *progressReporter) lines(ctx context.Context, stream string) *lineWriter {
+	return &lineWriter{emit: func(line string) {
+		p.send(ctx, "notifications/message", map[string]any{
+			"level":  mcp.LoggingLevelInfo,
+			"logger": p.tool,
+			"data": map[string]any{
+				"progressToken": p.token,
+				"stream":        stream,
+				"line":          line,
+			},
+		})
+	}}
+}
+
+// send delivers a notification, logging failures rather than failing the tool call
+func (p *progressReporter) send(ctx context.Context, method string, params map[string]any) {
+	if err := p.notifier.SendNotificationToClient(ctx, method, params); err != nil {
+		log.Printf("Failed to send %s: %v", method, err)
+	}
+}
+
+// lineWriter splits the output written to it into lines. Lines of go test
+// -json output are reduced to the test output they carry.
+type lineWriter struct {
+	emit func(line string)
+
+	mu      sync.Mutex
+	partial []byte
+}
+
+// Write emits every complete line in p and keeps the rest for the next write
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		w.emitLine(string(w.partial[:i]))
+		w.partial = w.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// flush emits the final line if it was not terminated by a newline
+func (w *lineWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.partial) > 0 {
+		w.emitLine(string(w.partial))
+		w.partial = nil
+	}
+}
+
+// emitLine sends a line, unwrapping go test -json events to their output
+func (w *lineWriter) emitLine(line string) {
+	if strings.HasPrefix(line, "{") {
+		var event TestEvent
+		if err := json.Unmarshal([]byte(line), &event); err == nil && event.Action != "" {
+			if event.Action != "output" && event.Action != "build-output" {
+				return
+			}
+			line = strings.TrimSuffix(event.Output, "\n")
+		}
+	}
+	w.emit(line)
+}
@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingNotifier records the notifications sent to a client
+type recordingNotifier struct {
+	mu       sync.Mutex
+	phases   []string
+	lines    []string
+	tokens   []interface{}
+	progress []int
+}
+
+func (n *recordingNotifier) SendNotificationToClient(ctx context.Context, method string, params map[string]any) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	switch method {
+	case "notifications/progress":
+		n.phases = append(n.phases, params["message"].(string))
+		n.tokens = append(n.tokens, params["progressToken"])
+		n.progress = append(n.progress, params["progress"].(int))
+	case "notifications/message":
+		data := params["data"].(map[string]any)
+		n.lines = append(n.lines, data["line"].(string))
+	}
+	return nil
+}
+
+func TestProgressNotifications(t *testing.T) {
+	notifier := &recordingNotifier{}
+	ctx := withProgress(context.Background(), notifier, "tok-1", "go_test")
+
+	result, err := ExecuteGoTestTool(ctx, newToolRequest("go_test", map[string]interface{}{
+		"code":     "package main\n\nfunc main() {}\n",
+		"testCode": "package main\n\nimport \"testing\"\n\nfunc TestLog(t *testing.T) { t.Log(\"live output\") }\n",
+		"verbose":  true,
+	}))
+	if err != nil || result.IsError {
+		t.Fatalf("go_test failed: %v %s", err, resultText(result))
+	}
+
+	phases := strings.Join(notifier.phases, "\n")
+	for _, want := range []string{"go mod init", "go test"} {
+		if !strings.Contains(phases, want) {
+			t.Errorf("Expected a %q phase, got:\n%s", want, phases)
+		}
+	}
+	for i, token := range notifier.tokens {
+		if token != "tok-1" || notifier.progress[i] != i+1 {
+			t.Errorf("Unexpected progress notification %d: token %v progress %d", i, token, notifier.progress[i])
+		}
+	}
+	if !strings.Contains(strings.Join(notifier.lines, "\n"), "live output") {
+		t.Errorf("Expected test output to be streamed, got:\n%s", strings.Join(notifier.lines, "\n"))
+	}
+	for _, line := range notifier.lines {
+		if strings.HasPrefix(line, "{") {
+			t.Errorf("Expected go test -json events to be unwrapped, got %s", line)
+		}
+	}
+}
+
+func TestLineWriter(t *testing.T) {
+	var lines []string
+	w := &lineWriter{emit: func(line string) { lines = append(lines, line) }}
+	w.Write([]byte("first\nsec"))
+	w.Write([]byte("ond\n{\"Action\":\"run\",\"Test\":\"TestX\"}\n"))
+	w.Write([]byte("{\"Action\":\"output\",\"Output\":\"=== RUN TestX\\n\"}\nlast"))
+	w.flush()
+
+	want := []string{"first", "second", "=== RUN TestX", "last"}
+	if strings.Join(lines, "|") != strings.Join(want, "|") {
+		t.Errorf("lines = %q, want %q", lines, want)
+	}
+}
@@ -0,0 +1,283 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// PathNotAllowedError reports a path argument outside the allowed roots
+type PathNotAllowedError struct {
+	Arg   string   // Argument that named the path
+	Path  string   // Canonical path that was rejected
+	Roots []string // Roots the path must be inside
+}
+
+// Error implements the error interface
+func (e *PathNotAllowedError) Error() string {
+	if len(e.Roots) == 0 {
+		return fmt.Sprintf("%s %s must stay inside the directory it is relative to", e.Arg, e.Path)
+	}
+	return fmt.Sprintf("%s %s is outside the allowed roots (%s)", e.Arg, e.Path, strings.Join(e.Roots, ", "))
+}
+
+// clientRootsWait bounds how long a tool call waits for the roots of a client
+// that declared the roots capability but has not listed them yet
+var clientRootsWait = 10 * time.Second
+
+// clientRoots holds the roots declared by each client session with roots/list,
+// and for the sessions still expected to list them a channel closed once they do
+var clientRoots = struct {
+	sync.Mutex
+	bySession map[string][]string
+	pending   map[string]chan struct{}
+}{bySession: make(map[string][]string), pending: make(map[string]chan struct{})}
+
+// ExpectClientRoots marks a session whose client declared the roots capability.
+// Its tool calls wait for SetClientRoots before their paths are checked.
+func ExpectClientRoots(session string) {
+	clientRoots.Lock()
+	defer clientRoots.Unlock()
+	if _, listed := clientRoots.bySession[session]; !listed && clientRoots.pending[session] == nil {
+		clientRoots.pending[session] = make(chan struct{})
+	}
+}
+
+// SetClientRoots records the roots a client session declared, as file:// URIs
+// or paths. Roots that are not local files are ignored; nil forgets the session.
+func SetClientRoots(session string, uris []string) {
+	var roots []string
+	for _, uri := range uris {
+		if path, ok := rootPath(uri); ok {
+			roots = append(roots, canonicalPath(path))
+		}
+	}
+
+	clientRoots.Lock()
+	defer clientRoots.Unlock()
+	if pending := clientRoots.pending[session]; pending != nil {
+		close(pending)
+		delete(clientRoots.pending, session)
+	}
+	if uris == nil {
+		delete(clientRoots.bySession, session)
+		return
+	}
+	clientRoots.bySession[session] = roots
+}
+
+// waitClientRoots waits until the client of the request has listed its roots,
+// if it is expected to, and fails when it does not within clientRootsWait
+func waitClientRoots(ctx context.Context) error {
+	if !getConfig().Roots.UseClientRoots {
+		return nil
+	}
+	clientRoots.Lock()
+	pending := clientRoots.pending[clientFromContext(ctx)]
+	clientRoots.Unlock()
+	if pending == nil {
+		return nil
+	}
+
+	timer := time.NewTimer(clientRootsWait)
+	defer timer.Stop()
+	select {
+	case <-pending:
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("the client declared the roots capability but has not answered roots/list; path arguments cannot be checked until it does")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// rootPath returns the local path of a root URI
+func rootPath(uri string) (string, bool) {
+	if !strings.Contains(uri, "://") {
+		return uri, uri != ""
+	}
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" || (u.Host != "" && u.Host != "localhost") {
+		return "", false
+	}
+	return filepath.FromSlash(u.Path), u.Path != ""
+}
+
+// allowedRoots returns the roots the paths of a request must be inside: the
+// configured roots and the roots declared by the client. An empty list means
+// that any path is allowed.
+func allowedRoots(ctx context.Context) []string {
+	cfg := getConfig().Roots
+	var roots []string
+	for _, root := range cfg.Allowed {
+		roots = append(roots, canonicalPath(root))
+	}
+	if cfg.UseClientRoots {
+		clientRoots.Lock()
+		roots = append(roots, clientRoots.bySession[clientFromContext(ctx)]...)
+		clientRoots.Unlock()
+	}
+	return roots
+}
+
+// canonicalPath returns path made absolute with symlinks resolved. Components
+// that do not exist yet, such as a build output, are kept as written below the
+// deepest existing directory. ".." is applied after the symlinks before it are
+// resolved, as the operating system does.
+func canonicalPath(path string) string {
+	if !filepath.IsAbs(path) {
+		wd, err := os.Getwd()
+		if err != nil {
+			return filepath.Clean(path)
+		}
+		path = wd + string(filepath.Separator) + path
+	}
+	rest := ""
+	for dir := path; ; {
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			return filepath.Join(resolved, rest)
+		}
+		i := strings.LastIndex(dir, string(filepath.Separator))
+		if i <= len(filepath.VolumeName(dir)) {
+			return filepath.Clean(path)
+		}
+		rest = filepath.Join(dir[i+1:], rest)
+		dir = dir[:i]
+	}
+}
+
+// pathWithin reports whether the canonical path is root or inside it
+func pathWithin(path, root string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// checkPathAllowed resolves path against base when it is relative and checks
+// that the result is inside one of roots. With no roots every path is allowed.
+func checkPathAllowed(arg, path, base string, roots []string) error {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(base, path)
+	}
+	canonical := canonicalPath(path)
+	if len(roots) == 0 {
+		return nil
+	}
+	for _, root := range roots {
+		if pathWithin(canonical, root) {
+			return nil
+		}
+	}
+	return &PathNotAllowedError{Arg: arg, Path: canonical, Roots: roots}
+}
+
+// checkRequestPaths checks every path argument of a tool call against the
+// allowed roots: project_path, workspace_path, working_dir, outputPath and the
+// go_workspace modules. Relative paths are taken relative to the project or
+// workspace. A relative outputPath for code input must stay inside the
+// temporary module even when no roots are configured.
+func checkRequestPaths(ctx context.Context, req mcp.CallToolRequest) error {
+	roots := allowedRoots(ctx)
+	projectPath := mcp.ParseString(req, "project_path", "")
+	workspacePath := mcp.ParseString(req, "workspace_path", "")
+
+	base := ""
+	for _, arg := range []struct{ name, path string }{
+		{"project_path", projectPath},
+		{"workspace_path", workspacePath},
+	} {
+		if arg.path == "" {
+			continue
+		}
+		if err := checkPathAllowed(arg.name, arg.path, "", roots); err != nil {
+			return err
+		}
+		if base == "" {
+			base = arg.path
+		}
+	}
+
+	if base == "" {
+		if id := mcp.ParseString(req, "session_id", ""); id != "" {
+			if session, err := sessions.Get(id); err == nil {
+				base = session.Dir
+				// Sessions are created by the server and always allowed
+				if len(roots) > 0 {
+					roots = append(roots, canonicalPath(session.Dir))
+				}
+			}
+		}
+	}
+
+	outputPath := mcp.ParseString(req, "outputPath", "")
+	if outputPath != "" && base == "" && !filepath.IsAbs(outputPath) && !filepath.IsLocal(outputPath) {
+		return &PathNotAllowedError{Arg: "outputPath", Path: outputPath}
+	}
+
+	paths := []struct{ name, path string }{
+		{"working_dir", mcp.ParseString(req, "working_dir", "")},
+		{"outputPath", outputPath},
+	}
+	if req.Params.Name != "go_session_create" {
+		// Selects a workspace module by its directory
+		paths = append(paths, struct{ name, path string }{"module", mcp.ParseString(req, "module", "")})
+	}
+	if req.Params.Name == "go_workspace" {
+		for _, module := range stringArrayArg(req, "modules") {
+			paths = append(paths, struct{ name, path string }{"modules", module})
+		}
+		for _, dir := range stringArrayArg(req, "add_use") {
+			paths = append(paths, struct{ name, path string }{"add_use", dir})
+		}
+		for _, dir := range localReplacements(req) {
+			paths = append(paths, struct{ name, path string }{"add_replace", dir})
+		}
+	}
+	for _, arg := range paths {
+		if arg.path == "" || (base == "" && !filepath.IsAbs(arg.path)) {
+			// Relative to a temporary directory
+			continue
+		}
+		if err := checkPathAllowed(arg.name, arg.path, base, roots); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// localReplacements returns the directories that add_replace entries of a
+// go_workspace call point to; replacements by a module version are left out
+func localReplacements(req mcp.CallToolRequest) []string {
+	var dirs []string
+	list, _ := req.GetArguments()["add_replace"].([]interface{})
+	for _, item := range list {
+		replace, _ := item.(map[string]interface{})
+		dir, _ := replace["new"].(string)
+		if filepath.IsAbs(dir) || dir == "." || dir == ".." || strings.HasPrefix(dir, "./") || strings.HasPrefix(dir, "../") {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// PathPolicyMiddleware rejects tool calls whose path arguments are outside the
+// configured roots and the roots declared by the client. Calls from a client
+// that declared the roots capability first wait for its roots.
+func PathPolicyMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if err := waitClientRoots(ctx); err != nil {
+			return executionErrorResult(err, "Client roots unavailable"), nil
+		}
+		if err := checkRequestPaths(ctx, req); err != nil {
+			return executionErrorResult(err, "Invalid path"), nil
+		}
+		return next(ctx, req)
+	}
+}
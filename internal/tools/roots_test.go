@@ -0,0 +1,161 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MrFixit96/go-dev-mcp/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestCanonicalPath(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "real", "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "real", "sub"), filepath.Join(dir, "link")); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+
+	tests := map[string]string{
+		filepath.Join(dir, "link"):                dir + "/real/sub",
+		filepath.Join(dir, "link", "out", "bin"):  dir + "/real/sub/out/bin",
+		dir + "/link/../escaped":                  dir + "/real/escaped",
+		filepath.Join(dir, "missing", "..", "ok"): dir + "/ok",
+	}
+	for path, want := range tests {
+		if got := canonicalPath(path); got != filepath.FromSlash(want) {
+			t.Errorf("canonicalPath(%s) = %s, want %s", path, got, want)
+		}
+	}
+}
+
+func TestCheckRequestPaths(t *testing.T) {
+	base, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	allowed := filepath.Join(base, "allowed")
+	outside := filepath.Join(base, "outside")
+	for _, dir := range []string{allowed, outside} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(outside, filepath.Join(allowed, "escape")); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Roots.Allowed = []string{allowed}
+	SetConfig(cfg)
+	defer SetConfig(config.DefaultConfig())
+
+	tests := []struct {
+		name    string
+		tool    string
+		args    map[string]interface{}
+		wantArg string // Argument expected to be rejected, or "" if allowed
+	}{
+		{"project inside", "go_build", map[string]interface{}{"project_path": allowed}, ""},
+		{"project outside", "go_build", map[string]interface{}{"project_path": outside}, "project_path"},
+		{"symlink out of root", "go_build", map[string]interface{}{"project_path": filepath.Join(allowed, "escape")}, "project_path"},
+		{"relative output", "go_build", map[string]interface{}{"project_path": allowed, "outputPath": "bin/app"}, ""},
+		{"output escapes project", "go_build", map[string]interface{}{"project_path": allowed, "outputPath": "../outside/app"}, "outputPath"},
+		{"absolute output for code", "go_build", map[string]interface{}{"code": "package main", "outputPath": filepath.Join(outside, "app")}, "outputPath"},
+		{"relative output for code", "go_build", map[string]interface{}{"code": "package main", "outputPath": "../../app"}, "outputPath"},
+		{"workspace module outside", "go_workspace", map[string]interface{}{"workspace_path": allowed, "modules": []interface{}{"../outside"}}, "modules"},
+		{"workspace use outside", "go_workspace", map[string]interface{}{"workspace_path": allowed, "command": "edit", "add_use": []interface{}{"./a", outside}}, "add_use"},
+		{"workspace use inside", "go_workspace", map[string]interface{}{"workspace_path": allowed, "command": "edit", "add_use": []interface{}{"./a"}}, ""},
+		{"workspace replace outside", "go_workspace", map[string]interface{}{"workspace_path": allowed, "command": "edit", "add_replace": []interface{}{map[string]interface{}{"old": "example.com/x", "new": "../outside/x"}}}, "add_replace"},
+		{"workspace replace by version", "go_workspace", map[string]interface{}{"workspace_path": allowed, "command": "edit", "add_replace": []interface{}{map[string]interface{}{"old": "example.com/x", "new": "example.com/y", "new_version": "v1.0.0"}}}, ""},
+		{"absolute module outside", "go_run", map[string]interface{}{"workspace_path": allowed, "module": outside}, "module"},
+		{"relative module inside", "go_build", map[string]interface{}{"workspace_path": allowed, "module": "app"}, ""},
+		{"session module name", "go_session_create", map[string]interface{}{"module": "/scratch"}, ""},
+		{"working dir outside", "go_run", map[string]interface{}{"project_path": allowed, "working_dir": outside}, "working_dir"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkRequestPaths(context.Background(), newToolRequest(tt.tool, tt.args))
+			var pathErr *PathNotAllowedError
+			switch {
+			case tt.wantArg == "" && err != nil:
+				t.Errorf("Expected the paths to be allowed, got %v", err)
+			case tt.wantArg != "" && (!errors.As(err, &pathErr) || pathErr.Arg != tt.wantArg):
+				t.Errorf("Expected %s to be rejected, got %v", tt.wantArg, err)
+			}
+		})
+	}
+
+	// Rejections are reported as validation errors by every tool
+	result, err := PathPolicyMiddleware(ExecuteGoBuildTool)(context.Background(), newToolRequest("go_build", map[string]interface{}{"project_path": outside}))
+	if err != nil || !result.IsError || !strings.Contains(resultText(result), `"type":"validation"`) {
+		t.Errorf("Expected a validation error, got %v %s", err, resultText(result))
+	}
+}
+
+func TestClientRoots(t *testing.T) {
+	SetConfig(config.DefaultConfig())
+	allowed := t.TempDir()
+	outside := t.TempDir()
+
+	// Without any roots every path is allowed
+	req := newToolRequest("go_build", map[string]interface{}{"project_path": outside})
+	if err := checkRequestPaths(context.Background(), req); err != nil {
+		t.Fatalf("Expected no restriction without roots, got %v", err)
+	}
+
+	SetClientRoots("", []string{"file://" + filepath.ToSlash(allowed), "https://example.com/repo"})
+	defer SetClientRoots("", nil)
+	if err := checkRequestPaths(context.Background(), req); err == nil {
+		t.Error("Expected a path outside the client roots to be rejected")
+	}
+	req = newToolRequest("go_build", map[string]interface{}{"project_path": allowed})
+	if err := checkRequestPaths(context.Background(), req); err != nil {
+		t.Errorf("Expected a path inside the client roots to be allowed, got %v", err)
+	}
+}
+
+func TestClientRootsWait(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Roots.UseClientRoots = true
+	SetConfig(cfg)
+	defer SetConfig(nil)
+	defer func(wait time.Duration) { clientRootsWait = wait }(clientRootsWait)
+	clientRootsWait = 50 * time.Millisecond
+
+	allowed := t.TempDir()
+	called := false
+	handler := PathPolicyMiddleware(func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		called = true
+		return mcp.NewToolResultText("ok"), nil
+	})
+	req := newToolRequest("go_build", map[string]interface{}{"project_path": allowed})
+
+	// A client that never lists its roots gets an error instead of unchecked paths
+	ExpectClientRoots("")
+	defer SetClientRoots("", nil)
+	result, err := handler(context.Background(), req)
+	if err != nil || !result.IsError || called {
+		t.Fatalf("Expected the call to be rejected while roots are pending, got %v %s", err, resultText(result))
+	}
+
+	// The call waits for the roots and is then checked against them
+	clientRootsWait = 5 * time.Second
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		SetClientRoots("", []string{"file://" + filepath.ToSlash(allowed)})
+	}()
+	result, err = handler(context.Background(), req)
+	if err != nil || result.IsError || !called {
+		t.Errorf("Expected the call to run once the roots arrived, got %v %s", err, resultText(result))
+	}
+}
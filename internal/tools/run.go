@@ -3,8 +3,12 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"time"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/mark3labs/mcp-go/mcp"
 )
@@ -16,63 +20,77 @@ func ExecuteGoRunTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallTo
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
-	// Get args if provided
-	var cmdArgs []string
-	if argsObj, ok := req.GetArguments()["args"].(map[string]interface{}); ok {
-		// Handle args as an object (per the mcp.WithObject parameter)
-		for _, v := range argsObj {
-			if strArg, ok := v.(string); ok {
-				cmdArgs = append(cmdArgs, strArg)
-			}
-		}
-	} else if argsArray, ok := req.GetArguments()["args"].([]interface{}); ok {
-		// Handle args as an array (for backward compatibility)
-		for _, arg := range argsArray {
-			if strArg, ok := arg.(string); ok {
-				cmdArgs = append(cmdArgs, strArg)
-			}
-		}
+	ctx = withRequestLimits(ctx, req)
+	// Program arguments, stdin and environment
+	cmdArgs, err := runArgs(req)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
 	}
-	// Get timeout if provided using Parse method
-	timeoutSecs := mcp.ParseFloat64(req, "timeoutSecs", 30.0)
+	env, err := runEnv(req, getConfig().Run.EnvAllowlist)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	input.Env = envList(env)
+	input.Stdin = mcp.ParseString(req, "stdin", "")
+	workingDir := mcp.ParseString(req, "working_dir", "")
+	// The timeoutSecs argument is applied through the request's resource limits
 	module := mcp.ParseString(req, "module", "") // For workspace module selection
-
-	// Create execution context with timeout
-	execCtx, cancel := context.WithTimeout(ctx, time.Duration(timeoutSecs)*time.Second)
-	defer cancel()
+	pkg := mcp.ParseString(req, "package", "")
 
 	// Prepare run args
 	args := []string{"run"}
 
 	// Handle different source types
+	target := ""
 	switch input.Source {
-	case SourceCode:
-		args = append(args, input.MainFile)
+	case SourceCode, SourceHybrid:
+		switch {
+		case pkg != "":
+			target = pkg
+		default:
+			// Run a package rather than files: files are written (or overlaid onto
+			// the project) as a tree, and go run would take program arguments
+			// ending in .go for more source files
+			target, err = codePackage(input)
+		}
 	case SourceWorkspace:
-		// For workspace execution, handle module selection
+		// Look for main packages in the selected module, or in every workspace module
+		modules := input.WorkspaceModules
 		if module != "" {
-			// Run specific module in workspace
-			args = append(args, module)
-		} else {
-			// Default to current directory in workspace
-			args = append(args, ".")
+			modules = []string{module}
 		}
+		target, err = resolveMainPackage(input.WorkspacePath, modules, pkg)
 	default:
-		args = append(args, "./...")
+		target, err = resolveMainPackage(input.ProjectPath, nil, pkg)
+	}
+	if err != nil {
+		return mainPackageErrorResult(err), nil
+	}
+
+	// Run from a subdirectory of the project or workspace; local packages then
+	// need an absolute path
+	root := diagnosticRoot(input)
+	if workingDir != "" {
+		if input.Source == SourceCode {
+			return mcp.NewToolResultError("working_dir requires project_path or workspace_path"), nil
+		}
+		input.WorkingDir, err = resolveWorkingDir(root, workingDir)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if isLocalPackage(target) {
+			target = filepath.Join(root, filepath.FromSlash(target))
+		}
 	}
+	args = append(args, target)
 
 	// Add command-line arguments
 	args = append(args, cmdArgs...)
 	// Execute using appropriate strategy
 	strategy := GetExecutionStrategy(input, args...)
-	result, err := strategy.Execute(execCtx, input, args)
-	// Check for timeout
-	if execCtx.Err() == context.DeadlineExceeded {
-		return mcp.NewToolResultError(fmt.Sprintf("Program execution timed out after %.0f seconds", timeoutSecs)), nil
-	}
-
+	result, err := strategy.Execute(ctx, input, args)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Execution error: %v", err)), nil
+		return executionErrorResult(err, "Execution error"), nil
 	}
 
 	// Format response
@@ -90,6 +108,25 @@ func ExecuteGoRunTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallTo
 		"stderr":   result.Stderr,
 		"exitCode": result.ExitCode,
 		"duration": result.Duration.String(),
+		"sandbox":  result.Sandbox,
+		"package":  target,
+		"args":     cmdArgs,
+		"stdin":    input.Stdin,
+		"env":      env,
+	}
+	if input.WorkingDir != "" {
+		response["workingDir"] = input.WorkingDir
+	} else if root != "" {
+		response["workingDir"] = root
+	}
+
+	// Report compiler errors when the program failed to build
+	if !result.Successful {
+		if errorDetails := compilerDiagnostics(resultDiagnostics(result, diagnosticRoot(input))); len(errorDetails) > 0 {
+			response["message"] = "Program failed to compile"
+			response["errorDetails"] = errorDetails
+			response["packages"] = GroupDiagnostics(errorDetails)
+		}
 	}
 
 	// Add natural language metadata
@@ -101,3 +138,119 @@ func ExecuteGoRunTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallTo
 
 	return mcp.NewToolResultText(string(jsonBytes)), nil
 }
+
+// mainPackageErrorResult reports why no main package could be chosen, listing the
+// candidates so the caller can retry with the package argument
+func mainPackageErrorResult(err error) *mcp.CallToolResult {
+	var pkgErr *MainPackageError
+	if !errors.As(err, &pkgErr) {
+		return mcp.NewToolResultError(err.Error())
+	}
+
+	detail := ErrorDetail{Type: ErrorTypeValidation, Message: pkgErr.Message}
+	for _, candidate := range pkgErr.Candidates {
+		detail.AppendSuggestion(fmt.Sprintf("Set package to %s", candidate))
+	}
+	response := map[string]interface{}{
+		"success":      false,
+		"message":      pkgErr.Message,
+		"errorDetails": []ErrorDetail{detail},
+	}
+	if len(pkgErr.Candidates) > 0 {
+		response["candidates"] = pkgErr.Candidates
+	}
+	jsonBytes, err := json.MarshalIndent(response, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(pkgErr.Error())
+	}
+	return mcp.NewToolResultError(string(jsonBytes))
+}
+
+// runArgs returns the program arguments, which must be an array of strings
+func runArgs(req mcp.CallToolRequest) ([]string, error) {
+	args := []string{}
+	value, ok := req.GetArguments()["args"]
+	if !ok || value == nil {
+		return args, nil
+	}
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("args must be an array of strings")
+	}
+	for i, item := range list {
+		arg, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("args[%d] must be a string", i)
+		}
+		args = append(args, arg)
+	}
+	return args, nil
+}
+
+// runEnv returns the environment variables requested for the program. Every
+// name must match an entry of the allowlist, which may contain glob patterns.
+func runEnv(req mcp.CallToolRequest, allowlist []string) (map[string]string, error) {
+	env := map[string]string{}
+	value, ok := req.GetArguments()["env"]
+	if !ok || value == nil {
+		return env, nil
+	}
+	vars, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("env must be an object mapping variable names to values")
+	}
+	for name, v := range vars {
+		if name == "" || strings.ContainsAny(name, "=\x00") {
+			return nil, fmt.Errorf("invalid environment variable name %q", name)
+		}
+		if !envAllowed(name, allowlist) {
+			return nil, fmt.Errorf("environment variable %s is not allowed; add it to run.envAllowlist in the server configuration", name)
+		}
+		switch v := v.(type) {
+		case string:
+			env[name] = v
+		case float64, bool:
+			env[name] = fmt.Sprint(v)
+		default:
+			return nil, fmt.Errorf("environment variable %s must have a string value", name)
+		}
+	}
+	return env, nil
+}
+
+// envAllowed reports whether name matches an allowlist entry
+func envAllowed(name string, allowlist []string) bool {
+	for _, pattern := range allowlist {
+		if matched, err := path.Match(pattern, name); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// envList converts environment variables to KEY=VALUE form, sorted by name
+func envList(env map[string]string) []string {
+	list := make([]string, 0, len(env))
+	for name, value := range env {
+		list = append(list, name+"="+value)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// resolveWorkingDir resolves a working directory relative to root and checks that
+// it is an existing directory inside root
+func resolveWorkingDir(root, dir string) (string, error) {
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(root, dir)
+	}
+	dir = filepath.Clean(dir)
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("working_dir %s is outside %s", dir, root)
+	}
+	if !dirExists(dir) {
+		return "", fmt.Errorf("working_dir %s does not exist", dir)
+	}
+	return dir, nil
+}
@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const runInputsProgram = `package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+func main() {
+	in, _ := io.ReadAll(os.Stdin)
+	wd, _ := os.Getwd()
+	fmt.Printf("args=%s\n", strings.Join(os.Args[1:], ","))
+	fmt.Printf("stdin=%s\n", in)
+	fmt.Printf("env=%s\n", os.Getenv("APP_MODE"))
+	fmt.Printf("wd=%s\n", wd)
+}
+`
+
+func TestRunInputs(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"go.mod":          "module example.com/app\n\ngo 1.21\n",
+		"main.go":         runInputsProgram,
+		"testdata/in.txt": "data\n",
+	})
+
+	args := map[string]interface{}{
+		"project_path": root,
+		"args":         []interface{}{"c", "a", "b", "--flag"},
+		"stdin":        "hello",
+		"env":          map[string]interface{}{"APP_MODE": "test"},
+		"working_dir":  "testdata",
+	}
+	result, err := ExecuteGoRunTool(context.Background(), newToolRequest("go_run", args))
+	if err != nil || result.IsError {
+		t.Fatalf("go_run failed: %v %+v", err, result)
+	}
+	var response struct {
+		Stdout     string            `json:"stdout"`
+		Args       []string          `json:"args"`
+		Env        map[string]string `json:"env"`
+		WorkingDir string            `json:"workingDir"`
+	}
+	if err := json.Unmarshal([]byte(resultText(result)), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	wantDir, _ := filepath.EvalSymlinks(filepath.Join(root, "testdata"))
+	for _, want := range []string{"args=c,a,b,--flag", "stdin=hello", "env=test", "wd=" + wantDir} {
+		if !strings.Contains(response.Stdout, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, response.Stdout)
+		}
+	}
+	if strings.Join(response.Args, ",") != "c,a,b,--flag" || response.Env["APP_MODE"] != "test" || response.WorkingDir != filepath.Join(root, "testdata") {
+		t.Errorf("Run inputs not reported back: %+v", response)
+	}
+
+	// Variables outside the allowlist and directories outside the project are rejected
+	args["env"] = map[string]interface{}{"LD_PRELOAD": "/tmp/evil.so"}
+	if result, _ := ExecuteGoRunTool(context.Background(), newToolRequest("go_run", args)); !result.IsError {
+		t.Error("Expected an error for an environment variable outside the allowlist")
+	}
+	args["env"] = map[string]interface{}{}
+	args["working_dir"] = ".."
+	if result, _ := ExecuteGoRunTool(context.Background(), newToolRequest("go_run", args)); !result.IsError {
+		t.Error("Expected an error for a working directory outside the project")
+	}
+}
+
+func TestRunCodeArgs(t *testing.T) {
+	t.Setenv("GOFLAGS", "")
+	args := map[string]interface{}{
+		"code": runInputsProgram,
+		"args": []interface{}{"input.go", "--", "-v"},
+	}
+	result, err := ExecuteGoRunTool(context.Background(), newToolRequest("go_run", args))
+	if err != nil || result.IsError {
+		t.Fatalf("go_run failed: %v %s", err, resultText(result))
+	}
+	// Arguments ending in .go reach the program instead of being compiled
+	if text := resultText(result); !strings.Contains(text, "args=input.go,--,-v") {
+		t.Errorf("Expected the program to get its arguments unchanged, got %s", text)
+	}
+}
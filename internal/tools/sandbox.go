@@ -0,0 +1,91 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Sandbox types selectable with Config.SandboxType
+const (
+	// SandboxProcess runs commands as ordinary child processes under the configured resource limits
+	SandboxProcess = "process"
+	// SandboxNamespace runs commands in Linux user, mount and network namespaces with a
+	// read-only root file system, writable working directories and no network access
+	SandboxNamespace = "namespace"
+	// SandboxNone runs commands without isolation or memory and CPU limits
+	SandboxNone = "none"
+)
+
+// Sandbox isolates the go processes spawned by the execution strategies and tool helpers.
+// Every command goes through execute, which prepares it with the active sandbox.
+type Sandbox interface {
+	// Name returns the sandbox type reported in tool responses
+	Name() string
+	// Prepare adapts cmd to run inside the sandbox. Besides cmd.Dir, the command may
+	// only modify the writable directories. The returned cleanup function must be
+	// called once the command has finished.
+	Prepare(cmd *exec.Cmd, writable []string) (cleanup func(), err error)
+	// EnforcesLimits reports whether memory and CPU limits apply to sandboxed commands
+	EnforcesLimits() bool
+}
+
+// NewSandbox returns the sandbox for a Config.SandboxType value
+func NewSandbox(sandboxType string) (Sandbox, error) {
+	switch strings.ToLower(sandboxType) {
+	case "", SandboxProcess:
+		return processSandbox{}, nil
+	case SandboxNamespace:
+		return newNamespaceSandbox()
+	case SandboxNone:
+		return noSandbox{}, nil
+	default:
+		return nil, fmt.Errorf("unknown sandbox type: %s (expected process, namespace or none)", sandboxType)
+	}
+}
+
+// activeSandbox returns the sandbox selected in the server configuration
+func activeSandbox() (Sandbox, error) {
+	return NewSandbox(getConfig().SandboxType)
+}
+
+// processSandbox runs commands as plain child processes, relying on resource limits for containment
+type processSandbox struct{}
+
+func (processSandbox) Name() string { return SandboxProcess }
+
+func (processSandbox) Prepare(cmd *exec.Cmd, writable []string) (func(), error) {
+	_, cleanup, err := withScratchDir(cmd)
+	return cleanup, err
+}
+
+func (processSandbox) EnforcesLimits() bool { return true }
+
+// noSandbox runs commands with the server's full privileges and no memory or CPU limits
+type noSandbox struct{}
+
+func (noSandbox) Name() string { return SandboxNone }
+
+func (noSandbox) Prepare(cmd *exec.Cmd, writable []string) (func(), error) {
+	_, cleanup, err := withScratchDir(cmd)
+	return cleanup, err
+}
+
+func (noSandbox) EnforcesLimits() bool { return false }
+
+// withScratchDir points TMPDIR and GOTMPDIR of cmd at a new scratch directory,
+// which the returned cleanup function removes. Temporary files, including the
+// go command's work directory, are then removed even when the command is killed.
+func withScratchDir(cmd *exec.Cmd) (string, func(), error) {
+	scratch, err := os.MkdirTemp("", "go-sandbox-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create sandbox scratch directory: %v", err)
+	}
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	cmd.Env = append(env[:len(env):len(env)], "TMPDIR="+scratch, "GOTMPDIR="+scratch)
+	return scratch, func() { os.RemoveAll(scratch) }, nil
+}
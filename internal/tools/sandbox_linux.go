@@ -0,0 +1,308 @@
+//go:build linux
+
+package tools
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// sandboxHelperArg marks a re-execution of the server binary that sets up the
+// namespace sandbox before replacing itself with the sandboxed command
+const sandboxHelperArg = "__go-dev-mcp-sandbox"
+
+// sandboxSpec is passed from the server to the sandbox helper
+type sandboxSpec struct {
+	Dir      string   `json:"dir"`      // Working directory of the command
+	Path     string   `json:"path"`     // Executable to run once the sandbox is set up
+	Writable []string `json:"writable"` // Directories that stay writable
+	// ReadOnly, when set, are the only directories made read-only; the rest of
+	// the file system is left as it is and Writable is ignored
+	ReadOnly []string `json:"readOnly,omitempty"`
+}
+
+func init() {
+	if len(os.Args) > 2 && os.Args[1] == sandboxHelperArg {
+		runSandboxHelper(os.Args[2], os.Args[3:])
+	}
+}
+
+// namespaceSandbox runs commands in new user, mount and network namespaces.
+// Inside, every mount is read-only except the command's working directory, the
+// configured writable directories, a per-command scratch directory and the Go
+// build cache. The network namespace has no interfaces besides a down loopback.
+type namespaceSandbox struct {
+	executable string
+}
+
+// newNamespaceSandbox returns the namespace sandbox; it fails if the server
+// binary cannot be located for re-execution
+func newNamespaceSandbox() (Sandbox, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("namespace sandbox unavailable: %v", err)
+	}
+	return &namespaceSandbox{executable: executable}, nil
+}
+
+func (s *namespaceSandbox) Name() string { return SandboxNamespace }
+
+func (s *namespaceSandbox) EnforcesLimits() bool { return true }
+
+// Prepare rewrites cmd to start the sandbox helper in fresh namespaces
+func (s *namespaceSandbox) Prepare(cmd *exec.Cmd, writable []string) (func(), error) {
+	scratch, cleanup, err := withScratchDir(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := cmd.Dir
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	spec := sandboxSpec{
+		Dir:      dir,
+		Path:     cmd.Path,
+		Writable: append([]string{dir, scratch}, writable...),
+	}
+	env := cmd.Env
+	if cache := goBuildCache(); cache != "" {
+		spec.Writable = append(spec.Writable, cache)
+		env = append(env, "GOCACHE="+cache)
+	}
+
+	if err := runInSandboxHelper(cmd, s.executable, spec); err != nil {
+		cleanup()
+		return nil, err
+	}
+	cmd.Env = env
+	return cleanup, nil
+}
+
+// runInSandboxHelper rewrites cmd to start executable as the sandbox helper
+// with spec, in new user, mount and network namespaces that map the server's
+// own user
+func runInSandboxHelper(cmd *exec.Cmd, executable string, spec sandboxSpec) error {
+	encoded, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+	cmd.Path = executable
+	cmd.Args = append([]string{executable, sandboxHelperArg, string(encoded)}, cmd.Args...)
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	uid, gid := os.Getuid(), os.Getgid()
+	cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWNET
+	cmd.SysProcAttr.UidMappings = []syscall.SysProcIDMap{{ContainerID: uid, HostID: uid, Size: 1}}
+	cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{{ContainerID: gid, HostID: gid, Size: 1}}
+	cmd.SysProcAttr.GidMappingsEnableSetgroups = false
+	return nil
+}
+
+var (
+	goCacheOnce sync.Once
+	goCacheDir  string
+)
+
+// goBuildCache returns the Go build cache directory, creating it if needed, so
+// that sandboxed builds can keep reusing it
+func goBuildCache() string {
+	goCacheOnce.Do(func() {
+		output, err := exec.Command("go", "env", "GOCACHE").Output()
+		if err != nil {
+			log.Printf("Warning: failed to locate Go build cache: %v", err)
+			return
+		}
+		dir := strings.TrimSpace(string(output))
+		if dir == "" || dir == "off" {
+			return
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			log.Printf("Warning: failed to create Go build cache %s: %v", dir, err)
+			return
+		}
+		goCacheDir = dir
+	})
+	return goCacheDir
+}
+
+// runSandboxHelper runs inside the new namespaces. It makes the file system
+// read-only apart from the writable directories, or only the read-only
+// directories when the spec names some, and then executes the command. It
+// never returns.
+func runSandboxHelper(encoded string, argv []string) {
+	var spec sandboxSpec
+	if err := json.Unmarshal([]byte(encoded), &spec); err != nil {
+		sandboxFail("invalid sandbox spec: %v", err)
+	}
+	if len(argv) == 0 {
+		sandboxFail("no command given")
+	}
+
+	// Keep mount changes inside this namespace
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		sandboxFail("failed to make mounts private: %v", err)
+	}
+
+	if len(spec.ReadOnly) > 0 {
+		bindReadOnly(spec.ReadOnly)
+	} else {
+		remountReadOnly(spec.Writable)
+	}
+
+	// The old working directory still refers to the mount below the bind
+	if err := os.Chdir(spec.Dir); err != nil {
+		sandboxFail("failed to enter %s: %v", spec.Dir, err)
+	}
+	err := syscall.Exec(spec.Path, argv, os.Environ())
+	sandboxFail("failed to execute %s: %v", spec.Path, err)
+}
+
+// remountReadOnly makes every mount read-only apart from the writable directories
+func remountReadOnly(dirs []string) {
+	// Bind each writable directory onto itself so it survives the read-only remount
+	writable := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		resolved, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			sandboxFail("writable directory %s: %v", dir, err)
+		}
+		if err := syscall.Mount(resolved, resolved, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+			sandboxFail("failed to bind %s: %v", resolved, err)
+		}
+		writable = append(writable, resolved)
+	}
+
+	mounts, err := readMountPoints()
+	if err != nil {
+		sandboxFail("failed to read mounts: %v", err)
+	}
+	for _, m := range mounts {
+		if underAny(m.path, writable) {
+			continue
+		}
+		flags := uintptr(syscall.MS_BIND|syscall.MS_REMOUNT|syscall.MS_RDONLY) | m.flags
+		if err := syscall.Mount("", m.path, "", flags, ""); err != nil && m.path == "/" {
+			sandboxFail("failed to make root read-only: %v", err)
+		}
+	}
+}
+
+// bindReadOnly binds each directory onto itself and makes the bind read-only,
+// keeping the flags of the mount it lives on
+func bindReadOnly(dirs []string) {
+	mounts, err := readMountPoints()
+	if err != nil {
+		sandboxFail("failed to read mounts: %v", err)
+	}
+	for _, dir := range dirs {
+		resolved, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			sandboxFail("read-only directory %s: %v", dir, err)
+		}
+		var flags uintptr
+		longest := -1
+		for _, m := range mounts {
+			if len(m.path) > longest && underAny(resolved, []string{m.path}) {
+				flags, longest = m.flags, len(m.path)
+			}
+		}
+		if err := syscall.Mount(resolved, resolved, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+			sandboxFail("failed to bind %s: %v", resolved, err)
+		}
+		if err := syscall.Mount("", resolved, "", syscall.MS_BIND|syscall.MS_REMOUNT|syscall.MS_RDONLY|flags, ""); err != nil {
+			sandboxFail("failed to make %s read-only: %v", resolved, err)
+		}
+	}
+}
+
+// mountPoint is a mount from /proc/self/mountinfo with the flags that must be
+// preserved when it is remounted inside a user namespace
+type mountPoint struct {
+	path  string
+	flags uintptr
+}
+
+// readMountPoints lists the mounts visible to the current process
+func readMountPoints() ([]mountPoint, error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var mounts []mountPoint
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 {
+			continue
+		}
+		m := mountPoint{path: unescapeMountPath(fields[4])}
+		for _, opt := range strings.Split(fields[5], ",") {
+			switch opt {
+			case "nosuid":
+				m.flags |= syscall.MS_NOSUID
+			case "nodev":
+				m.flags |= syscall.MS_NODEV
+			case "noexec":
+				m.flags |= syscall.MS_NOEXEC
+			case "noatime":
+				m.flags |= syscall.MS_NOATIME
+			case "nodiratime":
+				m.flags |= syscall.MS_NODIRATIME
+			case "relatime":
+				m.flags |= syscall.MS_RELATIME
+			}
+		}
+		mounts = append(mounts, m)
+	}
+	return mounts, scanner.Err()
+}
+
+// unescapeMountPath decodes the octal escapes used for spaces and other
+// special characters in /proc/self/mountinfo
+func unescapeMountPath(path string) string {
+	if !strings.Contains(path, `\`) {
+		return path
+	}
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] == '\\' && i+3 < len(path) {
+			var c byte
+			if _, err := fmt.Sscanf(path[i+1:i+4], "%03o", &c); err == nil {
+				b.WriteByte(c)
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(path[i])
+	}
+	return b.String()
+}
+
+// underAny reports whether path is one of dirs or inside one of them
+func underAny(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		if path == dir || strings.HasPrefix(path, strings.TrimSuffix(dir, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// sandboxFail reports a sandbox setup error on stderr and exits with status 126
+func sandboxFail(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "sandbox: "+format+"\n", args...)
+	os.Exit(126)
+}
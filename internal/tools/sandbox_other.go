@@ -0,0 +1,10 @@
+//go:build !linux
+
+package tools
+
+import "fmt"
+
+// newNamespaceSandbox fails outside Linux, where user, mount and network namespaces are unavailable
+func newNamespaceSandbox() (Sandbox, error) {
+	return nil, fmt.Errorf("namespace sandbox is only supported on Linux")
+}
@@ -0,0 +1,63 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/MrFixit96/go-dev-mcp/internal/config"
+)
+
+func TestNewSandbox(t *testing.T) {
+	for _, name := range []string{SandboxProcess, SandboxNone} {
+		sandbox, err := NewSandbox(name)
+		if err != nil {
+			t.Fatalf("Expected %s sandbox, got error: %v", name, err)
+		}
+		if sandbox.Name() != name {
+			t.Errorf("Expected sandbox name %s, got %s", name, sandbox.Name())
+		}
+	}
+	if _, err := NewSandbox("chroot"); err == nil {
+		t.Error("Expected an error for an unknown sandbox type")
+	}
+}
+
+func TestNamespaceSandboxIsolatesCommand(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("namespace sandbox requires Linux")
+	}
+	if err := exec.Command("unshare", "-Urnm", "true").Run(); err != nil {
+		t.Skipf("user namespaces unavailable: %v", err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.SandboxType = SandboxNamespace
+	SetConfig(cfg)
+	defer SetConfig(config.DefaultConfig())
+
+	workDir := t.TempDir()
+	outside := t.TempDir()
+	cmd := exec.Command("sh", "-c", "touch inside && touch "+filepath.Join(outside, "escaped"))
+	cmd.Dir = workDir
+	result, err := execute(context.Background(), cmd)
+	if err != nil {
+		t.Fatalf("Execution failed: %v", err)
+	}
+	if result.Sandbox != SandboxNamespace {
+		t.Errorf("Expected sandbox %s, got %s", SandboxNamespace, result.Sandbox)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "inside")); err != nil {
+		t.Errorf("Expected the working directory to be writable: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "escaped")); err == nil {
+		t.Error("Expected writes outside the working directory to fail")
+	}
+	if !strings.Contains(result.Stderr, "Read-only file system") {
+		t.Errorf("Expected a read-only file system error, got %q", result.Stderr)
+	}
+}
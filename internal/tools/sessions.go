@@ -0,0 +1,440 @@
+package tools
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Session is a scratch module that persists across tool calls, so that an
+// edit/build/test loop works on one directory instead of a new temporary module
+// per call
+type Session struct {
+	ID        string
+	Dir       string
+	Module    string
+	CreatedAt time.Time
+
+	mu       sync.Mutex
+	lastUsed time.Time
+}
+
+// touch records that the session was used
+func (s *Session) touch() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastUsed = time.Now()
+}
+
+// idleSince returns when the session was last used
+func (s *Session) idleSince() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastUsed
+}
+
+// Write saves files into the session and deletes the files named in remove.
+// The write is refused if it would take the session over its disk quota.
+func (s *Session) Write(files map[string]string, remove []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastUsed = time.Now()
+
+	size, err := dirSize(s.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to measure session %s: %v", s.ID, err)
+	}
+	for name, content := range files {
+		size += int64(len(content))
+		if info, err := os.Stat(filepath.Join(s.Dir, filepath.FromSlash(name))); err == nil {
+			size -= info.Size()
+		}
+	}
+	if quota := int64(getConfig().Sessions.MaxSizeMB) << 20; quota > 0 && size > quota {
+		return fmt.Errorf("session %s would use %d bytes, over its quota of %d MB", s.ID, size, getConfig().Sessions.MaxSizeMB)
+	}
+
+	for _, name := range remove {
+		clean, err := validateFilePath(name)
+		if err != nil {
+			return err
+		}
+		if err := os.RemoveAll(filepath.Join(s.Dir, filepath.FromSlash(clean))); err != nil {
+			return fmt.Errorf("failed to delete %s: %v", clean, err)
+		}
+	}
+	for name, content := range files {
+		target := filepath.Join(s.Dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(target, []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// summary returns the session state for a tool response
+func (s *Session) summary() map[string]interface{} {
+	lastUsed := s.idleSince()
+	summary := map[string]interface{}{
+		"sessionId":  s.ID,
+		"path":       s.Dir,
+		"module":     s.Module,
+		"createdAt":  s.CreatedAt.Format(time.RFC3339),
+		"lastUsedAt": lastUsed.Format(time.RFC3339),
+	}
+	if idle := getConfig().Sessions.IdleTimeoutSecs; idle > 0 {
+		summary["expiresAt"] = lastUsed.Add(time.Duration(idle) * time.Second).Format(time.RFC3339)
+	}
+
+	files := []string{}
+	var size int64
+	filepath.WalkDir(s.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if rel, err := filepath.Rel(s.Dir, path); err == nil {
+			files = append(files, filepath.ToSlash(rel))
+		}
+		if info, err := d.Info(); err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+	sort.Strings(files)
+	summary["files"] = files
+	summary["sizeBytes"] = size
+	return summary
+}
+
+// dirSize returns the total size of the files below dir
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, err
+}
+
+// maxSessionSweepInterval bounds the time between two checks for idle sessions
+const maxSessionSweepInterval = time.Minute
+
+// SessionRegistry keeps track of scratch sessions. Sessions left unused for
+// the configured idle timeout are deleted along with their directory, by a
+// background sweeper and whenever the registry is used, unless a command is
+// running on them.
+type SessionRegistry struct {
+	mu       sync.Mutex
+	sessions map[string]*Session
+	sweeper  sync.Once
+	closed   chan struct{}
+	close    sync.Once
+}
+
+// NewSessionRegistry creates an empty session registry
+func NewSessionRegistry() *SessionRegistry {
+	return &SessionRegistry{sessions: make(map[string]*Session), closed: make(chan struct{})}
+}
+
+// sessions is the registry used by the session tools and the session_id argument
+var sessions = NewSessionRegistry()
+
+// Create makes a new session with a module named module and the given files.
+// A go.mod among the files replaces the generated one.
+func (r *SessionRegistry) Create(ctx context.Context, module string, files map[string]string) (*Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prune()
+
+	cfg := getConfig().Sessions
+	if cfg.MaxSessions > 0 && len(r.sessions) >= cfg.MaxSessions {
+		return nil, fmt.Errorf("too many sessions (limit %d); delete one with go_session_delete", cfg.MaxSessions)
+	}
+
+	id, err := newSessionID()
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "go-session-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session directory: %v", err)
+	}
+	session := &Session{ID: id, Dir: dir, Module: module, CreatedAt: time.Now(), lastUsed: time.Now()}
+
+	if err := writeCodeModule(ctx, dir, InputContext{Source: SourceCode}, module); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	if err := session.Write(files, nil); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	r.sessions[id] = session
+	r.sweeper.Do(func() { go r.sweep() })
+	return session, nil
+}
+
+// Get returns the session with the given ID and marks it as used
+func (r *SessionRegistry) Get(id string) (*Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prune()
+	session, ok := r.sessions[id]
+	if !ok {
+		return nil, fmt.Errorf("session %s not found; sessions are deleted after %d seconds without use", id, getConfig().Sessions.IdleTimeoutSecs)
+	}
+	session.touch()
+	return session, nil
+}
+
+// List returns the sessions ordered by creation time
+func (r *SessionRegistry) List() []*Session {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prune()
+	list := make([]*Session, 0, len(r.sessions))
+	for _, session := range r.sessions {
+		list = append(list, session)
+	}
+	sort.Slice(list, func(i, k int) bool { return list[i].CreatedAt.Before(list[k].CreatedAt) })
+	return list
+}
+
+// Delete removes a session and its directory once the commands running on it
+// have finished, waiting for them as long as for any other lock
+func (r *SessionRegistry) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	session, ok := r.sessions[id]
+	if !ok {
+		r.mu.Unlock()
+		return fmt.Errorf("session %s not found", id)
+	}
+	delete(r.sessions, id)
+	r.mu.Unlock()
+
+	timeout := time.Duration(getConfig().Locks.TimeoutSecs) * time.Second
+	release, _, err := workspaceLocks.acquire(ctx, resolveLockRoot(session.Dir), lockWrite, timeout)
+	if err != nil {
+		// Keep the session while it is in use
+		r.mu.Lock()
+		r.sessions[id] = session
+		r.mu.Unlock()
+		return err
+	}
+	defer release()
+	return os.RemoveAll(session.Dir)
+}
+
+// Close stops the sweeper and deletes every session. The server calls it on shutdown.
+func (r *SessionRegistry) Close() {
+	r.close.Do(func() { close(r.closed) })
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, session := range r.sessions {
+		delete(r.sessions, id)
+		os.RemoveAll(session.Dir)
+	}
+}
+
+// CloseSessions deletes the sessions of the session tools
+func CloseSessions() {
+	sessions.Close()
+}
+
+// prune deletes sessions idle past the configured timeout, skipping those a
+// command is running on. The caller must hold r.mu.
+func (r *SessionRegistry) prune() {
+	idle := getConfig().Sessions.IdleTimeoutSecs
+	if idle <= 0 {
+		return
+	}
+	for id, session := range r.sessions {
+		if time.Since(session.idleSince()) <= time.Duration(idle)*time.Second {
+			continue
+		}
+		release, ok := workspaceLocks.tryAcquire(resolveLockRoot(session.Dir), lockWrite)
+		if !ok {
+			continue
+		}
+		delete(r.sessions, id)
+		os.RemoveAll(session.Dir)
+		release()
+	}
+}
+
+// sweep prunes the registry twice per idle timeout, and at least every
+// maxSessionSweepInterval, until the registry is closed
+func (r *SessionRegistry) sweep() {
+	ticker := time.NewTicker(sessionSweepInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.mu.Lock()
+			r.prune()
+			r.mu.Unlock()
+			ticker.Reset(sessionSweepInterval())
+		case <-r.closed:
+			return
+		}
+	}
+}
+
+// sessionSweepInterval returns how often the sweeper looks for idle sessions
+func sessionSweepInterval() time.Duration {
+	interval := time.Duration(getConfig().Sessions.IdleTimeoutSecs) * time.Second / 2
+	if interval <= 0 || interval > maxSessionSweepInterval {
+		return maxSessionSweepInterval
+	}
+	return interval
+}
+
+// newSessionID returns a random session identifier
+func newSessionID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate session ID: %v", err)
+	}
+	return "session-" + hex.EncodeToString(b), nil
+}
+
+// resolveSession points input at the session named by the session_id argument.
+// Code, test code and files given with it are saved into the session first, so
+// the command runs on the session as a project.
+func resolveSession(input *InputContext, id string) error {
+	if input.ProjectPath != "" || input.WorkspacePath != "" {
+		return fmt.Errorf("session_id cannot be combined with project_path or workspace_path")
+	}
+	session, err := sessions.Get(id)
+	if err != nil {
+		return err
+	}
+
+	files, err := input.sourceFiles()
+	if err != nil {
+		return err
+	}
+	if err := session.Write(files, nil); err != nil {
+		return err
+	}
+
+	input.Source = SourceProjectPath
+	input.ProjectPath = session.Dir
+	input.Code, input.TestCode, input.Files = "", "", nil
+	return nil
+}
+
+// ExecuteGoSessionCreateTool handles the go_session_create tool execution
+func ExecuteGoSessionCreateTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	files, err := parseFilesInput(req)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	ctx = withRequestLimits(ctx, req)
+
+	session, err := sessions.Create(ctx, mcp.ParseString(req, "module", "scratch"), files)
+	if err != nil {
+		return executionErrorResult(err, "Failed to create session"), nil
+	}
+
+	response := session.summary()
+	response["success"] = true
+	response["message"] = fmt.Sprintf("Created session %s; pass session_id to go_build, go_run, go_test, go_fmt, go_mod or go_analyze to work on it", session.ID)
+	return jsonResult(response)
+}
+
+// ExecuteGoSessionWriteTool handles the go_session_write tool execution
+func ExecuteGoSessionWriteTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	session, err := sessionFromRequest(req)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	files, err := parseFilesInput(req)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	var remove []string
+	if value, ok := req.GetArguments()["delete"]; ok && value != nil {
+		entries, ok := value.([]interface{})
+		if !ok {
+			return mcp.NewToolResultError("delete must be an array of file paths"), nil
+		}
+		for _, entry := range entries {
+			name, ok := entry.(string)
+			if !ok {
+				return mcp.NewToolResultError("delete must be an array of file paths"), nil
+			}
+			remove = append(remove, name)
+		}
+	}
+
+	if err := session.Write(files, remove); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	response := session.summary()
+	response["success"] = true
+	response["message"] = fmt.Sprintf("Wrote %d and deleted %d files in session %s", len(files), len(remove), session.ID)
+	return jsonResult(response)
+}
+
+// ExecuteGoSessionListTool handles the go_session_list tool execution
+func ExecuteGoSessionListTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	list := []map[string]interface{}{}
+	for _, session := range sessions.List() {
+		list = append(list, session.summary())
+	}
+
+	response := map[string]interface{}{
+		"success":  true,
+		"sessions": list,
+	}
+	return jsonResult(response)
+}
+
+// ExecuteGoSessionDeleteTool handles the go_session_delete tool execution
+func ExecuteGoSessionDeleteTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id, err := req.RequireString("session_id")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if err := sessions.Delete(ctx, id); err != nil {
+		return executionErrorResult(err, "Failed to delete session"), nil
+	}
+
+	response := map[string]interface{}{
+		"success":   true,
+		"sessionId": id,
+		"message":   fmt.Sprintf("Session %s deleted", id),
+	}
+	return jsonResult(response)
+}
+
+// sessionFromRequest looks up the session named by the session_id argument
+func sessionFromRequest(req mcp.CallToolRequest) (*Session, error) {
+	id, err := req.RequireString("session_id")
+	if err != nil {
+		return nil, err
+	}
+	return sessions.Get(id)
+}
@@ -0,0 +1,175 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MrFixit96/go-dev-mcp/internal/config"
+)
+
+func TestSessionWorkflow(t *testing.T) {
+	SetConfig(config.DefaultConfig())
+	t.Setenv("GOFLAGS", "")
+	ctx := context.Background()
+
+	result, err := ExecuteGoSessionCreateTool(ctx, newToolRequest("go_session_create", map[string]interface{}{
+		"module": "example.com/scratch",
+		"files": map[string]interface{}{
+			"calc/calc.go": "package calc\n\nfunc Add(a, b int) int { return a - b }\n",
+		},
+	}))
+	if err != nil || result.IsError {
+		t.Fatalf("go_session_create failed: %v %s", err, resultText(result))
+	}
+	var created struct {
+		SessionID string   `json:"sessionId"`
+		Path      string   `json:"path"`
+		Files     []string `json:"files"`
+	}
+	if err := json.Unmarshal([]byte(resultText(result)), &created); err != nil {
+		t.Fatal(err)
+	}
+	defer sessions.Delete(context.Background(), created.SessionID)
+	if strings.Join(created.Files, ",") != "calc/calc.go,go.mod" {
+		t.Errorf("Unexpected session files %v", created.Files)
+	}
+
+	// A test saved with the call runs against the session and finds the bug
+	result, err = ExecuteGoTestTool(ctx, newToolRequest("go_test", map[string]interface{}{
+		"session_id": created.SessionID,
+		"files": map[string]interface{}{
+			"calc/calc_test.go": "package calc\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {\n\tif Add(1, 2) != 3 {\n\t\tt.Fatal(\"wrong sum\")\n\t}\n}\n",
+		},
+	}))
+	if err != nil || !result.IsError || !strings.Contains(resultText(result), "wrong sum") {
+		t.Fatalf("Expected the session test to fail, got %v %s", err, resultText(result))
+	}
+
+	// Fixing the code in the same session makes the saved test pass
+	result, err = ExecuteGoSessionWriteTool(ctx, newToolRequest("go_session_write", map[string]interface{}{
+		"session_id": created.SessionID,
+		"files": map[string]interface{}{
+			"calc/calc.go": "package calc\n\nfunc Add(a, b int) int { return a + b }\n",
+		},
+	}))
+	if err != nil || result.IsError {
+		t.Fatalf("go_session_write failed: %v %s", err, resultText(result))
+	}
+	result, err = ExecuteGoTestTool(ctx, newToolRequest("go_test", map[string]interface{}{
+		"session_id": created.SessionID,
+	}))
+	if err != nil || result.IsError {
+		t.Fatalf("Expected the session test to pass, got %v %s", err, resultText(result))
+	}
+
+	result, err = ExecuteGoSessionListTool(ctx, newToolRequest("go_session_list", nil))
+	if err != nil || !strings.Contains(resultText(result), created.SessionID) {
+		t.Errorf("Expected the session to be listed, got %v %s", err, resultText(result))
+	}
+
+	result, err = ExecuteGoSessionDeleteTool(ctx, newToolRequest("go_session_delete", map[string]interface{}{
+		"session_id": created.SessionID,
+	}))
+	if err != nil || result.IsError {
+		t.Fatalf("go_session_delete failed: %v %s", err, resultText(result))
+	}
+	if _, err := os.Stat(created.Path); !os.IsNotExist(err) {
+		t.Errorf("Expected the session directory to be removed, got %v", err)
+	}
+}
+
+func TestSessionQuotaAndIdleTimeout(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Sessions.MaxSizeMB = 1
+	SetConfig(cfg)
+	defer SetConfig(config.DefaultConfig())
+	t.Setenv("GOFLAGS", "")
+
+	registry := NewSessionRegistry()
+	session, err := registry.Create(context.Background(), "scratch", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(session.Dir)
+
+	err = session.Write(map[string]string{"big.txt": strings.Repeat("x", 2<<20)}, nil)
+	if err == nil || !strings.Contains(err.Error(), "quota") {
+		t.Errorf("Expected a quota error, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(session.Dir, "big.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected the write to be refused, got %v", err)
+	}
+	if err := session.Write(nil, []string{"../escape"}); err == nil {
+		t.Error("Expected deleting a path outside the session to fail")
+	}
+
+	// An idle session is deleted the next time the registry is used
+	session.mu.Lock()
+	session.lastUsed = time.Now().Add(-2 * time.Duration(cfg.Sessions.IdleTimeoutSecs) * time.Second)
+	session.mu.Unlock()
+	if _, err := registry.Get(session.ID); err == nil {
+		t.Error("Expected the idle session to be gone")
+	}
+	if _, err := os.Stat(session.Dir); !os.IsNotExist(err) {
+		t.Errorf("Expected the idle session directory to be removed, got %v", err)
+	}
+}
+
+func TestSessionSweeper(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Sessions.IdleTimeoutSecs = 1
+	SetConfig(cfg)
+	defer SetConfig(config.DefaultConfig())
+	t.Setenv("GOFLAGS", "")
+
+	registry := NewSessionRegistry()
+	defer registry.Close()
+	session, err := registry.Create(context.Background(), "scratch", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exists := func() bool {
+		registry.mu.Lock()
+		defer registry.mu.Unlock()
+		_, ok := registry.sessions[session.ID]
+		return ok
+	}
+
+	// A session with a command running on it outlives its idle timeout
+	release, _, err := workspaceLocks.acquire(context.Background(), resolveLockRoot(session.Dir), lockRead, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(1500 * time.Millisecond)
+	if !exists() {
+		t.Error("Expected a session in use to be kept")
+	}
+	release()
+
+	// and is then removed without any further call
+	deadline := time.Now().Add(3 * time.Second)
+	for exists() && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if exists() {
+		t.Fatal("Expected the sweeper to delete the idle session")
+	}
+	if _, err := os.Stat(session.Dir); !os.IsNotExist(err) {
+		t.Errorf("Expected the idle session directory to be removed, got %v", err)
+	}
+
+	// Closing the registry deletes the remaining sessions
+	session, err = registry.Create(context.Background(), "scratch", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry.Close()
+	if _, err := os.Stat(session.Dir); !os.IsNotExist(err) {
+		t.Errorf("Expected closing to remove the session directory, got %v", err)
+	}
+}
@@ -2,11 +2,15 @@ package tools
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"go/parser"
+	"go/token"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
-	"time"
+	"strings"
 )
 
 // ExecutionStrategy defines the interface for different execution strategies
@@ -19,6 +23,14 @@ type CodeExecutionStrategy struct{}
 
 // Execute creates a temporary environment for code execution
 func (s *CodeExecutionStrategy) Execute(ctx context.Context, input InputContext, args []string) (*ExecutionResult, error) {
+	// Identical code and arguments give identical results for deterministic commands
+	key, cacheable := codeCacheKey(ctx, input, args)
+	if cacheable {
+		if result, _, ok := results.get(key); ok {
+			return result, nil
+		}
+	}
+
 	// Create temporary directory for the operation
 	tmpDir, err := os.MkdirTemp("", "go-exec-*")
 	if err != nil {
@@ -26,49 +38,19 @@ func (s *CodeExecutionStrategy) Execute(ctx context.Context, input InputContext,
 	}
 	defer os.RemoveAll(tmpDir)
 
-	// Create a simple Go module
-	modCmd := exec.Command("go", "mod", "init", "temp")
-	modCmd.Dir = tmpDir
-	if output, err := modCmd.CombinedOutput(); err != nil {
-		return nil, fmt.Errorf("failed to initialize Go module: %v\n%s", err, output)
-	}
-
-	// Write main source file
-	sourceFile := filepath.Join(tmpDir, input.MainFile)
-	if err := os.WriteFile(sourceFile, []byte(input.Code), 0644); err != nil {
-		return nil, fmt.Errorf("failed to write source code: %v", err)
-	}
-
-	// Write test file if test code is provided
-	if input.TestCode != "" {
-		testFileName := "main_test.go"
-		if input.MainFile != "main.go" {
-			// Generate test filename based on main file
-			ext := filepath.Ext(input.MainFile)
-			base := input.MainFile[:len(input.MainFile)-len(ext)]
-			testFileName = base + "_test" + ext
-		}
-
-		testFile := filepath.Join(tmpDir, testFileName)
-		if err := os.WriteFile(testFile, []byte(input.TestCode), 0644); err != nil {
-			return nil, fmt.Errorf("failed to write test code: %v", err)
-		}
+	// Write the code into a module, unless it brings its own go.mod
+	if err := writeCodeModule(ctx, tmpDir, input, "temp"); err != nil {
+		return nil, err
 	}
 
 	// Prepare command
-	cmd := exec.Command("go", args...)
-	cmd.Dir = tmpDir
+	cmd := goCommand(input, tmpDir, args...)
 
-	// Execute command with timeout if set
-	if deadline, ok := ctx.Deadline(); ok {
-		timeout := time.Until(deadline)
-		execCtx, cancel := context.WithTimeout(ctx, timeout)
-		defer cancel()
-		cmd = exec.CommandContext(execCtx, cmd.Path, cmd.Args[1:]...)
-		cmd.Dir = tmpDir
+	result, err := execute(ctx, cmd)
+	if err == nil && cacheable && result.Successful {
+		results.put(key, result, nil)
 	}
-
-	return execute(cmd)
+	return result, err
 }
 
 // ProjectExecutionStrategy handles execution in an existing project directory
@@ -77,105 +59,169 @@ type ProjectExecutionStrategy struct{}
 // Execute runs commands in the project directory
 func (s *ProjectExecutionStrategy) Execute(ctx context.Context, input InputContext, args []string) (*ExecutionResult, error) {
 	// Prepare command
-	cmd := exec.Command("go", args...)
-	cmd.Dir = input.ProjectPath
-
-	// Execute command with timeout if set
-	if deadline, ok := ctx.Deadline(); ok {
-		timeout := time.Until(deadline)
-		execCtx, cancel := context.WithTimeout(ctx, timeout)
-		defer cancel()
-		cmd = exec.CommandContext(execCtx, cmd.Path, cmd.Args[1:]...)
-		cmd.Dir = input.ProjectPath
+	dir := input.ProjectPath
+	if input.WorkingDir != "" {
+		dir = input.WorkingDir
 	}
+	cmd := goCommand(input, dir, args...)
 
-	return execute(cmd)
+	return execute(ctx, cmd, input.ProjectPath)
 }
 
 // HybridExecutionStrategy handles hybrid scenarios where both code and project path are provided
 type HybridExecutionStrategy struct{}
 
-// Execute runs commands with knowledge of both code and project structure
+// overlayCommands are the go commands that accept -overlay
+var overlayCommands = map[string]bool{"build": true, "run": true, "test": true, "vet": true, "list": true}
+
+// Execute builds the snippet against the real project. The code, test code and files
+// are written to a temporary directory and mapped to their paths inside the project
+// (the code at input.MainFile) with go build -overlay, so the project's own packages
+// resolve and no project file is copied or modified.
 func (s *HybridExecutionStrategy) Execute(ctx context.Context, input InputContext, args []string) (*ExecutionResult, error) {
-	// Create temporary directory for the operation
+	if len(args) == 0 || !overlayCommands[args[0]] {
+		return nil, fmt.Errorf("go %s does not support code overlays; pass either code or project_path", strings.Join(args, " "))
+	}
+
+	files, err := input.sourceFiles()
+	if err != nil {
+		return nil, err
+	}
+	// The go command resolves relative overlay paths against its working
+	// directory, not the project
+	if input.ProjectPath, err = filepath.Abs(input.ProjectPath); err != nil {
+		return nil, err
+	}
+	if args[0] == "test" || args[0] == "vet" {
+		// Test binaries and vet run inside the package directory
+		for _, arg := range args[1:] {
+			if isLocalPackage(arg) && !strings.Contains(arg, "...") && !dirExists(filepath.Join(input.ProjectPath, arg)) {
+				return nil, fmt.Errorf("go %s needs %s to be an existing package directory in the project", args[0], arg)
+			}
+		}
+	}
+
+	// Create temporary directory for the overlaid files
 	tmpDir, err := os.MkdirTemp("", "go-hybrid-*")
 	if err != nil {
 		return nil, fmt.Errorf("failed to create temp directory: %v", err)
 	}
 	defer os.RemoveAll(tmpDir)
 
-	// First, copy relevant files from the project to understand dependencies
-	err = copyRelevantProjectFiles(input.ProjectPath, tmpDir)
+	// Each file keeps its path below the temporary directory
+	overlay := goOverlay{Replace: make(map[string]string)}
+	for name, content := range files {
+		tmpFile := filepath.Join(tmpDir, "src", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(tmpFile), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(tmpFile, []byte(content), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %v", name, err)
+		}
+		overlay.Replace[filepath.Join(input.ProjectPath, filepath.FromSlash(name))] = tmpFile
+	}
+	overlayFile := filepath.Join(tmpDir, "overlay.json")
+	data, err := json.Marshal(overlay)
 	if err != nil {
-		return nil, fmt.Errorf("failed to copy project files: %v", err)
+		return nil, err
 	}
-
-	// Write main source file with the provided code
-	sourceFile := filepath.Join(tmpDir, input.MainFile)
-	if err := os.WriteFile(sourceFile, []byte(input.Code), 0644); err != nil {
-		return nil, fmt.Errorf("failed to write source code: %v", err)
+	if err := os.WriteFile(overlayFile, data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write overlay: %v", err)
 	}
 
-	// Write test file if test code is provided
-	if input.TestCode != "" {
-		testFileName := "main_test.go"
-		if input.MainFile != "main.go" {
-			// Generate test filename based on main file
-			ext := filepath.Ext(input.MainFile)
-			base := input.MainFile[:len(input.MainFile)-len(ext)]
-			testFileName = base + "_test" + ext
-		}
-
-		testFile := filepath.Join(tmpDir, testFileName)
-		if err := os.WriteFile(testFile, []byte(input.TestCode), 0644); err != nil {
-			return nil, fmt.Errorf("failed to write test code: %v", err)
-		}
+	// Prepare command; -overlay goes right after the subcommand
+	overlayArgs := append([]string{args[0], "-overlay=" + overlayFile}, args[1:]...)
+	dir := input.ProjectPath
+	if input.WorkingDir != "" {
+		dir = input.WorkingDir
 	}
+	cmd := goCommand(input, dir, overlayArgs...)
 
-	// Update the module dependencies
-	modTidyCmd := exec.Command("go", "mod", "tidy")
-	modTidyCmd.Dir = tmpDir
-	if output, err := modTidyCmd.CombinedOutput(); err != nil {
-		// Non-fatal error, just log it
-		fmt.Printf("Warning: failed to tidy module dependencies: %v\n%s", err, output)
+	result, err := execute(ctx, cmd, input.ProjectPath)
+	if result != nil {
+		overlay.restorePaths(result, dir)
 	}
+	return result, err
+}
 
-	// Prepare command
-	cmd := exec.Command("go", args...)
-	cmd.Dir = tmpDir
+// goOverlay is the JSON file read by go build -overlay
+type goOverlay struct {
+	Replace map[string]string
+}
 
-	// Execute command with timeout if set
-	if deadline, ok := ctx.Deadline(); ok {
-		timeout := time.Until(deadline)
-		execCtx, cancel := context.WithTimeout(ctx, timeout)
-		defer cancel()
-		cmd = exec.CommandContext(execCtx, cmd.Path, cmd.Args[1:]...)
-		cmd.Dir = tmpDir
+// restorePaths rewrites references to the temporary overlay files in the command
+// output so diagnostics point at the file's location in the project
+func (o goOverlay) restorePaths(result *ExecutionResult, dir string) {
+	var replacements []string
+	for projectFile, tmpFile := range o.Replace {
+		shown := projectFile
+		if rel, err := filepath.Rel(dir, projectFile); err == nil {
+			shown = rel
+		}
+		replacements = append(replacements, tmpFile, shown)
+		if rel, err := filepath.Rel(dir, tmpFile); err == nil {
+			replacements = append(replacements, rel, shown)
+		}
 	}
-
-	return execute(cmd)
+	replacer := strings.NewReplacer(replacements...)
+	result.Stdout = replacer.Replace(result.Stdout)
+	result.Stderr = replacer.Replace(result.Stderr)
 }
 
-// copyRelevantProjectFiles copies essential files from a project to understand dependencies
-func copyRelevantProjectFiles(srcDir, destDir string) error {
-	// Copy go.mod and go.sum if they exist
-	essentialFiles := []string{"go.mod", "go.sum"}
-	for _, file := range essentialFiles {
-		srcPath := filepath.Join(srcDir, file)
-		if _, err := os.Stat(srcPath); err == nil {
-			data, err := os.ReadFile(srcPath)
-			if err != nil {
-				return fmt.Errorf("failed to read %s: %v", file, err)
+// codePackage returns the package go run executes for code input: the package of
+// the main file when code is given, otherwise the only main package among the files
+func codePackage(input InputContext) (string, error) {
+	if input.Code != "" {
+		return relativePackage(".", path.Dir(input.MainFile)), nil
+	}
+	files, err := input.sourceFiles()
+	if err != nil {
+		return "", err
+	}
+	var candidates []string
+	for _, pkg := range input.sourcePackages() {
+		for name, content := range files {
+			if relativePackage(".", path.Dir(name)) != pkg || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+				continue
 			}
-
-			destPath := filepath.Join(destDir, file)
-			if err := os.WriteFile(destPath, data, 0644); err != nil {
-				return fmt.Errorf("failed to write %s: %v", file, err)
+			file, err := parser.ParseFile(token.NewFileSet(), name, content, parser.PackageClauseOnly)
+			if err == nil && file.Name.Name == "main" {
+				candidates = append(candidates, pkg)
+				break
 			}
 		}
 	}
+	switch len(candidates) {
+	case 0:
+		return "", &MainPackageError{Message: "no main package found in files"}
+	case 1:
+		return candidates[0], nil
+	default:
+		return "", &MainPackageError{Message: "multiple main packages found in files; set package to choose one", Candidates: candidates}
+	}
+}
 
-	return nil
+// testFileName returns the name of the test file that accompanies a source file
+func testFileName(mainFile string) string {
+	if mainFile == "main.go" {
+		return "main_test.go"
+	}
+	ext := filepath.Ext(mainFile)
+	return mainFile[:len(mainFile)-len(ext)] + "_test" + ext
+}
+
+// goCommand prepares a go command that runs in dir with the stdin and extra
+// environment variables of the input
+func goCommand(input InputContext, dir string, args ...string) *exec.Cmd {
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	if len(input.Env) > 0 {
+		cmd.Env = append(os.Environ(), input.Env...)
+	}
+	if input.Stdin != "" {
+		cmd.Stdin = strings.NewReader(input.Stdin)
+	}
+	return cmd
 }
 
 // CommandType represents different types of Go commands
@@ -324,7 +370,7 @@ func dirExists(dirname string) bool {
 // beyond the current binary selection. It considers factors like project structure and command type.
 func GetExecutionStrategy(input InputContext, args ...string) ExecutionStrategy {
 	// Handle hybrid case (both code and project_path provided)
-	if input.Source == SourceProjectPath && input.Code != "" {
+	if input.Source == SourceProjectPath && (input.Code != "" || len(input.Files) > 0) {
 		// Create a hybrid input source
 		hybridInput := input
 		hybridInput.Source = SourceHybrid
@@ -0,0 +1,70 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHybridOverlay(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"go.mod":         "module example.com/app\n\ngo 1.21\n",
+		"greet/greet.go": "package greet\n\nfunc Hello() string { return \"hello from the project\" }\n",
+	})
+	snippet := "package main\n\nimport (\n\t\"fmt\"\n\n\t\"example.com/app/greet\"\n)\n\nfunc main() { fmt.Println(greet.Hello()) }\n"
+
+	strategy := &HybridExecutionStrategy{}
+	input := InputContext{Source: SourceHybrid, ProjectPath: root, Code: snippet, MainFile: "cmd/try/main.go"}
+	result, err := strategy.Execute(context.Background(), input, []string{"run", "./cmd/try"})
+	if err != nil {
+		t.Fatalf("Hybrid execution failed: %v", err)
+	}
+	if !result.Successful || !strings.Contains(result.Stdout, "hello from the project") {
+		t.Errorf("Expected the snippet to use the project package, got stdout %q stderr %q", result.Stdout, result.Stderr)
+	}
+	if _, err := os.Stat(filepath.Join(root, "cmd")); !os.IsNotExist(err) {
+		t.Error("Hybrid execution must not create files in the project")
+	}
+
+	// Diagnostics point at the snippet's location in the project
+	input.Code = strings.Replace(snippet, "greet.Hello()", "greet.Missing()", 1)
+	result, err = strategy.Execute(context.Background(), input, []string{"build", "./..."})
+	if err != nil {
+		t.Fatalf("Hybrid execution failed: %v", err)
+	}
+	details := compilerDiagnostics(resultDiagnostics(result, root))
+	if result.Successful || len(details) != 1 || details[0].File != filepath.Join("cmd", "try", "main.go") {
+		t.Errorf("Expected an error in cmd/try/main.go, got %+v (stderr %q)", details, result.Stderr)
+	}
+
+	// Overlay paths stay correct for a project path relative to the server
+	chdir(t, filepath.Dir(root))
+	relative := input
+	relative.Code = snippet
+	relative.ProjectPath = filepath.Base(root)
+	result, err = strategy.Execute(context.Background(), relative, []string{"run", "./cmd/try"})
+	if err != nil || !strings.Contains(result.Stdout, "hello from the project") {
+		t.Errorf("Expected the overlay to apply with a relative project path, got %v %+v", err, result)
+	}
+
+	input.MainFile = "../outside.go"
+	if _, err := strategy.Execute(context.Background(), input, []string{"build", "./..."}); err == nil {
+		t.Error("Expected an error for a main file outside the project")
+	}
+}
+
+// chdir changes the working directory for the rest of the test
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
@@ -16,14 +16,15 @@ func ExecuteGoTestTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallT
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
+	ctx = withRequestLimits(ctx, req)
 	// Extract parameters
 	testPattern := mcp.ParseString(req, "testPattern", "")
 	verbose := mcp.ParseBoolean(req, "verbose", false)
 	coverage := mcp.ParseBoolean(req, "coverage", false)
 	module := mcp.ParseString(req, "module", "") // For workspace module selection
 
-	// Prepare test args
-	args := []string{"test"}
+	// Prepare test args; -json reports every test as a test2json event
+	args := []string{"test", "-json"}
 	if verbose {
 		args = append(args, "-v")
 	}
@@ -45,6 +46,9 @@ func ExecuteGoTestTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallT
 			// Test all modules in workspace
 			args = append(args, "./...")
 		}
+	case SourceHybrid:
+		// Test the packages the code is overlaid onto
+		args = append(args, input.sourcePackages()...)
 	default:
 		// Always add ./... to run all tests in the directory
 		args = append(args, "./...")
@@ -53,14 +57,15 @@ func ExecuteGoTestTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallT
 	strategy := GetExecutionStrategy(input, args...)
 	result, err := strategy.Execute(ctx, input, args)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Execution error: %v", err)), nil
+		return executionErrorResult(err, "Execution error"), nil
 	}
 
 	// Create structured response with proper error handling
+	report := ParseTestEvents(result.Stdout)
 	if result.Successful {
-		return formatTestSuccess(result, coverage), nil
+		return formatTestSuccess(result, report, coverage), nil
 	} else {
-		return formatTestError(result), nil
+		return formatTestError(result, report, input), nil
 	}
 }
 
@@ -77,22 +82,26 @@ func TestMain(t *testing.T) {
 }
 
 // formatTestSuccess creates a structured success response for tests
-func formatTestSuccess(result *ExecutionResult, withCoverage bool) *mcp.CallToolResult {
-	// Parse test output to extract coverage and test statistics
+func formatTestSuccess(result *ExecutionResult, report *TestReport, withCoverage bool) *mcp.CallToolResult {
+	// Extract coverage from the reconstructed test output
 	coverageInfo := ""
 	if withCoverage && result.Successful {
-		coverageInfo = extractCoverageInfo(result.Stdout)
+		coverageInfo = extractCoverageInfo(report.Output)
 	}
 
-	testStats := parseTestStats(result.Stdout)
-
 	response := map[string]interface{}{
 		"success":   true,
 		"message":   "Tests passed",
-		"output":    result.Stdout,
+		"output":    report.Output,
 		"duration":  result.Duration.String(),
+		"sandbox":   result.Sandbox,
+		"cacheHit":  result.CacheHit,
 		"coverage":  coverageInfo,
-		"testStats": testStats,
+		"testStats": report.Counts,
+		"packages":  report.Packages,
+	}
+	if modules := moduleSummaries(result); modules != nil {
+		response["modules"] = modules
 	}
 
 	// Add natural language metadata
@@ -107,19 +116,41 @@ func formatTestSuccess(result *ExecutionResult, withCoverage bool) *mcp.CallTool
 }
 
 // formatTestError creates a structured error response for tests
-func formatTestError(result *ExecutionResult) *mcp.CallToolResult {
-	// Parse test errors for more context
-	errorDetails := parseTestErrors(result.Stdout, result.Stderr)
+func formatTestError(result *ExecutionResult, report *TestReport, input InputContext) *mcp.CallToolResult {
+	// Build errors of the test binaries are reported on stderr or, on recent Go
+	// versions, as build-output events; failing tests come from the test events
+	var buildErrors []ErrorDetail
+	for _, r := range moduleResults(result) {
+		buildOutput := ParseTestEvents(r.Stdout).BuildOutput
+		buildErrors = append(buildErrors, ParseDiagnostics(r.Stderr+buildOutput, r.Dir, diagnosticRoot(input))...)
+	}
+	errorDetails := append(buildErrors, report.ErrorDetails()...)
+
+	message := "Tests failed"
+	if report.Counts.Failed == 0 && len(buildErrors) > 0 {
+		message = "Tests failed to build"
+	}
 
 	response := map[string]interface{}{
 		"success":      false,
-		"message":      "Tests failed",
-		"output":       result.Stdout,
+		"message":      message,
+		"output":       report.Output,
 		"stderr":       result.Stderr,
 		"exitCode":     result.ExitCode,
 		"duration":     result.Duration.String(),
+		"sandbox":      result.Sandbox,
+		"cacheHit":     result.CacheHit,
+		"testStats":    report.Counts,
+		"packages":     report.Packages,
+		"failedTests":  report.FailedTests(),
 		"errorDetails": errorDetails,
 	}
+	if len(buildErrors) > 0 {
+		response["buildErrors"] = GroupDiagnostics(buildErrors)
+	}
+	if modules := moduleSummaries(result); modules != nil {
+		response["modules"] = modules
+	}
 
 	// Add natural language metadata
 	AddNLMetadata(response, "go_test")
@@ -142,17 +173,3 @@ func extractCoverageInfo(output string) string {
 	}
 	return "Coverage information not available"
 }
-
-// parseTestStats extracts test statistics from output
-func parseTestStats(output string) string {
-	// In a real implementation, this would parse test output
-	// for test count, run time, etc. into structured JSON
-	return `{"count": "unknown", "passed": "unknown", "failed": "unknown"}`
-}
-
-// parseTestErrors extracts structured information from test failures
-func parseTestErrors(stdout, stderr string) string {
-	// In a real implementation, this would parse test failure output
-	// into structured JSON with file, line, and error details
-	return stderr
-}
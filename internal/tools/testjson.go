@@ -0,0 +1,350 @@
+package tools
+
+import (
+	"bufio"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Test statuses reported for packages and tests
+const (
+	TestStatusPass = "pass"
+	TestStatusFail = "fail"
+	TestStatusSkip = "skip"
+)
+
+// TestEvent is a single event emitted by go test -json (see cmd/test2json)
+type TestEvent struct {
+	Action     string  `json:"Action"`
+	Package    string  `json:"Package"`
+	ImportPath string  `json:"ImportPath"` // Set on build-output and build-fail events
+	Test       string  `json:"Test"`
+	Elapsed    float64 `json:"Elapsed"`
+	Output     string  `json:"Output"`
+	OutputType string  `json:"OutputType"` // frame, error or error-continue on recent Go versions
+}
+
+// TestCounts summarizes test outcomes. Subtests are counted individually.
+type TestCounts struct {
+	Total   int `json:"total"`
+	Passed  int `json:"passed"`
+	Failed  int `json:"failed"`
+	Skipped int `json:"skipped"`
+}
+
+// TestFailure is a failure message reported by t.Error, t.Fatal or a panic
+type TestFailure struct {
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+// TestCaseResult is the outcome of a single test and its subtests
+type TestCaseResult struct {
+	Name     string            `json:"name"`
+	Status   string            `json:"status"`
+	Elapsed  float64           `json:"elapsed"` // Seconds
+	Output   string            `json:"output,omitempty"`
+	Failures []TestFailure     `json:"failures,omitempty"`
+	Panic    string            `json:"panic,omitempty"`
+	Subtests []*TestCaseResult `json:"subtests,omitempty"`
+
+	typedOutput bool // Output events carry OutputType, so failures can be told apart from logs
+}
+
+// PackageTestResult is the outcome of testing one package
+type PackageTestResult struct {
+	Package  string            `json:"package"`
+	Status   string            `json:"status"`
+	Elapsed  float64           `json:"elapsed"` // Seconds
+	Coverage string            `json:"coverage,omitempty"`
+	Output   string            `json:"output,omitempty"` // Output not attributed to a test
+	Counts   TestCounts        `json:"counts"`
+	Tests    []*TestCaseResult `json:"tests,omitempty"`
+
+	tests map[string]*TestCaseResult
+}
+
+// FailedTest identifies a failing test and why it failed
+type FailedTest struct {
+	Package  string        `json:"package"`
+	Test     string        `json:"test"`
+	Failures []TestFailure `json:"failures,omitempty"`
+	Panic    string        `json:"panic,omitempty"`
+}
+
+// TestReport is the parsed output of go test -json
+type TestReport struct {
+	Packages []*PackageTestResult `json:"packages"`
+	Counts   TestCounts           `json:"counts"`
+	// Output is the plain text go test would have printed without -json
+	Output string `json:"-"`
+	// BuildOutput holds compiler output reported through build-output events
+	BuildOutput string `json:"-"`
+}
+
+var (
+	// testFailureLine matches "    file_test.go:12: message" as printed by t.Error and friends
+	testFailureLine = regexp.MustCompile(`^\s+([^\s:]+\.go):(\d+): ?(.*)$`)
+	// stackFrameLine matches "\t/path/file.go:12 +0x1d" in a goroutine trace
+	stackFrameLine = regexp.MustCompile(`^\t(.+\.go):(\d+)(?: \+0x[0-9a-f]+)?$`)
+	// coverageLine matches the per-package coverage summary
+	coverageLine = regexp.MustCompile(`^coverage: .*`)
+)
+
+// ParseTestEvents parses go test -json output into a per-package, per-test report.
+// Lines that are not JSON events are kept in the plain text output.
+func ParseTestEvents(stdout string) *TestReport {
+	report := &TestReport{}
+	packages := make(map[string]*PackageTestResult)
+	var output, buildOutput strings.Builder
+
+	pkgResult := func(name string) *PackageTestResult {
+		if p, ok := packages[name]; ok {
+			return p
+		}
+		p := &PackageTestResult{Package: name, tests: make(map[string]*TestCaseResult)}
+		packages[name] = p
+		report.Packages = append(report.Packages, p)
+		return p
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(stdout))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		var event TestEvent
+		if !strings.HasPrefix(line, "{") || json.Unmarshal([]byte(line), &event) != nil {
+			output.WriteString(line + "\n")
+			continue
+		}
+
+		switch event.Action {
+		case "build-output":
+			buildOutput.WriteString(event.Output)
+			output.WriteString(event.Output)
+			continue
+		case "build-fail":
+			continue
+		}
+
+		output.WriteString(event.Output)
+		if event.Package == "" {
+			continue
+		}
+		p := pkgResult(event.Package)
+
+		if event.Test == "" {
+			switch event.Action {
+			case "pass", "fail", "skip":
+				p.Status = event.Action
+				p.Elapsed = event.Elapsed
+			case "output":
+				text := strings.TrimRight(event.Output, "\n")
+				if coverageLine.MatchString(text) {
+					p.Coverage = text
+				} else if !isTestFrameLine(text, event.OutputType) {
+					p.Output += event.Output
+				}
+			}
+			continue
+		}
+
+		test := p.test(event.Test)
+		switch event.Action {
+		case "pass", "fail", "skip":
+			test.Status = event.Action
+			test.Elapsed = event.Elapsed
+		case "output":
+			test.addOutput(event.Output, event.OutputType)
+		}
+	}
+
+	report.Output = output.String()
+	report.BuildOutput = buildOutput.String()
+	for _, p := range report.Packages {
+		p.finish()
+		report.Counts.add(p.Counts)
+	}
+	return report
+}
+
+// FailedTests lists the failing tests whose failure is not explained by a failing subtest
+func (r *TestReport) FailedTests() []FailedTest {
+	var failed []FailedTest
+	var walk func(pkg string, tests []*TestCaseResult)
+	walk = func(pkg string, tests []*TestCaseResult) {
+		for _, t := range tests {
+			if t.Status != TestStatusFail {
+				continue
+			}
+			failingSubtest := false
+			for _, sub := range t.Subtests {
+				if sub.Status == TestStatusFail {
+					failingSubtest = true
+				}
+			}
+			if !failingSubtest || len(t.Failures) > 0 || t.Panic != "" {
+				failed = append(failed, FailedTest{Package: pkg, Test: t.Name, Failures: t.Failures, Panic: t.Panic})
+			}
+			walk(pkg, t.Subtests)
+		}
+	}
+	for _, p := range r.Packages {
+		walk(p.Package, p.Tests)
+	}
+	return failed
+}
+
+// ErrorDetails converts the failing tests into error details
+func (r *TestReport) ErrorDetails() []ErrorDetail {
+	var details []ErrorDetail
+	for _, f := range r.FailedTests() {
+		if len(f.Failures) == 0 {
+			details = append(details, ErrorDetail{
+				Type:    ErrorTypeTestFailure,
+				Message: f.Test + " failed",
+				Package: f.Package,
+			})
+		}
+		for _, failure := range f.Failures {
+			details = append(details, ErrorDetail{
+				Type:    ErrorTypeTestFailure,
+				Message: f.Test + ": " + failure.Message,
+				Package: f.Package,
+				File:    failure.File,
+				Line:    failure.Line,
+			})
+		}
+	}
+	return details
+}
+
+// test returns the result for a test, creating it and linking it to its parent test
+func (p *PackageTestResult) test(name string) *TestCaseResult {
+	if t, ok := p.tests[name]; ok {
+		return t
+	}
+	t := &TestCaseResult{Name: name}
+	p.tests[name] = t
+	if i := strings.LastIndex(name, "/"); i > 0 {
+		if parent, ok := p.tests[name[:i]]; ok {
+			parent.Subtests = append(parent.Subtests, t)
+			return t
+		}
+	}
+	p.Tests = append(p.Tests, t)
+	return t
+}
+
+// finish resolves tests that never reported a result and computes the package counts
+func (p *PackageTestResult) finish() {
+	if p.Status == "" {
+		p.Status = TestStatusFail
+	}
+	for _, t := range p.tests {
+		if t.Status == "" {
+			// The package was stopped (panic, timeout) while this test was running
+			t.Status = TestStatusFail
+		}
+		switch t.Status {
+		case TestStatusPass:
+			p.Counts.Passed++
+		case TestStatusFail:
+			p.Counts.Failed++
+		case TestStatusSkip:
+			p.Counts.Skipped++
+		}
+		p.Counts.Total++
+		if t.Status != TestStatusFail {
+			// Without OutputType, t.Log lines look like failures
+			t.Failures = nil
+		}
+		t.parsePanic()
+	}
+}
+
+// addOutput records a line of test output and extracts failure messages from it
+func (t *TestCaseResult) addOutput(text, outputType string) {
+	line := strings.TrimRight(text, "\n")
+	if outputType != "" {
+		t.typedOutput = true
+	}
+	if isTestFrameLine(line, outputType) {
+		return
+	}
+	t.Output += text
+
+	if t.Panic != "" || strings.HasPrefix(line, "panic: ") {
+		t.Panic += text
+		return
+	}
+	if outputType == "error-continue" || (!t.typedOutput && len(t.Failures) > 0 && strings.HasPrefix(line, "        ")) {
+		if n := len(t.Failures); n > 0 {
+			t.Failures[n-1].Message += "\n" + strings.TrimSpace(line)
+		}
+		return
+	}
+	if t.typedOutput && outputType != "error" {
+		return
+	}
+	if m := testFailureLine.FindStringSubmatch(line); m != nil {
+		lineNum, _ := strconv.Atoi(m[2])
+		t.Failures = append(t.Failures, TestFailure{File: m[1], Line: lineNum, Message: m[3]})
+	}
+}
+
+// parsePanic turns a recorded panic into a failure located at the frame that panicked
+func (t *TestCaseResult) parsePanic() {
+	if t.Panic == "" {
+		return
+	}
+	lines := strings.Split(t.Panic, "\n")
+	failure := TestFailure{Message: strings.TrimSpace(lines[0])}
+
+	// Frames are pairs of a function line and an indented file:line line. The
+	// panicking frame is the first one after panic() outside the runtime and testing packages.
+	afterPanic := false
+	for i := 0; i+1 < len(lines); i++ {
+		fn := lines[i]
+		m := stackFrameLine.FindStringSubmatch(lines[i+1])
+		if m == nil {
+			continue
+		}
+		i++
+		if strings.HasPrefix(fn, "panic(") {
+			afterPanic = true
+			continue
+		}
+		if !afterPanic || strings.HasPrefix(fn, "runtime.") || strings.HasPrefix(fn, "testing.") {
+			continue
+		}
+		failure.File = m[1]
+		failure.Line, _ = strconv.Atoi(m[2])
+		break
+	}
+	t.Failures = append(t.Failures, failure)
+}
+
+// isTestFrameLine reports whether a line is part of go test's own framing
+// (=== RUN, --- PASS, PASS, ok ...) rather than output of the test
+func isTestFrameLine(line, outputType string) bool {
+	if outputType == "frame" {
+		return true
+	}
+	trimmed := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmed, "=== ") ||
+		strings.HasPrefix(trimmed, "--- PASS") || strings.HasPrefix(trimmed, "--- FAIL") || strings.HasPrefix(trimmed, "--- SKIP") ||
+		trimmed == "PASS" || trimmed == "FAIL" ||
+		strings.HasPrefix(line, "ok  \t") || strings.HasPrefix(line, "FAIL\t") || strings.HasPrefix(line, "?   \t")
+}
+
+// add accumulates other counts into c
+func (c *TestCounts) add(other TestCounts) {
+	c.Total += other.Total
+	c.Passed += other.Passed
+	c.Failed += other.Failed
+	c.Skipped += other.Skipped
+}
@@ -0,0 +1,62 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseTestEvents(t *testing.T) {
+	stdout := strings.Join([]string{
+		`{"Action":"start","Package":"example.com/p"}`,
+		`{"Action":"run","Package":"example.com/p","Test":"TestPass"}`,
+		`{"Action":"output","Package":"example.com/p","Test":"TestPass","Output":"=== RUN   TestPass\n","OutputType":"frame"}`,
+		`{"Action":"output","Package":"example.com/p","Test":"TestPass","Output":"    p_test.go:5: just a log\n"}`,
+		`{"Action":"pass","Package":"example.com/p","Test":"TestPass","Elapsed":0.01}`,
+		`{"Action":"run","Package":"example.com/p","Test":"TestFail"}`,
+		`{"Action":"run","Package":"example.com/p","Test":"TestFail/bad"}`,
+		`{"Action":"output","Package":"example.com/p","Test":"TestFail/bad","Output":"    p_test.go:8: want 1\n","OutputType":"error"}`,
+		`{"Action":"output","Package":"example.com/p","Test":"TestFail/bad","Output":"        got 2\n","OutputType":"error-continue"}`,
+		`{"Action":"fail","Package":"example.com/p","Test":"TestFail/bad","Elapsed":0}`,
+		`{"Action":"run","Package":"example.com/p","Test":"TestFail/skip"}`,
+		`{"Action":"skip","Package":"example.com/p","Test":"TestFail/skip","Elapsed":0}`,
+		`{"Action":"fail","Package":"example.com/p","Test":"TestFail","Elapsed":0}`,
+		`{"Action":"run","Package":"example.com/p","Test":"TestPanic"}`,
+		`{"Action":"output","Package":"example.com/p","Test":"TestPanic","Output":"panic: boom\n"}`,
+		`{"Action":"output","Package":"example.com/p","Test":"TestPanic","Output":"panic({0x1, 0x2})\n"}`,
+		`{"Action":"output","Package":"example.com/p","Test":"TestPanic","Output":"\t/usr/local/go/src/runtime/panic.go:859 +0x125\n"}`,
+		`{"Action":"output","Package":"example.com/p","Test":"TestPanic","Output":"example.com/p.TestPanic(0x1)\n"}`,
+		`{"Action":"output","Package":"example.com/p","Test":"TestPanic","Output":"\t/src/p/p_test.go:11 +0x28\n"}`,
+		`{"Action":"fail","Package":"example.com/p","Test":"TestPanic","Elapsed":0}`,
+		`{"Action":"fail","Package":"example.com/p","Elapsed":0.5}`,
+	}, "\n")
+
+	report := ParseTestEvents(stdout)
+	want := TestCounts{Total: 5, Passed: 1, Failed: 3, Skipped: 1}
+	if report.Counts != want {
+		t.Errorf("Expected counts %+v, got %+v", want, report.Counts)
+	}
+	if len(report.Packages) != 1 || report.Packages[0].Status != TestStatusFail {
+		t.Fatalf("Expected one failing package, got %+v", report.Packages)
+	}
+
+	tests := report.Packages[0].Tests
+	if len(tests) != 3 || len(tests[1].Subtests) != 2 {
+		t.Fatalf("Expected 3 top-level tests with 2 subtests under TestFail, got %+v", tests)
+	}
+	if len(tests[0].Failures) != 0 {
+		t.Errorf("Expected log output of a passing test not to be a failure, got %+v", tests[0].Failures)
+	}
+
+	failed := report.FailedTests()
+	if len(failed) != 2 {
+		t.Fatalf("Expected 2 failed tests, got %+v", failed)
+	}
+	bad := failed[0]
+	if bad.Test != "TestFail/bad" || len(bad.Failures) != 1 || bad.Failures[0].Line != 8 || bad.Failures[0].Message != "want 1\ngot 2" {
+		t.Errorf("Unexpected failure for TestFail/bad: %+v", bad)
+	}
+	panicked := failed[1]
+	if panicked.Panic == "" || len(panicked.Failures) != 1 || panicked.Failures[0].File != "/src/p/p_test.go" || panicked.Failures[0].Line != 11 {
+		t.Errorf("Expected panic located at /src/p/p_test.go:11, got %+v", panicked)
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"os/exec"
+	"strings"
 	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
@@ -20,6 +22,14 @@ type ExecutionResult struct {
 	Duration   time.Duration
 	Successful bool
 	Command    string // Command that was executed
+	// LimitExceeded names the resource limit that stopped the command, if any
+	LimitExceeded string
+	Sandbox       string // Sandbox type the command ran in
+	Dir           string // Working directory of the command
+	// Modules holds the per-module results when a command fans out across a workspace
+	Modules []ModuleResult
+	// CacheHit is set when the result was served from the result cache
+	CacheHit bool
 }
 
 // NLMetadata represents natural language metadata for tools
@@ -179,43 +189,116 @@ var ToolNLMetadata = map[string]NLMetadata{
 	},
 }
 
-// execute runs a command and returns the execution result with better logging
-func execute(cmd *exec.Cmd) (*ExecutionResult, error) {
+// waitDelay bounds how long execute waits for output after the command was
+// stopped or exited
+const waitDelay = 5 * time.Second
+
+// outputWriterKey is the context key for the writer that receives live command output
+type outputWriterKey struct{}
+
+// withOutputWriter returns a context whose commands copy their output to w as it
+// is produced. w must be safe for concurrent use.
+func withOutputWriter(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, outputWriterKey{}, w)
+}
+
+// outputWriterFromContext returns the live output writer stored in ctx, if any
+func outputWriterFromContext(ctx context.Context) io.Writer {
+	w, _ := ctx.Value(outputWriterKey{}).(io.Writer)
+	return w
+}
+
+// execute runs a command in the configured sandbox under the resource limits
+// carried by ctx and returns the execution result. Besides cmd.Dir, the command
+// may modify the writable directories. The command and every process it started
+// are stopped when ctx is done or the configured timeout elapses; cancellation is
+// reported as a *CancelledError and limit violations as a *LimitExceededError.
+func execute(ctx context.Context, cmd *exec.Cmd, writable ...string) (*ExecutionResult, error) {
 	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
 
 	// Capture the command for debugging
 	cmdStr := cmd.String()
 	log.Printf("Executing command: %s", cmdStr)
 
-	// Execute with timeout context
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	sandbox, err := activeSandbox()
+	if err != nil {
+		return nil, err
+	}
+
+	// Wait for a worker; time in the queue does not count against the timeout
+	release, _, err := workers.acquire(ctx, clientFromContext(ctx))
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return nil, &CancelledError{}
+		}
+		return nil, err
+	}
+	defer release()
+
+	// Execute with the caller's context bounded by the configured timeout
+	limits := resourceLimitsFromContext(ctx)
+	if limits.TimeoutSecs > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(limits.TimeoutSecs)*time.Second)
+		defer cancel()
+	}
+
+	// Memory and CPU limits only apply when the sandbox enforces them
+	enforced := limits
+	if !sandbox.EnforcesLimits() {
+		enforced.MemoryLimit = 0
+		enforced.CPULimit = 0
+	}
 
 	// Use CommandContext instead of cmd.Run()
 	execCmd := exec.CommandContext(ctx, cmd.Path, cmd.Args[1:]...)
 	execCmd.Env = cmd.Env
 	execCmd.Dir = cmd.Dir
+	execCmd.Stdin = cmd.Stdin
+	execCmd.SysProcAttr = cmd.SysProcAttr
+	if offlineFromContext(ctx) {
+		prepareOffline(execCmd, sandbox)
+	}
+	execCmd.Env = limitEnv(execCmd.Env, enforced)
 	execCmd.Stdout = &stdout
 	execCmd.Stderr = &stderr
+	if w := outputWriterFromContext(ctx); w != nil {
+		// Mirror the output as it is produced, e.g. for async job output
+		fmt.Fprintf(w, "$ %s\n", cmdStr)
+		execCmd.Stdout = io.MultiWriter(execCmd.Stdout, w)
+		execCmd.Stderr = io.MultiWriter(execCmd.Stderr, w)
+	}
+	if p := progressFromContext(ctx); p != nil {
+		// Stream the phase and output lines to a client that asked for progress
+		reportPhase(ctx, strings.Join(cmd.Args, " "))
+		stdoutLines, stderrLines := p.lines(ctx, "stdout"), p.lines(ctx, "stderr")
+		defer stdoutLines.flush()
+		defer stderrLines.flush()
+		execCmd.Stdout = io.MultiWriter(execCmd.Stdout, stdoutLines)
+		execCmd.Stderr = io.MultiWriter(execCmd.Stderr, stderrLines)
+	}
 
-	start := time.Now()
-	err := execCmd.Run()
-	duration := time.Since(start)
+	cleanup, err := sandbox.Prepare(execCmd, writable)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare %s sandbox: %v", sandbox.Name(), err)
+	}
+	defer cleanup()
 
-	// Check if the context deadline exceeded
-	if ctx.Err() == context.DeadlineExceeded {
-		log.Printf("Command timed out after %v: %s", duration, cmdStr)
-		return &ExecutionResult{
-			Stdout:     stdout.String(),
-			Stderr:     "Command execution timed out",
-			ExitCode:   -1,
-			Duration:   duration,
-			Successful: false,
-			Command:    cmdStr,
-		}, fmt.Errorf("command timed out after %v", duration)
+	limiter := newProcessLimiter(execCmd, enforced)
+
+	// Stop everything the command started when ctx is done, and do not wait
+	// forever for pipes held open by a program that survived
+	killProcessGroupOnCancel(execCmd)
+	execCmd.WaitDelay = waitDelay
+
+	start := time.Now()
+	err = execCmd.Start()
+	if err == nil {
+		limiter.started(execCmd.Process.Pid)
+		err = execCmd.Wait()
 	}
+	duration := time.Since(start)
+	exceeded := limiter.finish(execCmd.ProcessState)
 
 	result := &ExecutionResult{
 		Stdout:     stdout.String(),
@@ -223,12 +306,43 @@ func execute(cmd *exec.Cmd) (*ExecutionResult, error) {
 		Duration:   duration,
 		Successful: err == nil,
 		Command:    cmdStr,
+		Sandbox:    sandbox.Name(),
+		Dir:        cmd.Dir,
 	}
 
 	if exitErr, ok := err.(*exec.ExitError); ok {
 		result.ExitCode = exitErr.ExitCode()
 	}
 
+	// A cancelled request is neither a failure of the command nor a limit
+	if ctx.Err() == context.Canceled {
+		result.Successful = false
+		result.ExitCode = -1
+		log.Printf("Command cancelled after %v: %s", duration, cmdStr)
+		return result, &CancelledError{Duration: duration, Command: cmdStr}
+	}
+
+	// Check if the context deadline exceeded
+	if ctx.Err() == context.DeadlineExceeded {
+		exceeded = LimitTimeout
+		result.Stderr = "Command execution timed out"
+		result.ExitCode = -1
+	} else if exceeded == "" && !result.Successful {
+		exceeded = limitFromOutput(result.Stderr, enforced)
+	}
+
+	if exceeded != "" {
+		result.Successful = false
+		result.LimitExceeded = exceeded
+		log.Printf("Command exceeded %s limit after %v: %s", exceeded, duration, cmdStr)
+		return result, &LimitExceededError{
+			Limit:    exceeded,
+			Limits:   limits,
+			Duration: duration,
+			Command:  cmdStr,
+		}
+	}
+
 	// Log execution results
 	if result.Successful {
 		log.Printf("Command succeeded in %v: %s", duration, cmdStr)
@@ -239,6 +353,21 @@ func execute(cmd *exec.Cmd) (*ExecutionResult, error) {
 	return result, nil
 }
 
+// executeSetup runs an auxiliary command such as go mod init under the same
+// limits as the main command and returns its combined output. An error is
+// returned when the command could not run or exited unsuccessfully.
+func executeSetup(ctx context.Context, cmd *exec.Cmd) (string, error) {
+	result, err := execute(ctx, cmd)
+	if err != nil {
+		return "", err
+	}
+	output := result.Stdout + result.Stderr
+	if !result.Successful {
+		return output, fmt.Errorf("%s exited with code %d", result.Command, result.ExitCode)
+	}
+	return output, nil
+}
+
 // FormatCommandResult creates a standardized JSON response for tool executions
 func FormatCommandResult(result *ExecutionResult, responseType string) *mcp.CallToolResult {
 	response := map[string]interface{}{
@@ -246,6 +375,7 @@ func FormatCommandResult(result *ExecutionResult, responseType string) *mcp.Call
 		"exitCode":  result.ExitCode,
 		"duration":  result.Duration.String(),
 		"command":   result.Command,
+		"sandbox":   result.Sandbox,
 		"type":      responseType,
 		"timestamp": time.Now().Format(time.RFC3339),
 	}
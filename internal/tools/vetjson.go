@@ -0,0 +1,304 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Position is a location in a source file. Line and column are 1-based.
+type Position struct {
+	File   string `json:"file"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+}
+
+// TextEdit replaces the bytes [Start, End) of a file with New
+type TextEdit struct {
+	File  string `json:"file"`
+	Start int    `json:"start"` // Byte offset
+	End   int    `json:"end"`   // Byte offset
+	New   string `json:"new"`
+}
+
+// SuggestedFix is a set of edits an analyzer proposes to resolve a diagnostic
+type SuggestedFix struct {
+	Message string     `json:"message"`
+	Edits   []TextEdit `json:"edits"`
+}
+
+// AnalysisDiagnostic is a finding reported by an analyzer
+type AnalysisDiagnostic struct {
+	Analyzer       string         `json:"analyzer"`
+	Package        string         `json:"package"`
+	Position       Position       `json:"position"`
+	End            *Position      `json:"end,omitempty"`
+	Message        string         `json:"message"`
+	SuggestedFixes []SuggestedFix `json:"suggestedFixes,omitempty"`
+}
+
+// String formats the diagnostic as go vet prints it
+func (d AnalysisDiagnostic) String() string {
+	return fmt.Sprintf("%s: %s (%s)", d.Position, d.Message, d.Analyzer)
+}
+
+// String formats the position as file:line:column
+func (p Position) String() string {
+	switch {
+	case p.Line == 0:
+		return p.File
+	case p.Column == 0:
+		return fmt.Sprintf("%s:%d", p.File, p.Line)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+// vetJSONDiagnostic is a diagnostic as printed by go vet -json
+type vetJSONDiagnostic struct {
+	Posn           string `json:"posn"`
+	End            string `json:"end"`
+	Message        string `json:"message"`
+	SuggestedFixes []struct {
+		Message string `json:"message"`
+		Edits   []struct {
+			Filename string `json:"filename"`
+			Start    int    `json:"start"`
+			End      int    `json:"end"`
+			New      string `json:"new"`
+		} `json:"edits"`
+	} `json:"suggested_fixes"`
+}
+
+// positionPattern matches "file:line:column" and "file:line"
+var positionPattern = regexp.MustCompile(`^(.*?):(\d+)(?::(\d+))?$`)
+
+// ParseVetOutput parses the output of go vet -json. Every package is printed as
+// a JSON object mapping the package path to the diagnostics of each analyzer;
+// packages that do not type-check are reported as text instead, and returned as
+// error details. Paths are resolved as ParseDiagnostics does. Diagnostics are
+// sorted by package and position.
+func ParseVetOutput(output, workDir, root string) ([]AnalysisDiagnostic, []ErrorDetail) {
+	if root == "" {
+		root = workDir
+	}
+	var diagnostics []AnalysisDiagnostic
+	var text strings.Builder
+
+	for rest := output; rest != ""; {
+		line, next, _ := strings.Cut(rest, "\n")
+		if !strings.HasPrefix(line, "{") {
+			// "vet: file:line:col: message" is a type error reported by the vet tool
+			text.WriteString(strings.TrimPrefix(line, "vet: ") + "\n")
+			rest = next
+			continue
+		}
+
+		var packages map[string]map[string]json.RawMessage
+		dec := json.NewDecoder(strings.NewReader(rest))
+		if err := dec.Decode(&packages); err != nil {
+			text.WriteString(line + "\n")
+			rest = next
+			continue
+		}
+		rest = rest[dec.InputOffset():]
+		rest = strings.TrimPrefix(strings.TrimLeft(rest, " \t\r"), "\n")
+
+		for pkg, analyzers := range packages {
+			for analyzer, raw := range analyzers {
+				var found []vetJSONDiagnostic
+				if err := json.Unmarshal(raw, &found); err != nil {
+					// An analyzer that failed reports {"error": "..."}
+					var failure struct {
+						Error string `json:"error"`
+					}
+					if json.Unmarshal(raw, &failure) == nil && failure.Error != "" {
+						text.WriteString(fmt.Sprintf("# %s\n%s: %s\n", pkg, analyzer, failure.Error))
+					}
+					continue
+				}
+				for _, d := range found {
+					diagnostics = append(diagnostics, vetDiagnostic(d, analyzer, pkg, workDir, root))
+				}
+			}
+		}
+	}
+
+	sortAnalysisDiagnostics(diagnostics)
+	return diagnostics, ParseDiagnostics(text.String(), workDir, root)
+}
+
+// vetDiagnostic converts a go vet -json diagnostic
+func vetDiagnostic(d vetJSONDiagnostic, analyzer, pkg, workDir, root string) AnalysisDiagnostic {
+	diagnostic := AnalysisDiagnostic{
+		Analyzer: analyzer,
+		Package:  pkg,
+		Position: parsePosition(d.Posn, workDir, root),
+		Message:  d.Message,
+	}
+	if d.End != "" && d.End != d.Posn {
+		end := parsePosition(d.End, workDir, root)
+		diagnostic.End = &end
+	}
+	for _, f := range d.SuggestedFixes {
+		fix := SuggestedFix{Message: f.Message, Edits: []TextEdit{}}
+		for _, e := range f.Edits {
+			fix.Edits = append(fix.Edits, TextEdit{
+				File:  resolveDiagnosticPath(e.Filename, workDir, root),
+				Start: e.Start,
+				End:   e.End,
+				New:   e.New,
+			})
+		}
+		diagnostic.SuggestedFixes = append(diagnostic.SuggestedFixes, fix)
+	}
+	return diagnostic
+}
+
+// parsePosition parses a "file:line:column" position
+func parsePosition(posn, workDir, root string) Position {
+	m := positionPattern.FindStringSubmatch(posn)
+	if m == nil {
+		return Position{File: resolveDiagnosticPath(posn, workDir, root)}
+	}
+	line, _ := strconv.Atoi(m[2])
+	column, _ := strconv.Atoi(m[3])
+	return Position{File: resolveDiagnosticPath(m[1], workDir, root), Line: line, Column: column}
+}
+
+// sortAnalysisDiagnostics orders diagnostics by package, position and analyzer
+func sortAnalysisDiagnostics(diagnostics []AnalysisDiagnostic) {
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i], diagnostics[j]
+		switch {
+		case a.Package != b.Package:
+			return a.Package < b.Package
+		case a.Position.File != b.Position.File:
+			return a.Position.File < b.Position.File
+		case a.Position.Line != b.Position.Line:
+			return a.Position.Line < b.Position.Line
+		case a.Position.Column != b.Position.Column:
+			return a.Position.Column < b.Position.Column
+		}
+		return a.Analyzer < b.Analyzer
+	})
+}
+
+var (
+	vetAnalyzersOnce  sync.Once
+	vetAnalyzersValue map[string]bool
+	vetAnalyzersErr   error
+)
+
+// vetAnalyzers returns the names of the analyzers go vet registers
+func vetAnalyzers() (map[string]bool, error) {
+	vetAnalyzersOnce.Do(func() {
+		out, err := exec.Command("go", "tool", "vet", "help").Output()
+		if err != nil {
+			vetAnalyzersErr = fmt.Errorf("cannot list the go vet analyzers: %v", err)
+			return
+		}
+		vetAnalyzersValue = parseVetAnalyzers(string(out))
+	})
+	return vetAnalyzersValue, vetAnalyzersErr
+}
+
+// parseVetAnalyzers extracts the analyzer names from the output of go tool vet help
+func parseVetAnalyzers(help string) map[string]bool {
+	analyzers := make(map[string]bool)
+	_, list, found := strings.Cut(help, "Registered analyzers:")
+	if !found {
+		return analyzers
+	}
+	for _, line := range strings.Split(list, "\n") {
+		if !strings.HasPrefix(line, "    ") {
+			if strings.TrimSpace(line) != "" && len(analyzers) > 0 {
+				break
+			}
+			continue
+		}
+		if fields := strings.Fields(line); len(fields) > 0 {
+			analyzers[fields[0]] = true
+		}
+	}
+	return analyzers
+}
+
+// analyzerFlagName matches the flag part of an analyzer flag such as printf.funcs
+var analyzerFlagName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// vetFlags returns the go vet flags that select analyzers and configure them:
+// analyzers enables only the named analyzers, disable turns analyzers off, and
+// analyzerFlags sets analyzer flags such as {"printf.funcs": "Logf"}. Every name
+// must be an analyzer go vet registers, so no other vet flag can be passed, or
+// one that only runs in-process (see requestAnalyzers), which is left out.
+func vetFlags(req mcp.CallToolRequest) ([]string, error) {
+	enable := stringArrayArg(req, "analyzers")
+	disable := stringArrayArg(req, "disable")
+	options, _ := req.GetArguments()["analyzerFlags"].(map[string]interface{})
+	if len(enable) == 0 && len(disable) == 0 && len(options) == 0 {
+		return nil, nil
+	}
+
+	known, err := vetAnalyzers()
+	if err != nil {
+		return nil, err
+	}
+	checkAnalyzer := func(name string) error {
+		if !known[name] {
+			return fmt.Errorf("unknown go vet analyzer %q", name)
+		}
+		return nil
+	}
+
+	var flags []string
+	for _, name := range enable {
+		if driverAnalyzers[name] != nil && !known[name] {
+			if !getConfig().Analysis.InProcess {
+				return nil, fmt.Errorf("analyzer %q runs in-process, which analysis.inProcess turns off", name)
+			}
+			continue
+		}
+		if err := checkAnalyzer(name); err != nil {
+			return nil, err
+		}
+		flags = append(flags, "-"+name)
+	}
+	for _, name := range disable {
+		if driverAnalyzers[name] != nil && !known[name] {
+			continue
+		}
+		if err := checkAnalyzer(name); err != nil {
+			return nil, err
+		}
+		flags = append(flags, "-"+name+"=false")
+	}
+
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		analyzer, flag, ok := strings.Cut(name, ".")
+		if !ok || !analyzerFlagName.MatchString(flag) {
+			return nil, fmt.Errorf("analyzer flag %q must have the form analyzer.flag", name)
+		}
+		if err := checkAnalyzer(analyzer); err != nil {
+			return nil, err
+		}
+		switch value := options[name].(type) {
+		case string, bool, float64:
+			flags = append(flags, fmt.Sprintf("-%s=%v", name, value))
+		default:
+			return nil, fmt.Errorf("analyzer flag %s must have a string, boolean or number value", name)
+		}
+	}
+	return flags, nil
+}
@@ -0,0 +1,110 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/MrFixit96/go-dev-mcp/internal/config"
+)
+
+func TestParseVetOutput(t *testing.T) {
+	output := strings.Join([]string{
+		`{}`,
+		`{`,
+		`	"example.com/p/a": {`,
+		`		"printf": [`,
+		`			{"posn": "/src/p/a/a.go:6:14", "end": "/src/p/a/a.go:6:16", "message": "fmt.Printf format %s has arg x of wrong type int"}`,
+		`		],`,
+		`		"assign": [`,
+		`			{"posn": "/src/p/a/a.go:7:2", "end": "/src/p/a/a.go:7:2", "message": "self-assignment of x",`,
+		`			 "suggested_fixes": [{"message": "Remove self-assignment", "edits": [{"filename": "/src/p/a/a.go", "start": 64, "end": 71, "new": ""}]}]}`,
+		`		]`,
+		`	}`,
+		`}`,
+		`# example.com/p/b`,
+		`vet: b/b.go:3:23: cannot use "x" (untyped string constant) as int value in return statement`,
+	}, "\n")
+
+	diagnostics, failures := ParseVetOutput(output, "/src/p", "/src/p")
+	if len(diagnostics) != 2 {
+		t.Fatalf("Expected 2 diagnostics, got %+v", diagnostics)
+	}
+	printf, assign := diagnostics[0], diagnostics[1]
+	if printf.Analyzer != "printf" || printf.Package != "example.com/p/a" || printf.Position != (Position{File: "a/a.go", Line: 6, Column: 14}) {
+		t.Errorf("Unexpected printf diagnostic %+v", printf)
+	}
+	if printf.End == nil || printf.End.Column != 16 || assign.End != nil {
+		t.Errorf("Expected an end only for the printf diagnostic, got %v and %v", printf.End, assign.End)
+	}
+	if len(assign.SuggestedFixes) != 1 || assign.SuggestedFixes[0].Edits[0] != (TextEdit{File: "a/a.go", Start: 64, End: 71}) {
+		t.Errorf("Unexpected suggested fixes %+v", assign.SuggestedFixes)
+	}
+	if got := assign.String(); got != "a/a.go:7:2: self-assignment of x (assign)" {
+		t.Errorf("Unexpected diagnostic line %q", got)
+	}
+
+	if len(failures) != 1 || failures[0].File != "b/b.go" || failures[0].Package != "example.com/p/b" || failures[0].Kind != DiagnosticTypeMismatch {
+		t.Errorf("Expected the type error of package b, got %+v", failures)
+	}
+}
+
+func TestVetFlags(t *testing.T) {
+	flags, err := vetFlags(newToolRequest("go_analyze", map[string]interface{}{
+		"analyzers":     []interface{}{"printf"},
+		"disable":       []interface{}{"assign"},
+		"analyzerFlags": map[string]interface{}{"printf.funcs": "Logf", "unusedresult.funcs": "errors.New"},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(flags, " "); got != "-printf -assign=false -printf.funcs=Logf -unusedresult.funcs=errors.New" {
+		t.Errorf("Unexpected vet flags %s", got)
+	}
+
+	for _, args := range []map[string]interface{}{
+		{"analyzers": []interface{}{"vettool"}},
+		{"disable": []interface{}{"json"}},
+		{"analyzerFlags": map[string]interface{}{"vettool": "/bin/sh"}},
+		{"analyzerFlags": map[string]interface{}{"printf.funcs=x -vettool": "y"}},
+	} {
+		if _, err := vetFlags(newToolRequest("go_analyze", args)); err == nil {
+			t.Errorf("Expected %v to be rejected", args)
+		}
+	}
+}
+
+func TestAnalyzeDiagnostics(t *testing.T) {
+	SetConfig(config.DefaultConfig())
+	t.Setenv("GOFLAGS", "")
+	code := "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tx := 1\n\tx = x\n\tfmt.Printf(\"%s\\n\", x)\n}\n"
+
+	result, err := ExecuteGoAnalyzeTool(context.Background(), newToolRequest("go_analyze", map[string]interface{}{"code": code}))
+	if err != nil || result.IsError {
+		t.Fatalf("go_analyze failed: %v %s", err, resultText(result))
+	}
+	var response struct {
+		Success     bool                 `json:"success"`
+		Issues      []string             `json:"issues"`
+		Diagnostics []AnalysisDiagnostic `json:"diagnostics"`
+	}
+	if err := json.Unmarshal([]byte(resultText(result)), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Success || len(response.Diagnostics) != 2 || len(response.Issues) != 2 {
+		t.Fatalf("Expected two diagnostics, got %s", resultText(result))
+	}
+	if d := response.Diagnostics[0]; d.Analyzer != "assign" || d.Position.File != "main.go" || d.Position.Line != 7 {
+		t.Errorf("Unexpected first diagnostic %+v", d)
+	}
+
+	// Disabling the analyzers that fire leaves nothing to report
+	result, _ = ExecuteGoAnalyzeTool(context.Background(), newToolRequest("go_analyze", map[string]interface{}{
+		"code":    code,
+		"disable": []interface{}{"assign", "printf"},
+	}))
+	if err := json.Unmarshal([]byte(resultText(result)), &response); err != nil || !response.Success || len(response.Diagnostics) != 0 {
+		t.Errorf("Expected no diagnostics with the analyzers disabled, got %v %s", err, resultText(result))
+	}
+}
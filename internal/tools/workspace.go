@@ -4,9 +4,10 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -30,79 +31,204 @@ func (s *WorkspaceExecutionStrategy) Execute(ctx context.Context, input InputCon
 		return nil, fmt.Errorf("path is not a valid Go workspace: %s", input.WorkspacePath)
 	}
 
-	// For workspace operations, we need to adapt the execution context
-	workingDir, modifiedArgs, err := s.adaptWorkspaceExecution(args, input.WorkspacePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to adapt workspace execution: %v", err)
+	// Commands that target every package fan out across all workspace modules
+	if isFanOutCommand(args) {
+		modules, err := s.GetWorkspaceModules(input.WorkspacePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get workspace modules: %v", err)
+		}
+		if len(modules) > 0 {
+			return s.executeFanOut(ctx, input, args, modules)
+		}
 	}
 
-	// Prepare command
-	cmd := exec.Command("go", modifiedArgs...)
-	cmd.Dir = workingDir
-
-	// Execute command with timeout if set
-	if deadline, ok := ctx.Deadline(); ok {
-		timeout := time.Until(deadline)
-		execCtx, cancel := context.WithTimeout(ctx, timeout)
-		defer cancel()
-		cmd = exec.CommandContext(execCtx, cmd.Path, cmd.Args[1:]...)
-		cmd.Dir = workingDir
+	// Prepare command; workspace commands run from the workspace root
+	dir := input.WorkspacePath
+	if input.WorkingDir != "" {
+		dir = input.WorkingDir
 	}
+	cmd := goCommand(input, dir, args...)
 
-	return execute(cmd)
+	return execute(ctx, cmd, input.WorkspacePath)
 }
 
-// adaptWorkspaceExecution determines the proper working directory and command args for workspace operations.
-// It analyzes the command type and workspace structure to determine the optimal execution context.
-// For code-related commands (fmt, vet, test, build), it finds an appropriate module directory.
-// For workspace commands (work), it ensures execution from the workspace root.
-// Returns the working directory, modified arguments, and any error encountered.
-func (s *WorkspaceExecutionStrategy) adaptWorkspaceExecution(args []string, workspacePath string) (string, []string, error) {
-	if len(args) == 0 {
-		return workspacePath, args, nil
+// isFanOutCommand reports whether a command works on code (fmt, vet, test, build)
+// and targets "./...", meaning all packages of every workspace module
+func isFanOutCommand(args []string) bool {
+	if len(args) < 2 {
+		return false
 	}
-
-	// For commands that work on code (fmt, vet, test, build), we need to run them from within a module
 	switch args[0] {
 	case "fmt", "vet", "test", "build":
-		// Check if the command targets "./..." which means "all packages"
-		if len(args) > 1 && args[1] == "./..." {
-			// Get the first available module to run the command in
-			modules, err := s.GetWorkspaceModules(workspacePath)
-			if err != nil {
-				return workspacePath, args, fmt.Errorf("failed to get workspace modules: %v", err)
+		return containsString(args[1:], "./...")
+	}
+	return false
+}
+
+// executeFanOut runs the command in each workspace module, up to input.Parallelism
+// modules at a time, and aggregates the results in workspace order. The aggregate
+// succeeds only if the command succeeded in every module; a module whose command
+// fails to run or exceeds a limit records the error in its own result. Only the
+// cancellation of the whole request is returned as an error.
+func (s *WorkspaceExecutionStrategy) executeFanOut(ctx context.Context, input InputContext, args []string, modules []string) (*ExecutionResult, error) {
+	workers := input.Parallelism
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > runtime.NumCPU() {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(modules) {
+		workers = len(modules)
+	}
+
+	results := make([]ModuleResult, len(modules))
+	errs := make([]error, len(modules))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+
+	start := time.Now()
+	for i, module := range modules {
+		results[i].Module = module
+		modulePath := workspaceModuleDir(input.WorkspacePath, module)
+		if !fileExists(filepath.Join(modulePath, "go.mod")) {
+			results[i].ExecutionResult = &ExecutionResult{
+				Stderr:   fmt.Sprintf("workspace module %s has no go.mod file\n", module),
+				ExitCode: 1,
+				Dir:      modulePath,
 			}
+			continue
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func(i int, dir string) {
+			defer wg.Done()
+			defer func() { <-sem }()
 
-			if len(modules) > 0 {
-				// Find the first module that exists
-				for _, module := range modules {
-					// Determine the module path
-					var modulePath string
-					if module == "./" {
-						modulePath = workspacePath
-					} else {
-						// Remove the "./" prefix for path construction
-						cleanModule := strings.TrimPrefix(module, "./")
-						modulePath = filepath.Join(workspacePath, cleanModule)
-					}
-
-					// Check if the module directory exists and has a go.mod file
-					if stat, err := os.Stat(modulePath); err == nil && stat.IsDir() {
-						if goModPath := filepath.Join(modulePath, "go.mod"); fileExists(goModPath) {
-							// Run the command from within this module directory
-							return modulePath, args, nil
-						}
-					}
-				}
+			reportPhase(ctx, "module "+modules[i])
+			cmd := goCommand(input, dir, args...)
+			results[i].ExecutionResult, errs[i] = execute(ctx, cmd, input.WorkspacePath)
+		}(i, modulePath)
+	}
+	wg.Wait()
+
+	if ctx.Err() == context.Canceled {
+		return nil, &CancelledError{Duration: time.Since(start), Command: fmt.Sprintf("go %s", strings.Join(args, " "))}
+	}
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		if results[i].ExecutionResult == nil {
+			results[i].ExecutionResult = &ExecutionResult{
+				Stderr:   err.Error() + "\n",
+				ExitCode: -1,
+				Dir:      workspaceModuleDir(input.WorkspacePath, modules[i]),
+			}
+		}
+		results[i].Successful = false
+		results[i].Error = err.Error()
+	}
+
+	aggregate := &ExecutionResult{
+		Successful: true,
+		Duration:   time.Since(start),
+		Command:    fmt.Sprintf("go %s (in %d workspace modules)", strings.Join(args, " "), len(modules)),
+		Dir:        input.WorkspacePath,
+		Modules:    results,
+	}
+	var stdout, stderr strings.Builder
+	for _, r := range results {
+		stdout.WriteString(r.Stdout)
+		stderr.WriteString(r.Stderr)
+		if r.Sandbox != "" {
+			aggregate.Sandbox = r.Sandbox
+		}
+		if !r.Successful {
+			aggregate.Successful = false
+			if aggregate.ExitCode == 0 {
+				aggregate.ExitCode = r.ExitCode
 			}
 		}
-	case "work":
-		// Workspace commands should run from the workspace root
-		return workspacePath, args, nil
 	}
+	aggregate.Stdout = stdout.String()
+	aggregate.Stderr = stderr.String()
+	return aggregate, nil
+}
+
+// ModuleResult is the result of a command run in one workspace module
+type ModuleResult struct {
+	Module string // Module path as listed in go.work
+	Error  string // Why the command could not run or was stopped in the module, if it was
+	*ExecutionResult
+}
+
+// workspaceModuleDir returns the directory of a module listed in go.work, whose
+// use directives are relative to the workspace or absolute
+func workspaceModuleDir(workspace, module string) string {
+	if filepath.IsAbs(module) {
+		return filepath.Clean(module)
+	}
+	return filepath.Join(workspace, filepath.FromSlash(module))
+}
+
+// moduleResults returns the per-module results of a fanned-out command, or the
+// result itself when the command ran once
+func moduleResults(result *ExecutionResult) []*ExecutionResult {
+	if len(result.Modules) == 0 {
+		return []*ExecutionResult{result}
+	}
+	results := make([]*ExecutionResult, len(result.Modules))
+	for i, m := range result.Modules {
+		results[i] = m.ExecutionResult
+	}
+	return results
+}
+
+// moduleSummaries summarizes a fanned-out command keyed by module, or returns nil
+// when the command ran once
+func moduleSummaries(result *ExecutionResult) map[string]interface{} {
+	if len(result.Modules) == 0 {
+		return nil
+	}
+	summaries := make(map[string]interface{}, len(result.Modules))
+	for _, m := range result.Modules {
+		summary := map[string]interface{}{
+			"success":  m.Successful,
+			"exitCode": m.ExitCode,
+			"duration": m.Duration.String(),
+		}
+		if m.Stdout != "" {
+			summary["stdout"] = m.Stdout
+		}
+		if m.Stderr != "" {
+			summary["stderr"] = m.Stderr
+		}
+		if m.Error != "" {
+			summary["error"] = m.Error
+		}
+		if m.LimitExceeded != "" {
+			summary["limitExceeded"] = m.LimitExceeded
+		}
+		summaries[m.Module] = summary
+	}
+	return summaries
+}
 
-	// Default: run from workspace root
-	return workspacePath, args, nil
+// resultDiagnostics parses the compiler diagnostics of a result, resolving paths
+// against the directory each module's command ran in
+func resultDiagnostics(result *ExecutionResult, root string) []ErrorDetail {
+	var details []ErrorDetail
+	for _, r := range moduleResults(result) {
+		details = append(details, ParseDiagnostics(r.Stderr, r.Dir, root)...)
+	}
+	return details
 }
 
 // isValidWorkspace checks if the given path is a valid Go workspace.
@@ -147,6 +273,7 @@ func (s *WorkspaceExecutionStrategy) GetWorkspaceModules(workspacePath string) (
 // - Workspace path
 // - Whether a go.work file exists
 // - List of all discovered modules
+// - The parsed go.work file (go and toolchain versions, godebug settings, use and replace directives)
 // Returns a WorkspaceInfo struct with the collected data or an error if validation fails.
 func (s *WorkspaceExecutionStrategy) GetWorkspaceInfo(workspacePath string) (*WorkspaceInfo, error) {
 	if !s.isValidWorkspace(workspacePath) {
@@ -164,12 +291,22 @@ func (s *WorkspaceExecutionStrategy) GetWorkspaceInfo(workspacePath string) (*Wo
 	}
 	info.Modules = modules
 
+	if info.HasGoWork {
+		work, err := LoadGoWork(filepath.Join(workspacePath, "go.work"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse go.work file: %v", err)
+		}
+		model := work.Model()
+		info.GoWork = &model
+	}
+
 	return info, nil
 }
 
 // WorkspaceInfo contains information about a Go workspace
 type WorkspaceInfo struct {
-	Path      string   `json:"path"`
-	HasGoWork bool     `json:"hasGoWork"`
-	Modules   []string `json:"modules"`
+	Path      string       `json:"path"`
+	HasGoWork bool         `json:"hasGoWork"`
+	Modules   []string     `json:"modules"`
+	GoWork    *GoWorkModel `json:"goWork,omitempty"` // Nil when there is no go.work file
 }
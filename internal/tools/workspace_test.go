@@ -2,12 +2,16 @@ package tools
 
 import (
 	"context"
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/MrFixit96/go-dev-mcp/internal/config"
 )
 
 func TestWorkspaceDetection(t *testing.T) {
@@ -192,3 +196,149 @@ func main() {
 		t.Errorf("Expected successful execution, got: %s", result.Stderr)
 	}
 }
+
+func TestWorkspaceFanOut(t *testing.T) {
+	// Workspace mode rejects -mod=mod from the environment
+	t.Setenv("GOFLAGS", "")
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"go.work":        "go 1.21\n\nuse (\n\t./good\n\t./broken\n)\n",
+		"good/go.mod":    "module example.com/good\n\ngo 1.21\n",
+		"good/main.go":   "package main\n\nfunc main() {}\n",
+		"broken/go.mod":  "module example.com/broken\n\ngo 1.21\n",
+		"broken/main.go": "package main\n\nfunc main() { undefinedFunc() }\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tempDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	strategy := &WorkspaceExecutionStrategy{}
+	input := InputContext{Source: SourceWorkspace, WorkspacePath: tempDir, Parallelism: 2}
+	result, err := strategy.Execute(context.Background(), input, []string{"build", "./..."})
+	if err != nil {
+		t.Fatalf("Workspace execution failed: %v", err)
+	}
+
+	if result.Successful {
+		t.Error("Expected the aggregate to fail when one module fails to build")
+	}
+	if len(result.Modules) != 2 {
+		t.Fatalf("Expected results for 2 modules, got %d", len(result.Modules))
+	}
+	if result.Modules[0].Module != "./good" || !result.Modules[0].Successful {
+		t.Errorf("Expected ./good to build, got %+v", result.Modules[0])
+	}
+	if result.Modules[1].Module != "./broken" || result.Modules[1].Successful {
+		t.Errorf("Expected ./broken to fail, got %+v", result.Modules[1])
+	}
+
+	details := resultDiagnostics(result, tempDir)
+	if len(details) != 1 || details[0].File != filepath.Join("broken", "main.go") || details[0].Kind != DiagnosticUndefined {
+		t.Errorf("Expected an undefined error in broken/main.go, got %+v", details)
+	}
+}
+
+func TestWorkspaceEdit(t *testing.T) {
+	tempDir := t.TempDir()
+	original := "go 1.21\n\n// Core modules\nuse ./api // public API\n"
+	workFile := filepath.Join(tempDir, "go.work")
+	if err := os.WriteFile(workFile, []byte(original), 0644); err != nil {
+		t.Fatalf("Failed to create go.work file: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(tempDir, "cli"), 0755); err != nil {
+		t.Fatalf("Failed to create module directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "cli", "go.mod"), []byte("module example.com/cli\n"), 0644); err != nil {
+		t.Fatalf("Failed to create go.mod file: %v", err)
+	}
+
+	args := map[string]interface{}{
+		"command":        "edit",
+		"workspace_path": tempDir,
+		"add_use":        []interface{}{"./cli"},
+		"add_replace":    []interface{}{map[string]interface{}{"old": "example.com/dep", "new": "../dep"}},
+		"go_version":     "1.23.0",
+		"dry_run":        true,
+	}
+	var response struct {
+		Changed       bool        `json:"changed"`
+		Diff          string      `json:"diff"`
+		Configuration GoWorkModel `json:"configuration"`
+	}
+	callWorkspaceEdit(t, args, &response)
+
+	if !response.Changed || !strings.Contains(response.Diff, "+go 1.23.0") || !strings.Contains(response.Diff, "-go 1.21") {
+		t.Errorf("Expected a diff updating the go version, got:\n%s", response.Diff)
+	}
+	if response.Configuration.Go != "1.23.0" || len(response.Configuration.Use) != 2 || len(response.Configuration.Replace) != 1 {
+		t.Errorf("Unexpected configuration: %+v", response.Configuration)
+	}
+	if content, _ := os.ReadFile(workFile); string(content) != original {
+		t.Errorf("Dry run modified go.work:\n%s", content)
+	}
+
+	args["dry_run"] = false
+	callWorkspaceEdit(t, args, &response)
+	content, _ := os.ReadFile(workFile)
+	for _, want := range []string{"go 1.23.0", "// Core modules", "./api // public API", "./cli", "example.com/dep => ../dep"} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("go.work is missing %q:\n%s", want, content)
+		}
+	}
+
+	args["add_use"] = []interface{}{"./missing"}
+	result, err := ExecuteGoWorkspaceTool(context.Background(), newToolRequest("go_workspace", args))
+	if err != nil || !result.IsError {
+		t.Errorf("Expected an error for a directory without go.mod, got %+v", result)
+	}
+}
+
+// callWorkspaceEdit runs go_workspace edit and decodes the response
+func callWorkspaceEdit(t *testing.T, args map[string]interface{}, response interface{}) {
+	t.Helper()
+	result, err := ExecuteGoWorkspaceTool(context.Background(), newToolRequest("go_workspace", args))
+	if err != nil || result.IsError {
+		t.Fatalf("Workspace edit failed: %v %+v", err, result)
+	}
+	if err := json.Unmarshal([]byte(resultText(result)), response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+}
+
+func TestWorkspaceFanOutModuleErrors(t *testing.T) {
+	t.Setenv("GOFLAGS", "")
+	tempDir := t.TempDir()
+	external := t.TempDir()
+	writeTestFiles(t, tempDir, map[string]string{
+		"go.work":           "go 1.21\n\nuse (\n\t./slow\n\t" + filepath.ToSlash(external) + "\n)\n",
+		"slow/go.mod":       "module example.com/slow\n\ngo 1.21\n",
+		"slow/slow_test.go": "package slow\n\nimport (\n\t\"testing\"\n\t\"time\"\n)\n\nfunc TestSlow(t *testing.T) { time.Sleep(time.Minute) }\n",
+	})
+	writeTestFiles(t, external, map[string]string{
+		"go.mod":       "module example.com/fast\n\ngo 1.21\n",
+		"fast_test.go": "package fast\n\nimport \"testing\"\n\nfunc TestFast(t *testing.T) {}\n",
+	})
+
+	ctx := WithResourceLimits(context.Background(), config.ResourceLimits{TimeoutSecs: 5})
+	strategy := &WorkspaceExecutionStrategy{}
+	input := InputContext{Source: SourceWorkspace, WorkspacePath: tempDir, Parallelism: 2}
+	result, err := strategy.Execute(ctx, input, []string{"test", "./..."})
+	if err != nil {
+		t.Fatalf("Expected the timeout to stay in the module result, got %v", err)
+	}
+	if result.Successful || len(result.Modules) != 2 {
+		t.Fatalf("Expected a failed aggregate of 2 modules, got %+v", result)
+	}
+	if slow := result.Modules[0]; slow.Error == "" || slow.LimitExceeded != LimitTimeout {
+		t.Errorf("Expected ./slow to record the timeout, got %+v", slow)
+	}
+	if fast := result.Modules[1]; !fast.Successful || fast.Dir != external {
+		t.Errorf("Expected the module with an absolute path to pass in %s, got %+v", external, fast)
+	}
+}
@@ -7,7 +7,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"time"
+	"strings"
 
 	"github.com/mark3labs/mcp-go/mcp"
 )
@@ -28,6 +28,7 @@ func ExecuteGoWorkspaceTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.
 	if workspacePath == "" {
 		return mcp.NewToolResultError("workspace_path parameter is required"), nil
 	}
+	ctx = withRequestLimits(ctx, req)
 
 	// Execute the appropriate workspace command
 	switch command {
@@ -60,35 +61,18 @@ func executeWorkspaceInit(ctx context.Context, workspacePath string, req mcp.Cal
 	}
 
 	// Get modules to include in the workspace
-	modules := []string{}
-	if modulesArg, ok := req.GetArguments()["modules"]; ok {
-		if modulesList, ok := modulesArg.([]interface{}); ok {
-			for _, module := range modulesList {
-				if moduleStr, ok := module.(string); ok {
-					modules = append(modules, moduleStr)
-				}
-			}
-		}
-	}
+	modules := stringArrayArg(req, "modules")
 
 	// Prepare go work init command
 	args := []string{"work", "init"}
 	args = append(args, modules...)
 
 	// Execute command
-	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd := exec.Command("go", args...)
 	cmd.Dir = workspacePath
-
-	// Execute with timeout if set
-	if deadline, ok := ctx.Deadline(); ok {
-		execCtx, cancel := context.WithTimeout(ctx, time.Until(deadline))
-		defer cancel()
-		cmd = exec.CommandContext(execCtx, cmd.Path, cmd.Args[1:]...)
-		cmd.Dir = workspacePath
-	}
-	result, err := execute(cmd)
+	result, err := execute(ctx, cmd)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Workspace init failed: %v", err)), nil
+		return executionErrorResult(err, "Workspace init failed"), nil
 	}
 
 	// Format response
@@ -97,6 +81,7 @@ func executeWorkspaceInit(ctx context.Context, workspacePath string, req mcp.Cal
 		"message":  "Workspace initialized successfully",
 		"path":     workspacePath,
 		"duration": result.Duration.String(),
+		"sandbox":  result.Sandbox,
 		"stdout":   result.Stdout,
 		"stderr":   result.Stderr,
 		"modules":  modules,
@@ -125,16 +110,7 @@ func executeWorkspaceUse(ctx context.Context, workspacePath string, req mcp.Call
 	}
 
 	// Get modules to add
-	modules := []string{}
-	if modulesArg, ok := req.GetArguments()["modules"]; ok {
-		if modulesList, ok := modulesArg.([]interface{}); ok {
-			for _, module := range modulesList {
-				if moduleStr, ok := module.(string); ok {
-					modules = append(modules, moduleStr)
-				}
-			}
-		}
-	}
+	modules := stringArrayArg(req, "modules")
 
 	if len(modules) == 0 {
 		return mcp.NewToolResultError("modules parameter is required for 'use' command"), nil
@@ -145,11 +121,11 @@ func executeWorkspaceUse(ctx context.Context, workspacePath string, req mcp.Call
 	args = append(args, modules...)
 
 	// Execute command
-	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd := exec.Command("go", args...)
 	cmd.Dir = workspacePath
-	result, err := execute(cmd)
+	result, err := execute(ctx, cmd)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Workspace use failed: %v", err)), nil
+		return executionErrorResult(err, "Workspace use failed"), nil
 	}
 
 	response := map[string]interface{}{
@@ -157,6 +133,7 @@ func executeWorkspaceUse(ctx context.Context, workspacePath string, req mcp.Call
 		"message":  "Modules added to workspace successfully",
 		"modules":  modules,
 		"duration": result.Duration.String(),
+		"sandbox":  result.Sandbox,
 		"stdout":   result.Stdout,
 		"stderr":   result.Stderr,
 	}
@@ -183,17 +160,18 @@ func executeWorkspaceSync(ctx context.Context, workspacePath string, req mcp.Cal
 	}
 
 	// Execute go work sync
-	cmd := exec.CommandContext(ctx, "go", "work", "sync")
+	cmd := exec.Command("go", "work", "sync")
 	cmd.Dir = workspacePath
-	result, err := execute(cmd)
+	result, err := execute(ctx, cmd)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Workspace sync failed: %v", err)), nil
+		return executionErrorResult(err, "Workspace sync failed"), nil
 	}
 
 	response := map[string]interface{}{
 		"success":  result.Successful,
 		"message":  "Workspace synchronized successfully",
 		"duration": result.Duration.String(),
+		"sandbox":  result.Sandbox,
 		"stdout":   result.Stdout,
 		"stderr":   result.Stderr,
 	}
@@ -208,10 +186,12 @@ func executeWorkspaceSync(ctx context.Context, workspacePath string, req mcp.Cal
 	return mcp.NewToolResultText(string(jsonBytes)), nil
 }
 
-// executeWorkspaceEdit opens the go.work file for editing or modifies it programmatically.
-// It runs 'go work edit -json' to retrieve the current workspace configuration in JSON format.
-// This provides structured access to workspace settings and module configurations.
-// Returns a tool result with the workspace configuration data.
+// executeWorkspaceEdit modifies the go.work file programmatically.
+// It applies the requested edits (add_use, drop_use, add_replace, drop_replace,
+// go_version, toolchain) through the go.work model, which keeps existing comments,
+// and writes the result back unless dry_run is set. Without edits it only reports
+// the current configuration.
+// Returns a tool result with the resulting configuration and a diff of go.work.
 func executeWorkspaceEdit(ctx context.Context, workspacePath string, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	// Check if workspace exists
 	goWorkPath := filepath.Join(workspacePath, "go.work")
@@ -219,20 +199,41 @@ func executeWorkspaceEdit(ctx context.Context, workspacePath string, req mcp.Cal
 		return mcp.NewToolResultError("go.work file not found. Initialize the workspace first."), nil
 	}
 
-	// For now, just execute go work edit command
-	cmd := exec.CommandContext(ctx, "go", "work", "edit", "-json")
-	cmd.Dir = workspacePath
-	result, err := execute(cmd)
+	original, err := os.ReadFile(goWorkPath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to read go.work: %v", err)), nil
+	}
+	work, err := ParseGoWork(goWorkPath, original)
 	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to parse go.work: %v", err)), nil
+	}
+
+	if err := applyWorkspaceEdits(work, workspacePath, req); err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Workspace edit failed: %v", err)), nil
 	}
 
+	updated := work.Format()
+	diff := UnifiedDiff("a/go.work", "b/go.work", original, updated)
+	dryRun := mcp.ParseBoolean(req, "dry_run", false)
+
+	message := "Workspace configuration retrieved"
+	switch {
+	case diff != "" && dryRun:
+		message = "Dry run: go.work would be modified"
+	case diff != "":
+		if err := work.Save(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Workspace edit failed: %v", err)), nil
+		}
+		message = "go.work updated successfully"
+	}
+
 	response := map[string]interface{}{
-		"success":       result.Successful,
-		"message":       "Workspace configuration retrieved",
-		"configuration": result.Stdout,
-		"duration":      result.Duration.String(),
-		"stderr":        result.Stderr,
+		"success":       true,
+		"message":       message,
+		"configuration": work.Model(),
+		"changed":       diff != "",
+		"dryRun":        dryRun,
+		"diff":          diff,
 	}
 
 	AddNLMetadata(response, "go_workspace")
@@ -245,6 +246,80 @@ func executeWorkspaceEdit(ctx context.Context, workspacePath string, req mcp.Cal
 	return mcp.NewToolResultText(string(jsonBytes)), nil
 }
 
+// applyWorkspaceEdits applies the edit operations from the request to the go.work model.
+// Directories given to add_use must contain a go.mod file.
+func applyWorkspaceEdits(work *GoWork, workspacePath string, req mcp.CallToolRequest) error {
+	args := req.GetArguments()
+
+	if version, ok := args["go_version"].(string); ok {
+		if err := work.SetGo(version); err != nil {
+			return fmt.Errorf("invalid go version %q: %v", version, err)
+		}
+	}
+	if toolchain, ok := args["toolchain"].(string); ok {
+		if err := work.SetToolchain(toolchain); err != nil {
+			return fmt.Errorf("invalid toolchain %q: %v", toolchain, err)
+		}
+	}
+
+	for _, dir := range stringArrayArg(req, "drop_use") {
+		if err := work.DropUse(dir); err != nil {
+			return fmt.Errorf("cannot drop use %s: %v", dir, err)
+		}
+	}
+	for _, dir := range stringArrayArg(req, "add_use") {
+		modDir := dir
+		if !filepath.IsAbs(modDir) {
+			modDir = filepath.Join(workspacePath, dir)
+		}
+		if !fileExists(filepath.Join(modDir, "go.mod")) {
+			return fmt.Errorf("cannot use %s: directory has no go.mod file", dir)
+		}
+		if err := work.AddUse(dir); err != nil {
+			return fmt.Errorf("cannot use %s: %v", dir, err)
+		}
+	}
+
+	for _, target := range stringArrayArg(req, "drop_replace") {
+		path, version, _ := strings.Cut(target, "@")
+		if err := work.DropReplace(path, version); err != nil {
+			return fmt.Errorf("cannot drop replace %s: %v", target, err)
+		}
+	}
+	if list, ok := args["add_replace"].([]interface{}); ok {
+		for _, item := range list {
+			replace, ok := item.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("add_replace entries must be objects with old and new fields")
+			}
+			oldPath, _ := replace["old"].(string)
+			oldVersion, _ := replace["old_version"].(string)
+			newPath, _ := replace["new"].(string)
+			newVersion, _ := replace["new_version"].(string)
+			if oldPath == "" || newPath == "" {
+				return fmt.Errorf("add_replace entries require old and new")
+			}
+			if err := work.AddReplace(oldPath, oldVersion, newPath, newVersion); err != nil {
+				return fmt.Errorf("cannot replace %s: %v", oldPath, err)
+			}
+		}
+	}
+	return nil
+}
+
+// stringArrayArg returns the string elements of an array argument
+func stringArrayArg(req mcp.CallToolRequest, name string) []string {
+	values := []string{}
+	if list, ok := req.GetArguments()[name].([]interface{}); ok {
+		for _, item := range list {
+			if value, ok := item.(string); ok {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
 // executeWorkspaceVendor vendors all workspace dependencies.
 // It runs 'go work vendor' to create a vendor directory containing all dependencies
 // for all modules in the workspace. This enables offline builds and dependency isolation.
@@ -257,17 +332,18 @@ func executeWorkspaceVendor(ctx context.Context, workspacePath string, req mcp.C
 	}
 
 	// Execute go work vendor
-	cmd := exec.CommandContext(ctx, "go", "work", "vendor")
+	cmd := exec.Command("go", "work", "vendor")
 	cmd.Dir = workspacePath
-	result, err := execute(cmd)
+	result, err := execute(ctx, cmd)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Workspace vendor failed: %v", err)), nil
+		return executionErrorResult(err, "Workspace vendor failed"), nil
 	}
 
 	response := map[string]interface{}{
 		"success":  result.Successful,
 		"message":  "Workspace dependencies vendored successfully",
 		"duration": result.Duration.String(),
+		"sandbox":  result.Sandbox,
 		"stdout":   result.Stdout,
 		"stderr":   result.Stderr,
 	}